@@ -1,25 +1,75 @@
 package main
 
 import (
-	"log"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/ridwandwisiswanto/tcr/internal/agent"
+	"github.com/ridwandwisiswanto/tcr/internal/config"
+	"github.com/ridwandwisiswanto/tcr/internal/logging"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
 )
 
+var logger = logging.For("agentd")
+
 func main() {
-	log.Println("🚀 Starting tower-agentd...")
+	configPath := flag.String("config", os.Getenv(config.DefaultPathEnv), "path to YAML config file (section \"agent\")")
+	printConfig := flag.Bool("print-config", false, "print the effective config and exit")
+	flag.Parse()
 
 	// 1️⃣ Load .env (biar TOWER_URL, GITHUB_OWNER, dsb kebaca)
 	if err := godotenv.Load(); err != nil {
-		log.Printf("⚠️  .env not found or failed to load: %v", err)
+		logger.Warn(".env not found or failed to load", "err", err)
+	}
+
+	// 2️⃣ Config: default → file → env, gagal cepat kalau ada nilai yang salah
+	cfg, err := agent.LoadConfig(*configPath)
+	if *printConfig {
+		out, perr := config.Print("agent", cfg)
+		if perr != nil {
+			logging.Fatal(logger, "cannot print config", "err", perr)
+		}
+		fmt.Print(out)
+		if err != nil {
+			logging.Fatal(logger, "invalid config", "err", err)
+		}
+		return
+	}
+	if err != nil {
+		logging.Fatal(logger, "invalid config", "err", err)
 	}
+	// 📝 Logger terstruktur: format tetap, level ikut hot-reload lewat Agent.Reload
+	if err := logging.Setup("agentd", cfg.Logging); err != nil {
+		logging.Fatal(logger, "invalid logging config", "err", err)
+	}
+	logger.Info("starting tower-agentd", "instance", cfg.InstanceName)
+
+	// 🔭 OpenTelemetry (endpoint kosong = mati; tidak ikut hot-reload)
+	shutdownTracing, err := tracing.Setup("agentd", cfg.Tracing)
+	if err != nil {
+		logging.Fatal(logger, "cannot set up tracing", "err", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// 3️⃣ Start agent
+	a := agent.NewAgent(cfg)
+	logger.Info("agent configured", "tower_url", a.Config().TowerURL, "repo", a.Config().RepoFullName)
 
-	// 2️⃣ Start agent
-	a := agent.NewAgent()
-	log.Printf("🌐 Tower URL: %s | Repo: %s", a.Config().TowerURL, a.Config().RepoFullName)
+	config.Watch(*configPath, 10*time.Second, func() {
+		next, err := agent.LoadConfig(*configPath)
+		if err != nil {
+			logger.Warn("config reload rejected, keeping current settings", "err", err)
+			return
+		}
+		a.Reload(next)
+	})
 
 	if err := a.Run(); err != nil {
-		log.Fatalf("❌ Agent exited: %v", err)
+		shutdownTracing(context.Background())
+		logging.Fatal(logger, "agent exited", "err", err)
 	}
 }
@@ -1,11 +1,10 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"os/exec"
@@ -13,13 +12,25 @@ import (
 	"syscall"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ridwandwisiswanto/tcr/internal/auth"
+	"github.com/ridwandwisiswanto/tcr/internal/config"
+	"github.com/ridwandwisiswanto/tcr/internal/core"
 	"github.com/ridwandwisiswanto/tcr/internal/github"
+	"github.com/ridwandwisiswanto/tcr/internal/logging"
+	"github.com/ridwandwisiswanto/tcr/internal/pki"
+	"github.com/ridwandwisiswanto/tcr/internal/runnerlog"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
 )
 
 var (
 	controllerURL = "http://localhost:8080"
 	runnerID      = "runner-001"
 	isBusy        = false
+	tower         *auth.Client
+	logger        = logging.For("runnerd")
 )
 
 // Struct untuk menerima token dari Tower
@@ -29,6 +40,33 @@ type RegistrationPayload struct {
 }
 
 func main() {
+	if env := os.Getenv("TOWER_URL"); env != "" {
+		controllerURL = env
+	}
+	tower = auth.NewClient(controllerURL, runnerID, auth.RoleRunner, os.Getenv("TOWER_BOOTSTRAP_SECRET"))
+
+	// 📝 Logger terstruktur dari env (LOG_LEVEL, LOG_FORMAT, LOG_LEVELS)
+	logCfg := config.DefaultLogging()
+	if err := config.ApplyEnv(&logCfg); err != nil {
+		logging.Fatal(logger, "invalid logging env", "err", err)
+	}
+	if err := logging.Setup("runnerd", logCfg); err != nil {
+		logging.Fatal(logger, "invalid logging config", "err", err)
+	}
+
+	// 🔐 credential plaintext versi lama dipindah ke store terenkripsi
+	github.MigrateLegacyCredential()
+
+	// 🔭 OpenTelemetry dari env (OTEL_EXPORTER_OTLP_ENDPOINT, TCR_TRACE_SAMPLE_RATIO)
+	traceCfg := config.DefaultTracing()
+	if err := config.ApplyEnv(&traceCfg); err != nil {
+		logging.Fatal(logger, "invalid tracing env", "err", err)
+	}
+	shutdownTracing, err := tracing.Setup("runnerd", traceCfg)
+	if err != nil {
+		logging.Fatal(logger, "cannot set up tracing", "err", err)
+	}
+
 	http.HandleFunc("/register-hybrid", func(w http.ResponseWriter, r *http.Request) {
 		var payload github.RegistrationPayload
 		body, _ := io.ReadAll(r.Body)
@@ -39,16 +77,13 @@ func main() {
 			return
 		}
 
-		runnerName := os.Getenv("RUNNER_NAME")
-		if runnerName == "" {
-			runnerName = "runner-001"
-		}
+		runnerName := github.HybridRunnerName()
 
 		url := payload.URL
-		log.Printf("🧩 Registering runner %s (Hybrid Mode)...", runnerName)
+		logger.Info("registering runner (hybrid mode)", "runner_id", runnerName)
 
 		if err := github.HybridRegister(payload.Token, url, runnerName); err != nil {
-			log.Printf("❌ Registration failed: %v", err)
+			logger.Error("runner registration failed", "runner_id", runnerName, "err", err)
 			http.Error(w, err.Error(), 500)
 			return
 		}
@@ -59,18 +94,52 @@ func main() {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	// Log runner (tail / follow), dipakai Tower untuk proxy ke dashboard
+	http.HandleFunc("/logs", runnerlog.Handler(func(name string) (string, bool) {
+		if name != "" && name != github.HybridRunnerName() {
+			return "", false
+		}
+		return github.HybridLogPath(), true
+	}))
+
+	// Tower membatalkan job (tcrctl jobs cancel): hentikan Runner.Worker
+	http.HandleFunc("/job/cancel", handleJobCancel)
+
 	// Handle graceful shutdown (auto unregister)
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sig
 		github.HybridUnregister()
+		shutdownTracing(context.Background())
 		os.Exit(0)
 	}()
 
 	port := ":8081"
-	log.Printf("🏃 Runner '%s' listening on %s", os.Getenv("RUNNER_NAME"), port)
-	log.Fatal(http.ListenAndServe(port, nil))
+	srv := &http.Server{Addr: port, Handler: tracing.Middleware(http.DefaultServeMux)}
+
+	// 🔏 mTLS: enroll sertifikat dari Tower, hanya Tower yang boleh memanggil runner
+	if caFile := os.Getenv("TOWER_CA_FILE"); caFile != "" {
+		pool, err := pki.LoadPool(caFile)
+		if err != nil {
+			logging.Fatal(logger, "cannot load tower CA", "path", caFile, "err", err)
+		}
+		hostname, _ := os.Hostname()
+		certs, err := tower.EnableMTLS(pool, []string{hostname, "localhost", "127.0.0.1"})
+		if err != nil {
+			logging.Fatal(logger, "cannot enroll certificate", "err", err)
+		}
+		srv.Handler = tracing.Middleware(pki.RequirePeerRole(http.DefaultServeMux, pki.RoleTower))
+		srv.TLSConfig = pki.ServerConfig(certs, pool, true)
+
+		logger.Info("runner listening", "runner_id", github.HybridRunnerName(), "addr", port, "mtls", true)
+		logging.Fatal(logger, "server stopped", "err", srv.ListenAndServeTLS("", ""))
+	}
+
+	// tanpa mTLS: hanya request yang ditandatangani towerd (bootstrap secret)
+	srv.Handler = tracing.Middleware(auth.RequireTower(http.DefaultServeMux, os.Getenv("TOWER_BOOTSTRAP_SECRET")))
+	logger.Info("runner listening", "runner_id", github.HybridRunnerName(), "addr", port)
+	logging.Fatal(logger, "server stopped", "err", srv.ListenAndServe())
 
 	//if env := os.Getenv("RUNNER_ID"); env != "" {
 	//	runnerID = env
@@ -93,11 +162,12 @@ func heartbeatLoop() {
 		if isBusy {
 			continue
 		}
-		url := fmt.Sprintf("%s/heartbeat?id=%s&port=8081", controllerURL, runnerID)
-		_, err := http.Get(url)
+		resp, err := tower.Do("GET", fmt.Sprintf("/heartbeat?id=%s&port=8081", runnerID), nil)
 		if err != nil {
-			log.Printf("⚠️ Heartbeat failed: %v", err)
+			logger.Warn("heartbeat failed", "runner_id", runnerID, "err", err)
+			continue
 		}
+		resp.Body.Close()
 	}
 }
 
@@ -107,36 +177,73 @@ func handleJob(w http.ResponseWriter, r *http.Request) {
 	json.Unmarshal(body, &job)
 
 	jobID := fmt.Sprintf("%v", job["id"])
-	log.Printf("📦 Received job: %v", job)
+
+	// trace_context di payload adalah span dispatch towerd; pekerjaan runner
+	// dan callback /job/result masuk ke trace job yang sama
+	var dispatched core.Job
+	json.Unmarshal(body, &dispatched)
+	logger.Info("job received", "job_id", jobID, "job", dispatched.JobName, "repo", dispatched.RepoOwner+"/"+dispatched.RepoName)
+	ctx, span := tracing.Tracer().Start(tracing.FromCarrier(r.Context(), dispatched.TraceContext), "runner.job",
+		trace.WithAttributes(attribute.String("tcr.job.id", jobID), attribute.String("tcr.runner.id", runnerID)))
+	defer span.End()
 
 	isBusy = true
-	reportResult(jobID, "running")
+	reportResult(ctx, jobID, "running")
 
 	time.Sleep(5 * time.Second) // simulate work
 
 	// misal 90% success, 10% fail (random)
 	if time.Now().Unix()%10 == 0 {
-		reportResult(jobID, "failed")
-		log.Printf("❌ Job %s failed", jobID)
+		reportResult(ctx, jobID, "failed")
+		logger.Warn("job failed", "job_id", jobID)
 	} else {
-		reportResult(jobID, "success")
-		log.Printf("✅ Job %s done", jobID)
+		reportResult(ctx, jobID, "success")
+		logger.Info("job done", "job_id", jobID)
 	}
 
 	isBusy = false
 	w.WriteHeader(http.StatusOK)
 }
 
-func reportResult(jobID, status string) {
+// handleJobCancel melayani POST /job/cancel {"id": "..."} dari Tower. 200
+// berarti job sudah dihentikan (atau runner memang sedang tidak menjalankan
+// job), sehingga Tower boleh langsung membebaskan runner.
+func handleJobCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+
+	aborted, err := github.HybridAbortJob(10 * time.Second)
+	if err != nil {
+		logger.Error("cannot abort job", "job_id", req.ID, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("job aborted", "job_id", req.ID, "workers", aborted)
+	if aborted > 0 {
+		reportResult(r.Context(), req.ID, "cancelled")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"aborted": aborted})
+}
+
+func reportResult(ctx context.Context, jobID, status string) {
 	body := fmt.Sprintf(`{"id":"%s","status":"%s","runner_id":"%s"}`, jobID, status, runnerID)
-	url := fmt.Sprintf("%s/job/result", controllerURL)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer([]byte(body)))
+	resp, err := tower.DoContext(ctx, "POST", "/job/result", []byte(body))
 	if err != nil {
-		log.Printf("⚠️ Failed to report result: %v", err)
+		logger.Warn("failed to report result", "job_id", jobID, "status", status, "err", err)
 		return
 	}
 	defer resp.Body.Close()
-	log.Printf("📨 Reported job %s as %s", jobID, status)
+	logger.Info("result reported", "job_id", jobID, "status", status)
 }
 
 func RegisterHandler(w http.ResponseWriter, r *http.Request) {
@@ -149,13 +256,13 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("🪪 Received registration token, registering to GitHub Actions...")
+	logger.Info("registration token received, registering to GitHub Actions")
 
 	cmd := exec.Command(
 		"./config.sh",
 		"--url", payload.URL,
 		"--token", payload.Token,
-		"--name", os.Getenv("RUNNER_NAME"),
+		"--name", github.HybridRunnerName(),
 		"--unattended",
 		"--replace",
 	)
@@ -164,12 +271,12 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
-		log.Printf("❌ Failed to register runner: %v", err)
+		logger.Error("failed to register runner", "err", err)
 		http.Error(w, "runner registration failed", 500)
 		return
 	}
 
-	log.Printf("✅ Runner successfully registered to GitHub Actions.")
+	logger.Info("runner registered to GitHub Actions")
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -185,18 +292,15 @@ func RegisterAPIModeHandler(w http.ResponseWriter, r *http.Request) {
 
 	owner := os.Getenv("GITHUB_OWNER")
 	repo := os.Getenv("GITHUB_REPO")
-	runnerName := os.Getenv("RUNNER_NAME")
-	if runnerName == "" {
-		runnerName = "runner-001"
-	}
+	runnerName := github.HybridRunnerName()
 
-	log.Printf("🧩 Registering runner %s directly via GitHub API...", runnerName)
+	logger.Info("registering runner via GitHub API", "runner_id", runnerName, "repo", owner+"/"+repo)
 	if err := github.RegisterRunnerDirect(owner, repo, payload.Token, runnerName); err != nil {
-		log.Printf("❌ Runner registration failed: %v", err)
+		logger.Error("runner registration failed", "runner_id", runnerName, "err", err)
 		http.Error(w, fmt.Sprintf("failed: %v", err), 500)
 		return
 	}
 
-	log.Printf("✅ Runner %s registered successfully via API!", runnerName)
+	logger.Info("runner registered via API", "runner_id", runnerName)
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+func agentsCmd(c *client, out *printer, args []string) error {
+	if err := need(args, 1, "agents list|drain|remove"); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("agents list", flag.ExitOnError)
+		q := url.Values{}
+		for _, name := range []string{"status", "label", "sort"} {
+			fs.Func(name, "filter/sort by "+name, func(v string) error { q.Set(name, v); return nil })
+		}
+		fs.Parse(args[1:])
+
+		agents, err := list[agent](c, "/api/v1/agents", q)
+		if err != nil {
+			return err
+		}
+		out.print(agents, []string{"ID", "STATE", "RUNNERS", "LABELS", "VERSION", "DISK", "LAST SEEN"}, func(add func(...interface{})) {
+			for _, a := range agents {
+				state := a.State
+				if a.DrainRequested && state != "draining" && state != "stopped" {
+					state += " (drain requested)"
+				}
+				add(a.ID, state, a.Runners, strings.Join(a.Labels, ","), a.RunnerVersion,
+					fmt.Sprintf("%.0f%%", a.DiskUsedPercent), age(a.LastSeen))
+			}
+		})
+		return nil
+
+	case "drain":
+		if err := need(args, 2, "agents drain <id>"); err != nil {
+			return err
+		}
+		if err := c.post("/api/v1/agents/"+url.PathEscape(args[1])+"/drain", nil, nil, nil); err != nil {
+			return err
+		}
+		out.message("agent %s: drain requested", args[1])
+		return nil
+
+	case "remove":
+		if err := need(args, 2, "agents remove <id>"); err != nil {
+			return err
+		}
+		if err := c.delete("/api/v1/agents/" + url.PathEscape(args[1])); err != nil {
+			return err
+		}
+		out.message("agent %s: removed", args[1])
+		return nil
+	}
+	return need(nil, 1, "agents list|drain|remove")
+}
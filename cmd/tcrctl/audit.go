@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// auditCmd menampilkan audit log towerd; "audit export" menulis JSON lines
+// mentah dari /api/v1/audit/export ke stdout (atau --file)
+func auditCmd(c *client, out *printer, args []string) error {
+	export := len(args) > 0 && args[0] == "export"
+	if export {
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	q := url.Values{}
+	for _, name := range []string{"actor", "action", "target", "result", "since", "until"} {
+		fs.Func(name, "filter by "+name, func(v string) error { q.Set(name, v); return nil })
+	}
+	file := fs.String("file", "", "write the export to this file instead of stdout")
+	fs.Parse(args)
+
+	if export {
+		c.http.Timeout = 0
+		resp, err := c.do("GET", "/api/v1/audit/export", q, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		var w io.Writer = os.Stdout
+		if *file != "" {
+			f, err := os.OpenFile(*file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		_, err = io.Copy(w, resp.Body)
+		return err
+	}
+
+	entries, err := list[auditEntry](c, "/api/v1/audit", q)
+	if err != nil {
+		return err
+	}
+	out.print(entries, []string{"TIME", "ACTOR", "ACTION", "TARGET", "RESULT", "PARAMS"}, func(add func(...interface{})) {
+		for _, e := range entries {
+			target, result := e.Target, e.Result
+			if target == "" {
+				target = "-"
+			}
+			if e.Error != "" {
+				result += ": " + e.Error
+			}
+			add(e.Time.Local().Format("2006-01-02 15:04:05"), e.Actor, e.Action, target, result, params(e.Params))
+		}
+	})
+	return nil
+}
+
+// params meringkas parameter audit sebagai key=value terurut
+func params(m map[string]interface{}) string {
+	if len(m) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", k, m[k])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/pki"
+)
+
+// client memanggil endpoint admin towerd dengan Bearer admin token
+type client struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+func newClient(server, token, caFile string) (*client, error) {
+	c := &client{
+		base:  strings.TrimRight(server, "/"),
+		token: token,
+		http:  &http.Client{Timeout: 15 * time.Second},
+	}
+	if caFile != "" {
+		pool, err := pki.LoadPool(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("load CA: %v", err)
+		}
+		c.http.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	}
+	return c, nil
+}
+
+func (c *client) do(method, path string, q url.Values, body interface{}) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		r = bytes.NewReader(b)
+	}
+	u := c.base + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("%s %s: %d (check --token / TOWER_ADMIN_TOKEN)", method, path, resp.StatusCode)
+		}
+		// /api/v1 membalas {"error": {"code": ..., "message": ...}}
+		var apiErr struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(msg, &apiErr) == nil && apiErr.Error.Message != "" {
+			return nil, fmt.Errorf("%s: %s", apiErr.Error.Code, apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("%s %s: %d %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// getJSON memanggil GET dan decode JSON ke out
+func (c *client) getJSON(path string, q url.Values, out interface{}) error {
+	resp, err := c.do("GET", path, q, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// post memanggil POST; out boleh nil kalau body balasan tidak dipakai
+func (c *client) post(path string, q url.Values, body, out interface{}) error {
+	resp, err := c.do("POST", path, q, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// list mengambil semua halaman dari list endpoint /api/v1
+func list[T any](c *client, path string, q url.Values) ([]T, error) {
+	if q == nil {
+		q = url.Values{}
+	}
+	q.Set("limit", "500")
+	var all []T
+	for offset := 0; ; {
+		q.Set("offset", strconv.Itoa(offset))
+		var p page[T]
+		if err := c.getJSON(path, q, &p); err != nil {
+			return nil, err
+		}
+		all = append(all, p.Items...)
+		offset += len(p.Items)
+		if len(p.Items) == 0 || offset >= p.Total {
+			return all, nil
+		}
+	}
+}
+
+// delete memanggil DELETE tanpa body
+func (c *client) delete(path string) error {
+	resp, err := c.do("DELETE", path, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// printer menulis hasil sebagai tabel (default) atau JSON (-o json)
+type printer struct {
+	json bool
+}
+
+// print menulis v sebagai JSON, atau memanggil table untuk mengisi baris tabel
+func (p *printer) print(v interface{}, header []string, rows func(add func(cols ...interface{}))) {
+	if p.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(v)
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	rows(func(cols ...interface{}) {
+		s := make([]string, len(cols))
+		for i, c := range cols {
+			s[i] = fmt.Sprint(c)
+		}
+		fmt.Fprintln(tw, strings.Join(s, "\t"))
+	})
+	tw.Flush()
+}
+
+// message mencetak hasil aksi (cancel/drain/remove/scale)
+func (p *printer) message(format string, args ...interface{}) {
+	if p.json {
+		json.NewEncoder(os.Stdout).Encode(map[string]string{"result": fmt.Sprintf(format, args...)})
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return time.Since(t).Round(time.Second).String()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// eventsCmd men-tail stream Server-Sent Events towerd (/api/v1/events/stream);
+// kalau koneksi putus, menyambung ulang dari event terakhir yang diterima.
+func eventsCmd(c *client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("events", flag.ExitOnError)
+	typ := fs.String("type", "", "event types or prefixes, comma separated (job.*, runner.busy)")
+	subject := fs.String("subject", "", "only events about these job/runner/agent ids (comma separated)")
+	fs.Parse(args)
+
+	q := url.Values{}
+	if *typ != "" {
+		q.Set("type", *typ)
+	}
+	if *subject != "" {
+		q.Set("subject", *subject)
+	}
+
+	// stream tidak boleh kena timeout client
+	c.http.Timeout = 0
+	var lastID string
+	for {
+		if lastID != "" {
+			q.Set("last_event_id", lastID)
+		}
+		resp, err := c.do("GET", "/api/v1/events/stream", q, nil)
+		if err != nil {
+			return err
+		}
+
+		sc := bufio.NewScanner(resp.Body)
+		sc.Buffer(make([]byte, 64*1024), 1<<20)
+		for sc.Scan() {
+			line := sc.Text()
+			if id, ok := strings.CutPrefix(line, "id: "); ok {
+				lastID = id
+				continue
+			}
+			data, ok := strings.CutPrefix(line, "data: ")
+			if !ok {
+				continue
+			}
+			if out.json {
+				fmt.Fprintln(os.Stdout, data)
+				continue
+			}
+
+			var ev event
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				fmt.Println(data)
+				continue
+			}
+			fmt.Printf("%s  %-18s %-24s %s\n", ev.Time.Format("15:04:05"), ev.Type, ev.Subject, string(ev.Data))
+		}
+		resp.Body.Close()
+
+		fmt.Fprintf(os.Stderr, "stream closed (%v), reconnecting...\n", sc.Err())
+		time.Sleep(2 * time.Second)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func jobsCmd(c *client, out *printer, args []string) error {
+	if err := need(args, 1, "jobs list|describe|cancel|requeue"); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("jobs list", flag.ExitOnError)
+		q := url.Values{}
+		for _, name := range []string{"status", "repo", "label", "since", "until", "sort"} {
+			fs.Func(name, "filter/sort by "+name, func(v string) error { q.Set(name, v); return nil })
+		}
+		fs.Parse(args[1:])
+
+		jobs, err := list[job](c, "/api/v1/jobs", q)
+		if err != nil {
+			return err
+		}
+		out.print(jobs, []string{"ID", "REPO", "JOB", "STATUS", "LABELS", "WAIT", "AGE"}, func(add func(...interface{})) {
+			for _, j := range jobs {
+				add(j.ID, j.Repo, j.Name, j.Status, strings.Join(j.Labels, ","),
+					time.Duration(j.Wait*float64(time.Second)).Round(time.Second).String(), age(j.CreatedAt))
+			}
+		})
+		return nil
+
+	case "describe":
+		if err := need(args, 2, "jobs describe <id>"); err != nil {
+			return err
+		}
+		var j job
+		if err := c.getJSON("/api/v1/jobs/"+url.PathEscape(args[1]), nil, &j); err != nil {
+			return err
+		}
+		out.print(j, []string{"FIELD", "VALUE"}, func(add func(...interface{})) {
+			add("ID", j.ID)
+			add("Repo", j.Repo)
+			add("Job", j.Name)
+			add("Action", j.Action)
+			add("Status", j.Status)
+			add("Labels", strings.Join(j.Labels, ","))
+			add("Created", j.CreatedAt.Format("2006-01-02 15:04:05"))
+			if j.RunnerID != "" {
+				add("Runner", j.RunnerID)
+			}
+			if j.RunID != 0 {
+				add("GitHub run", j.RunID)
+			}
+			if j.TraceID != "" {
+				add("Trace", j.TraceID)
+			}
+			for _, h := range j.History {
+				line := h.Action + " by " + h.By + " at " + h.At.Format("2006-01-02 15:04:05")
+				if h.Reason != "" {
+					line += " (" + h.Reason + ")"
+				}
+				add("History", line)
+			}
+		})
+		return nil
+
+	case "cancel", "requeue":
+		fs := flag.NewFlagSet("jobs "+args[0], flag.ExitOnError)
+		reason := fs.String("reason", "", "reason recorded in the job history")
+		fs.Parse(args[1:])
+		if err := need(fs.Args(), 1, "jobs "+args[0]+" [--reason text] <id>"); err != nil {
+			return err
+		}
+		id := fs.Arg(0)
+		var j job
+		body := map[string]string{"reason": *reason}
+		if err := c.post("/api/v1/jobs/"+url.PathEscape(id)+"/"+args[0], nil, body, &j); err != nil {
+			return err
+		}
+		out.message("job %s: %s", j.ID, j.Status)
+		return nil
+	}
+	return need(nil, 1, "jobs list|describe|cancel|requeue")
+}
@@ -0,0 +1,109 @@
+// tcrctl adalah CLI operator untuk towerd: job, runner, agent, kapasitas pool,
+// scaling, event stream, audit log dan delivery webhook, dengan output tabel atau JSON.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: tcrctl [flags] <command> [args]
+
+Commands:
+  jobs list [--status s] [--repo owner/name] [--label l] [--since t] [--until t] [--sort f]
+  jobs describe <id>
+  jobs cancel [--reason text] <id>
+  jobs requeue [--reason text] <id>
+  runners list [--status idle|busy|draining|offline]
+  runners drain <id>
+  runners remove <id>
+  agents list [--status s] [--label l]
+  agents drain <id>
+  agents remove <id>
+  pool list|capacity
+  scale up|down <count>
+  events [--type job.*,runner.busy] [--subject id]
+  audit [--actor a] [--action runner.*] [--target t] [--result ok|error] [--since t] [--until t]
+  audit export [filters] [--file audit.jsonl]
+  webhooks list [--status dropped] [--event e] [--repo owner/name] [--source webhook|reconcile]
+  webhooks show <id>
+  webhooks replay <id>... | --status dropped
+  webhooks reconcile [--since t]
+
+Flags:
+`
+
+func main() {
+	global := flag.NewFlagSet("tcrctl", flag.ExitOnError)
+	server := global.String("server", getEnv("TCR_SERVER", getEnv("TOWER_URL", "http://localhost:8080")), "towerd base URL")
+	token := global.String("token", os.Getenv("TOWER_ADMIN_TOKEN"), "admin token (TOWER_ADMIN_TOKEN)")
+	caFile := global.String("ca", os.Getenv("TOWER_CA_FILE"), "CA bundle for https towerd")
+	output := global.String("o", "table", "output format: table or json")
+	global.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		global.PrintDefaults()
+	}
+	global.Parse(os.Args[1:])
+
+	args := global.Args()
+	if len(args) == 0 {
+		global.Usage()
+		os.Exit(2)
+	}
+	if *output != "table" && *output != "json" {
+		fatalf("unknown output format %q", *output)
+	}
+
+	c, err := newClient(*server, *token, *caFile)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	out := &printer{json: *output == "json"}
+
+	var cmdErr error
+	switch args[0] {
+	case "jobs":
+		cmdErr = jobsCmd(c, out, args[1:])
+	case "runners":
+		cmdErr = runnersCmd(c, out, args[1:])
+	case "agents":
+		cmdErr = agentsCmd(c, out, args[1:])
+	case "pool":
+		cmdErr = poolCmd(c, out, args[1:])
+	case "scale":
+		cmdErr = scaleCmd(c, out, args[1:])
+	case "events":
+		cmdErr = eventsCmd(c, out, args[1:])
+	case "audit":
+		cmdErr = auditCmd(c, out, args[1:])
+	case "webhooks":
+		cmdErr = webhooksCmd(c, out, args[1:])
+	default:
+		global.Usage()
+		os.Exit(2)
+	}
+	if cmdErr != nil {
+		fatalf("%v", cmdErr)
+	}
+}
+
+// need memastikan subcommand mendapat argumen yang cukup
+func need(args []string, n int, form string) error {
+	if len(args) < n {
+		return fmt.Errorf("usage: tcrctl %s", form)
+	}
+	return nil
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "tcrctl: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+func getEnv(k, def string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+func poolCmd(c *client, out *printer, args []string) error {
+	if err := need(args, 1, "pool list|capacity"); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		pools, err := list[pool](c, "/api/v1/pools", nil)
+		if err != nil {
+			return err
+		}
+		out.print(pools, []string{"POOL", "AGENTS", "ACTIVE", "RUNNERS"}, func(add func(...interface{})) {
+			for _, p := range pools {
+				add(p.Name, p.Agents, p.ActiveAgents, p.Runners)
+			}
+		})
+		return nil
+
+	case "capacity":
+		var cap capacity
+		if err := c.getJSON("/api/v1/pools/capacity", nil, &cap); err != nil {
+			return err
+		}
+		out.print(cap, []string{"METRIC", "VALUE"}, func(add func(...interface{})) {
+			add("agents", cap.Agents)
+			add("agent runners", cap.AgentRunners)
+			add("runners", cap.Runners)
+			add("busy runners", cap.BusyRunners)
+			add("draining runners", cap.DrainingRunners)
+			add("queued jobs", cap.QueuedJobs)
+			add("dispatched jobs", cap.DispatchedJobs)
+			add("max runners total", cap.MaxRunnersTotal)
+			add("scale step max", cap.ScaleStepMax)
+		})
+		return nil
+	}
+	return need(nil, 1, "pool list|capacity")
+}
+
+func scaleCmd(c *client, out *printer, args []string) error {
+	if err := need(args, 2, "scale up|down <count>"); err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n <= 0 {
+		return fmt.Errorf("count must be a positive integer, got %q", args[1])
+	}
+	body := map[string]interface{}{"direction": args[0], "count": n}
+	if err := c.post("/api/v1/pools/scale", nil, body, nil); err != nil {
+		return err
+	}
+	out.message("scale %s by %d requested", args[0], n)
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"net/url"
+)
+
+func runnersCmd(c *client, out *printer, args []string) error {
+	if err := need(args, 1, "runners list|drain|remove"); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("runners list", flag.ExitOnError)
+		q := url.Values{}
+		for _, name := range []string{"status", "sort"} {
+			fs.Func(name, "filter/sort by "+name, func(v string) error { q.Set(name, v); return nil })
+		}
+		fs.Parse(args[1:])
+
+		runners, err := list[runner](c, "/api/v1/runners", q)
+		if err != nil {
+			return err
+		}
+		out.print(runners, []string{"ID", "ADDRESS", "STATUS", "LAST SEEN"}, func(add func(...interface{})) {
+			for _, r := range runners {
+				add(r.ID, r.Address+":"+r.Port, r.Status, age(r.LastSeen))
+			}
+		})
+		return nil
+
+	case "drain":
+		if err := need(args, 2, "runners drain <id>"); err != nil {
+			return err
+		}
+		if err := c.post("/api/v1/runners/"+url.PathEscape(args[1])+"/drain", nil, nil, nil); err != nil {
+			return err
+		}
+		out.message("runner %s: draining", args[1])
+		return nil
+
+	case "remove":
+		if err := need(args, 2, "runners remove <id>"); err != nil {
+			return err
+		}
+		if err := c.delete("/api/v1/runners/" + url.PathEscape(args[1])); err != nil {
+			return err
+		}
+		out.message("runner %s: removed", args[1])
+		return nil
+	}
+	return need(nil, 1, "runners list|drain|remove")
+}
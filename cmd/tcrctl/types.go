@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Tipe di bawah mengikuti JSON /api/v1 towerd; sengaja tidak meng-import
+// internal/api supaya tcrctl tidak ikut membawa dependency server.
+
+type page[T any] struct {
+	Items  []T `json:"items"`
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type job struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`
+	Repo      string    `json:"repo"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Labels    []string  `json:"labels"`
+	CreatedAt time.Time `json:"created_at"`
+	RunnerID  string    `json:"runner_id"`
+	RunID     int64     `json:"run_id"`
+	TraceID   string    `json:"trace_id"`
+	Wait      float64   `json:"wait_seconds"`
+	History   []struct {
+		Action string    `json:"action"`
+		By     string    `json:"by"`
+		Reason string    `json:"reason"`
+		At     time.Time `json:"at"`
+	} `json:"history"`
+}
+
+type runner struct {
+	ID       string    `json:"id"`
+	Address  string    `json:"address"`
+	Port     string    `json:"port"`
+	Status   string    `json:"status"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+type agent struct {
+	ID              string    `json:"id"`
+	State           string    `json:"state"`
+	Runners         int       `json:"runners"`
+	Labels          []string  `json:"labels"`
+	RunnerVersion   string    `json:"runner_version"`
+	DiskUsedPercent float64   `json:"disk_used_percent"`
+	DrainRequested  bool      `json:"drain_requested"`
+	LastSeen        time.Time `json:"last_seen"`
+}
+
+type pool struct {
+	Name         string `json:"name"`
+	Agents       int    `json:"agents"`
+	ActiveAgents int    `json:"active_agents"`
+	Runners      int    `json:"runners"`
+}
+
+type capacity struct {
+	Agents          int `json:"agents"`
+	AgentRunners    int `json:"agent_runners"`
+	Runners         int `json:"runners"`
+	BusyRunners     int `json:"busy_runners"`
+	DrainingRunners int `json:"draining_runners"`
+	QueuedJobs      int `json:"queued_jobs"`
+	DispatchedJobs  int `json:"dispatched_jobs"`
+	MaxRunnersTotal int `json:"max_runners_total"`
+	ScaleStepMax    int `json:"scale_step_max"`
+}
+
+type event struct {
+	ID      int64           `json:"id"`
+	Type    string          `json:"type"`
+	Time    time.Time       `json:"time"`
+	Subject string          `json:"subject"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type auditEntry struct {
+	ID     int64                  `json:"id"`
+	Time   time.Time              `json:"time"`
+	Actor  string                 `json:"actor"`
+	Action string                 `json:"action"`
+	Target string                 `json:"target"`
+	Params map[string]interface{} `json:"params"`
+	Result string                 `json:"result"`
+	Error  string                 `json:"error"`
+}
+
+type delivery struct {
+	ID          string            `json:"id"`
+	Event       string            `json:"event"`
+	Action      string            `json:"action"`
+	Repo        string            `json:"repo"`
+	Source      string            `json:"source"`
+	Status      string            `json:"status"`
+	Error       string            `json:"error"`
+	JobID       string            `json:"job_id"`
+	Attempts    int               `json:"attempts"`
+	ReceivedAt  time.Time         `json:"received_at"`
+	ProcessedAt time.Time         `json:"processed_at"`
+	Headers     map[string]string `json:"headers"`
+	Body        json.RawMessage   `json:"body"`
+}
+
+type replayResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	JobID  string `json:"job_id"`
+	Error  string `json:"error"`
+}
+
+type reconcileResult struct {
+	Since     time.Time `json:"since"`
+	Checked   int       `json:"checked"`
+	Missing   int       `json:"missing"`
+	Recovered int       `json:"recovered"`
+	Failed    int       `json:"failed"`
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"net/url"
+)
+
+func webhooksCmd(c *client, out *printer, args []string) error {
+	if err := need(args, 1, "webhooks list|show|replay|reconcile"); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("webhooks list", flag.ExitOnError)
+		q := url.Values{}
+		for _, name := range []string{"status", "event", "repo", "source", "since", "until", "sort"} {
+			fs.Func(name, "filter/sort by "+name, func(v string) error { q.Set(name, v); return nil })
+		}
+		fs.Parse(args[1:])
+
+		deliveries, err := list[delivery](c, "/api/v1/webhooks/deliveries", q)
+		if err != nil {
+			return err
+		}
+		out.print(deliveries, []string{"ID", "EVENT", "REPO", "SOURCE", "STATUS", "ATTEMPTS", "JOB", "AGE"}, func(add func(...interface{})) {
+			for _, d := range deliveries {
+				add(d.ID, d.Event+"."+orDash(d.Action), orDash(d.Repo), d.Source, d.Status, d.Attempts, orDash(d.JobID), age(d.ReceivedAt))
+			}
+		})
+		return nil
+
+	case "show":
+		if err := need(args, 2, "webhooks show <id>"); err != nil {
+			return err
+		}
+		var d delivery
+		if err := c.getJSON("/api/v1/webhooks/deliveries/"+url.PathEscape(args[1]), nil, &d); err != nil {
+			return err
+		}
+		out.print(d, []string{"FIELD", "VALUE"}, func(add func(...interface{})) {
+			add("ID", d.ID)
+			add("Event", d.Event+"."+orDash(d.Action))
+			add("Repo", orDash(d.Repo))
+			add("Source", d.Source)
+			add("Status", d.Status)
+			if d.Error != "" {
+				add("Error", d.Error)
+			}
+			add("Attempts", d.Attempts)
+			add("Job", orDash(d.JobID))
+			add("Received", d.ReceivedAt.Local().Format("2006-01-02 15:04:05"))
+			if !d.ProcessedAt.IsZero() {
+				add("Processed", d.ProcessedAt.Local().Format("2006-01-02 15:04:05"))
+			}
+			for k, v := range d.Headers {
+				add("Header", k+": "+v)
+			}
+			add("Body", len(d.Body))
+		})
+		return nil
+
+	case "replay":
+		fs := flag.NewFlagSet("webhooks replay", flag.ExitOnError)
+		status := fs.String("status", "", "replay every stored delivery with this status (e.g. dropped)")
+		fs.Parse(args[1:])
+		if len(fs.Args()) == 0 && *status == "" {
+			return need(nil, 1, "webhooks replay <id>... | --status dropped")
+		}
+
+		var results []replayResult
+		body := map[string]interface{}{"ids": fs.Args(), "status": *status}
+		if err := c.post("/api/v1/webhooks/replay", nil, body, &results); err != nil {
+			return err
+		}
+		out.print(results, []string{"ID", "STATUS", "JOB", "ERROR"}, func(add func(...interface{})) {
+			for _, r := range results {
+				add(r.ID, r.Status, orDash(r.JobID), orDash(r.Error))
+			}
+		})
+		return nil
+
+	case "reconcile":
+		fs := flag.NewFlagSet("webhooks reconcile", flag.ExitOnError)
+		q := url.Values{}
+		fs.Func("since", "RFC3339 start of the window (default: since the previous run)", func(v string) error { q.Set("since", v); return nil })
+		fs.Parse(args[1:])
+
+		var res reconcileResult
+		if err := c.post("/api/v1/webhooks/reconcile", q, nil, &res); err != nil {
+			return err
+		}
+		out.print(res, []string{"SINCE", "CHECKED", "MISSING", "RECOVERED", "FAILED"}, func(add func(...interface{})) {
+			add(res.Since.Local().Format("2006-01-02 15:04:05"), res.Checked, res.Missing, res.Recovered, res.Failed)
+		})
+		return nil
+	}
+	return need(nil, 1, "webhooks list|show|replay|reconcile")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -1,38 +1,163 @@
 package main
 
 import (
-	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/ridwandwisiswanto/tcr/internal/api"
+	"github.com/ridwandwisiswanto/tcr/internal/audit"
+	"github.com/ridwandwisiswanto/tcr/internal/auth"
+	"github.com/ridwandwisiswanto/tcr/internal/config"
 	"github.com/ridwandwisiswanto/tcr/internal/controller"
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+	"github.com/ridwandwisiswanto/tcr/internal/dashboard"
 	"github.com/ridwandwisiswanto/tcr/internal/github"
+	"github.com/ridwandwisiswanto/tcr/internal/logging"
+	"github.com/ridwandwisiswanto/tcr/internal/pki"
+	"github.com/ridwandwisiswanto/tcr/internal/secrets"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
 )
 
+var logger = logging.For("towerd")
+
 func main() {
+	configPath := flag.String("config", os.Getenv(config.DefaultPathEnv), "path to YAML config file (section \"tower\")")
+	printConfig := flag.Bool("print-config", false, "print the effective config and exit")
+	flag.Parse()
+
 	// _ = godotenv.Load()
 	if err := godotenv.Load(); err != nil {
-		log.Printf("⚠️  .env not found or failed to load: %v", err)
+		logger.Warn(".env not found or failed to load", "err", err)
 	}
 
-	secret := os.Getenv("GITHUB_WEBHOOK_SECRET")
-	if secret == "" {
-		log.Fatal("❌ GITHUB_WEBHOOK_SECRET is not set.")
+	// ⚙️ Config: default → file → env, gagal cepat kalau ada nilai yang salah
+	cfg, err := config.LoadTower(*configPath)
+	if *printConfig {
+		out, perr := config.Print("tower", cfg)
+		if perr != nil {
+			logging.Fatal(logger, "cannot print config", "err", perr)
+		}
+		fmt.Print(out)
+		if err != nil {
+			logging.Fatal(logger, "invalid config", "err", err)
+		}
+		return
+	}
+	if err != nil {
+		logging.Fatal(logger, "invalid config", "err", err)
 	}
+	// 📝 Logger terstruktur: format tetap, level ikut hot-reload lewat controller.Reload
+	if err := logging.Setup("towerd", cfg.Logging); err != nil {
+		logging.Fatal(logger, "invalid logging config", "err", err)
+	}
+	controller.Configure(cfg)
 
-	mode := os.Getenv("MODE")
-	log.Printf("🧩 MODE env detected = '%s'", mode)
+	// 🔭 OpenTelemetry: trace job webhook → queue → dispatch → result (tidak ikut hot-reload)
+	shutdownTracing, err := tracing.Setup("towerd", cfg.Tracing)
+	if err != nil {
+		logging.Fatal(logger, "cannot set up tracing", "err", err)
+	}
+	config.Watch(*configPath, 10*time.Second, func() {
+		next, err := config.LoadTower(*configPath)
+		if err != nil {
+			logger.Warn("config reload rejected, keeping current settings", "err", err)
+			return
+		}
+		controller.Reload(next)
+	})
+
+	// 📜 Audit log append-only (JSON lines) untuk aksi admin, token dan scaling
+	auditLog, err := audit.Open(cfg.Audit.Path)
+	if err != nil {
+		logging.Fatal(logger, "cannot open audit log", "path", cfg.Audit.Path, "err", err)
+	}
+	audit.SetDefault(auditLog)
+	if cfg.Audit.Path == "" {
+		logger.Warn("audit log is in memory only, set tower.audit.path to keep it")
+	}
+
+	// 📨 Delivery webhook mentah disimpan untuk replay & rekonsiliasi
+	retention := time.Duration(cfg.Webhook.RetentionHours) * time.Hour
+	deliveries, err := github.OpenDeliveryStore(cfg.Webhook.DeliveriesDir, retention)
+	if err != nil {
+		logging.Fatal(logger, "cannot open webhook delivery store", "dir", cfg.Webhook.DeliveriesDir, "err", err)
+	}
+	github.Deliveries = deliveries
+	github.Reconcile.HookID = cfg.Webhook.HookID
+	github.Reconcile.Interval = time.Duration(cfg.Webhook.ReconcileIntervalSec) * time.Second
+	github.Reconcile.Lookback = retention
 
-	if mode == "" {
-		log.Fatal("❌ MODE is not set.")
+	// 📥 Intake job webhook → scheduler; job yang belum diambil selamat dari restart
+	intake, err := core.OpenIntake(cfg.Queue.Dir, cfg.Queue.Capacity, cfg.Queue.HighWatermark)
+	if err != nil {
+		logging.Fatal(logger, "cannot open job intake", "dir", cfg.Queue.Dir, "err", err)
 	}
+	core.JobQueue = intake
+	github.QueueRetryAfter = time.Duration(cfg.Queue.RetryAfterSec) * time.Second
+
+	// 🔐 Secret dari SECRETS_DIR / Vault / env (lihat internal/secrets)
+	secrets.SetDefault(secrets.FromEnv())
+
+	if secrets.Get("GITHUB_WEBHOOK_SECRET") == "" {
+		logging.Fatal(logger, "GITHUB_WEBHOOK_SECRET is not set")
+	}
+
+	// 🔐 Credential agent/runner: enroll pakai bootstrap secret → token HMAC
+	authSrv := &auth.Server{
+		Issuer:     auth.NewIssuer(secrets.Get("TOWER_AUTH_KEY")),
+		Bootstrap:  secrets.Get("TOWER_BOOTSTRAP_SECRET"),
+		AdminToken: secrets.Get("TOWER_ADMIN_TOKEN"),
+		TTL:        24 * time.Hour,
+	}
+	controller.ConfigureOutboundAuth(authSrv.Bootstrap)
+	if authSrv.Bootstrap == "" {
+		logger.Warn("TOWER_BOOTSTRAP_SECRET is not set, agents cannot enroll")
+	}
+	// 🔏 mTLS: CA internal menerbitkan sertifikat untuk towerd & agent yang enroll
+	var tlsCfg *tls.Config
+	if cfg.TLS.Enabled {
+		ca, err := pki.LoadOrCreateCA(cfg.TLS.PKIDir)
+		if err != nil {
+			logging.Fatal(logger, "cannot load internal CA", "dir", cfg.TLS.PKIDir, "err", err)
+		}
+		certTTL := time.Duration(cfg.TLS.CertTTLHours) * time.Hour
+		hosts := cfg.TLS.Hosts
+
+		rot, err := pki.NewRotator("towerd", func() (*tls.Certificate, error) {
+			return ca.Issue("towerd", pki.RoleTower, hosts, certTTL)
+		})
+		if err != nil {
+			logging.Fatal(logger, "cannot issue tower certificate", "err", err)
+		}
+
+		authSrv.CA = ca
+		authSrv.CertTTL = certTTL
+		tlsCfg = pki.ServerConfig(rot, ca.Pool(), false)
+		controller.ConfigureOutboundTLS(pki.ClientConfig(rot, ca.Pool(), auth.RoleAgent, auth.RoleRunner))
+	}
+
+	github.AuthorizeAgent = func(r *http.Request) bool {
+		c, ok := auth.FromRequest(r)
+		return ok && (c.Role == auth.RoleAgent || c.Role == auth.RoleAdmin)
+	}
+	github.RequestActor = controller.Actor
+
+	logger.Info("tower mode", "mode", cfg.Mode)
 
 	http.HandleFunc("/register-runner", func(w http.ResponseWriter, r *http.Request) {
+		if !github.AuthorizeAgent(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
 		token, err := github.GetRunnerRegistrationToken()
+		auditToken(r, "", err)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -43,6 +168,7 @@ func main() {
 	http.HandleFunc("/register-to-runner", func(w http.ResponseWriter, r *http.Request) {
 		token, err := github.GetRunnerRegistrationToken()
 		if err != nil {
+			auditToken(r, "", err)
 			http.Error(w, err.Error(), 500)
 			return
 		}
@@ -53,9 +179,10 @@ func main() {
 		}
 
 		data, _ := json.Marshal(payload)
-		resp, err := http.Post("http://localhost:8081/register", "application/json", bytes.NewBuffer(data))
+		resp, err := controller.PostJSON(controller.RunnerURL("localhost", "8081", "/register"), data)
+		auditToken(r, "/register", err)
 		if err != nil {
-			log.Printf("❌ Failed to send registration token to runner: %v", err)
+			logger.Error("failed to send registration token to runner", "err", err)
 			http.Error(w, "failed to send token", 500)
 			return
 		}
@@ -67,6 +194,7 @@ func main() {
 	http.HandleFunc("/register-to-runner-api", func(w http.ResponseWriter, r *http.Request) {
 		token, err := github.GetRunnerRegistrationToken()
 		if err != nil {
+			auditToken(r, "", err)
 			http.Error(w, err.Error(), 500)
 			return
 		}
@@ -77,9 +205,10 @@ func main() {
 		}
 
 		data, _ := json.Marshal(payload)
-		resp, err := http.Post("http://localhost:8081/register-api", "application/json", bytes.NewBuffer(data))
+		resp, err := controller.PostJSON(controller.RunnerURL("localhost", "8081", "/register-api"), data)
+		auditToken(r, "/register-api", err)
 		if err != nil {
-			log.Printf("❌ Failed to send token to runner: %v", err)
+			logger.Error("failed to send token to runner", "err", err)
 			http.Error(w, "failed to send token", 500)
 			return
 		}
@@ -91,6 +220,7 @@ func main() {
 	http.HandleFunc("/register-hybrid", func(w http.ResponseWriter, r *http.Request) {
 		token, err := github.GetRunnerRegistrationToken()
 		if err != nil {
+			auditToken(r, "", err)
 			http.Error(w, err.Error(), 500)
 			return
 		}
@@ -101,7 +231,8 @@ func main() {
 		}
 
 		data, _ := json.Marshal(payload)
-		resp, err := http.Post("http://localhost:8081/register-hybrid", "application/json", bytes.NewBuffer(data))
+		resp, err := controller.PostJSON(controller.RunnerURL("localhost", "8081", "/register-hybrid"), data)
+		auditToken(r, "/register-hybrid", err)
 		if err != nil {
 			http.Error(w, "failed to send token to runner", 500)
 			return
@@ -113,12 +244,22 @@ func main() {
 
 	// Daftar routes (semua sebelum ListenAndServe)
 	http.HandleFunc("/github/webhook", github.WebhookHandler)
-	controller.RegisterHTTPRoutes()   // /jobs
-	controller.RegisterRunnerRoutes() // /heartbeat, /runners
-	controller.RegisterResultRoute()  // /job/result
-	controller.ExposeMetrics()        //metrics
+	github.Reconcile.Start()
+	controller.RegisterHTTPRoutes()                    // /jobs
+	controller.RegisterRunnerRoutes()                  // /heartbeat, /runners
+	controller.RegisterResultRoute()                   // /job/result
+	controller.RegisterAgentRoutes()                   // /vm/heartbeat, /agents
+	controller.RegisterRolloutRoutes()                 // /runner-version
+	controller.RegisterOpsRoutes()                     // /capacity, /scale, drain/remove
+	http.Handle("/api/v1/", api.NewV1())               // versioned admin API
+	http.Handle(dashboard.Prefix, dashboard.Handler()) // /ui/ web dashboard
+	controller.ExposeMetrics()                         //metrics
+	if cfg.MetricsListen != "" {
+		controller.ServeMetrics(cfg.MetricsListen)
+	}
 	// controller.AutoResetStuckRunners() // add this line ✅
 	http.HandleFunc("/github/token", github.TokenHandler)
+	http.HandleFunc("/enroll", authSrv.EnrollHandler)
 	controller.StartJobQueueListener()
 	controller.StartPoller()
 
@@ -126,7 +267,35 @@ func main() {
 	controller.StartDispatcher()
 	// controller.AutoResetStuckRunners()
 
-	port := ":8080"
-	log.Printf("🚀 Towerd (Integration + Queue + Dispatcher + Callback) running on %s", port)
-	log.Fatal(http.ListenAndServe(port, nil))
+	port := cfg.Listen
+	logger.Info("towerd listening", "addr", port)
+	handler := tracing.Middleware(authSrv.Protect(http.DefaultServeMux, auth.Policy{
+		// /ui/ hanya asset statis; data dashboard diambil dari /api/v1 dengan admin token
+		Public:  []string{"/github/webhook", "/enroll", dashboard.Prefix},
+		Clients: []string{"/github/token", "/vm/heartbeat", "/heartbeat", "/job/result"},
+	}))
+	srv := &http.Server{Addr: port, Handler: handler, TLSConfig: tlsCfg}
+	if tlsCfg != nil {
+		logger.Info("mTLS enabled")
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	shutdownTracing(context.Background())
+	// logging.Fatal memanggil os.Exit, jadi defer tidak akan jalan
+	if cerr := auditLog.Close(); cerr != nil {
+		logger.Warn("cannot close audit log", "path", cfg.Audit.Path, "err", cerr)
+	}
+	logging.Fatal(logger, "server stopped", "err", err)
+}
+
+// auditToken mencatat token registrasi yang dibagikan lewat endpoint lama;
+// sentTo adalah path runnerd yang menerima token (kosong kalau token
+// dibalas langsung ke pemanggil)
+func auditToken(r *http.Request, sentTo string, err error) {
+	params := map[string]interface{}{"endpoint": r.URL.Path, "remote_addr": r.RemoteAddr}
+	if sentTo != "" {
+		params["sent_to"] = sentTo
+	}
+	audit.Record(controller.Actor(r), audit.TokenIssue, github.TokenRegistration, params, err)
 }
@@ -1,35 +1,71 @@
 package agent
 
 import (
-	"log"
+	"crypto/x509"
+	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/ridwandwisiswanto/tcr/internal/github"
+	"github.com/ridwandwisiswanto/tcr/internal/logging"
+	"github.com/ridwandwisiswanto/tcr/internal/pki"
+)
+
+const (
+	StateRunning   = "running"
+	StateDraining  = "draining"
+	StateUpgrading = "upgrading"
+	StateStopped   = "stopped"
 )
 
 type Agent struct {
-	runners []*Runner
-	config  Config
+	mu       sync.Mutex
+	runners  []*Runner
+	config   Config
+	draining bool
+
+	// state rolling upgrade (lihat upgrade.go)
+	upgrading     bool
+	prefetching   bool
+	upgradeFailed string
+
+	// hasil WorkspaceMonitor terakhir (lihat workspace.go)
+	disk      DiskReport
+	replacing bool
+
+	// sertifikat mTLS dari Tower (nil kalau TOWER_CA_FILE tidak di-set)
+	certs  *pki.Rotator
+	caPool *x509.CertPool
 }
 
-func NewAgent() *Agent {
-	cfg := LoadConfig()
+func NewAgent(cfg Config) *Agent {
 
 	// ✅ Auto-fix runner path (absolute)
 	if !strings.HasPrefix(cfg.RunnerDir, "/") {
 		cwd, _ := os.Getwd()
 		cfg.RunnerDir = filepath.Join(cwd, cfg.RunnerDir)
-		log.Printf("📂 Fixed relative runner path → %s", cfg.RunnerDir)
+		logger.Info("runner dir made absolute", "runner_dir", cfg.RunnerDir)
+	}
+
+	// 📦 Default cache tarball runner dibagi semua instance di VM ini
+	if cfg.RunnerCacheDir == "" {
+		cfg.RunnerCacheDir = filepath.Join(cfg.RunnerDir, "_cache")
+	}
+
+	if cfg.LogDir == "" {
+		cfg.LogDir = filepath.Join(cfg.RunnerDir, "_logs")
 	}
 
 	// ✅ Pastikan folder ada (create jika belum)
 	if _, err := os.Stat(cfg.RunnerDir); os.IsNotExist(err) {
-		log.Printf("⚠️ Runner dir not found, creating at %s", cfg.RunnerDir)
+		logger.Warn("runner dir not found, creating it", "runner_dir", cfg.RunnerDir)
 		if err := os.MkdirAll(cfg.RunnerDir, 0755); err != nil {
-			log.Fatalf("❌ Cannot create runner dir: %v", err)
+			logging.Fatal(logger, "cannot create runner dir", "runner_dir", cfg.RunnerDir, "err", err)
 		}
 	}
 
@@ -40,54 +76,178 @@ func NewAgent() *Agent {
 }
 
 func (a *Agent) Run() error {
-	log.Printf("🚀 Starting tower-agentd...")
-	log.Printf("🧩 agentd started (max runners: %d)", a.config.MaxRunners)
+	logger.Info("agentd started", "max_runners", a.config.MaxRunners, "runner_version", a.config.RunnerVersion)
+
+	// 🔐 credential plaintext versi lama dipindah ke store terenkripsi
+	github.MigrateLegacyCredential()
+
+	// 🔏 mTLS ke Tower: enroll sertifikat client sebelum panggilan pertama
+	if a.config.CAFile != "" {
+		pool, err := pki.LoadPool(a.config.CAFile)
+		if err != nil {
+			return fmt.Errorf("load tower CA: %w", err)
+		}
+		certs, err := towerClient(a.config).EnableMTLS(pool, a.config.TLSHosts)
+		if err != nil {
+			return fmt.Errorf("enroll certificate: %w", err)
+		}
+		a.certs, a.caPool = certs, pool
+	}
 
-	// 1️⃣ Pastikan binary runner tersedia
-	if err := EnsureRunnerBinary(a.config.RunnerDir, a.config.RunnerVersion); err != nil {
-		log.Printf("⚠️ EnsureRunnerBinary failed: %v", err)
+	// 1️⃣ Pastikan tarball runner tersedia di cache (download sekali per VM)
+	if _, err := FetchRunnerTarball(a.config); err != nil {
+		logger.Error("cannot fetch runner tarball", "version", a.config.RunnerVersion, "err", err)
 		return err
 	}
 
+	// 🛑 Tangkap SIGTERM/SIGINT sedini mungkin supaya spawn bisa dihentikan
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
 	// 2️⃣ Spawn runners
 	for i := 1; i <= a.config.MaxRunners; i++ {
+		if len(sig) > 0 {
+			break
+		}
 		r, err := SpawnRunner(i, a.config)
 		if err != nil {
-			log.Printf("⚠️ runner-%d failed spawn: %v", i, err)
+			logger.Error("runner spawn failed", "index", i, "err", err)
 			continue
 		}
+		a.mu.Lock()
 		a.runners = append(a.runners, r)
+		a.mu.Unlock()
 	}
 
-	// 3️⃣ Kirim heartbeat loop
+	// 🧹 Bersihkan core versi lama yang sudah tidak dipakai instance
+	GCRunnerCores(a.config.RunnerDir, a.config.RunnerVersion)
+
+	// 3️⃣ Kirim heartbeat loop + ukur disk workspace + API log runner
+	go a.ServeAPI()
+	go a.WorkspaceMonitor()
 	go a.HeartbeatLoop()
 
-	// 4️⃣ Monitor idle
-	a.MonitorIdle()
+	// 4️⃣ Monitor idle, sampai idle monitor selesai atau ada signal
+	idleDone := make(chan struct{})
+	go func() {
+		a.MonitorIdle()
+		close(idleDone)
+	}()
+
+	select {
+	case s := <-sig:
+		drain := a.Config().DrainTimeout
+		logger.Info("signal received, draining runners", "signal", s.String(), "timeout_sec", drain)
+		a.Drain(time.Duration(drain) * time.Second)
+	case <-idleDone:
+	}
 
 	return nil
 }
 
+// State mengembalikan state agent saat ini untuk heartbeat
+func (a *Agent) State() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.draining {
+		return StateDraining
+	}
+	if a.upgrading {
+		return StateUpgrading
+	}
+	return StateRunning
+}
+
+// Drain berhenti menerima job baru, menunggu job yang sedang jalan selesai
+// (maksimal sampai timeout), mematikan proses runner, deregister dari GitHub,
+// lalu melaporkan state akhir ke Tower.
+func (a *Agent) Drain(timeout time.Duration) {
+	a.mu.Lock()
+	a.draining = true
+	pending := append([]*Runner(nil), a.runners...)
+	a.mu.Unlock()
+
+	if err := a.sendHeartbeat(StateDraining); err != nil {
+		logger.Warn("cannot report draining state", "err", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for len(pending) > 0 {
+		var busy []*Runner
+		for _, r := range pending {
+			// status yang tidak bisa dibaca dianggap sibuk sampai deadline,
+			// supaya job yang sedang jalan tidak ikut dimatikan
+			isBusy, err := isRunnerBusy(r.Name)
+			if err != nil {
+				logger.Warn("cannot check runner busy state", "runner_id", r.Name, "err", err)
+				isBusy = true
+			}
+			if isBusy && time.Now().Before(deadline) {
+				busy = append(busy, r)
+				continue
+			}
+			if isBusy {
+				logger.Warn("drain deadline exceeded, stopping busy runner", "runner_id", r.Name, "busy_state_known", err == nil)
+			}
+			// runner idle langsung dimatikan supaya tidak ambil job baru
+			r.Stop(10 * time.Second)
+		}
+
+		pending = busy
+		if len(pending) > 0 {
+			logger.Info("waiting for busy runners", "busy", len(pending))
+			time.Sleep(runnerPollInterval)
+		}
+	}
+
+	a.DeregisterAll()
+
+	a.mu.Lock()
+	a.runners = nil
+	a.mu.Unlock()
+
+	if err := a.sendHeartbeat(StateStopped); err != nil {
+		logger.Warn("cannot report final state", "err", err)
+	}
+	logger.Info("drain complete")
+}
+
 func (a *Agent) MonitorIdle() {
 	for {
 		time.Sleep(15 * time.Second)
 
+		a.mu.Lock()
+		if a.draining {
+			a.mu.Unlock()
+			return
+		}
+		if a.upgrading {
+			a.mu.Unlock()
+			continue
+		}
+		idle := AllRunnersIdle(a.runners, a.config.IdleTimeout)
+		autoShutdown := a.config.AutoShutdown
+		a.mu.Unlock()
+
 		// Jika semua runner idle
-		if AllRunnersIdle(a.runners, a.config.IdleTimeout) {
-			log.Println("🧹 All runners idle — shutting down soon")
+		if idle {
+			logger.Info("all runners idle, deregistering")
 			a.DeregisterAll()
 
 			// 🔒 Kosongkan daftar runner agar tidak loop terus
+			a.mu.Lock()
 			a.runners = nil
+			a.mu.Unlock()
 
 			// 🚀 Kalau auto-shutdown aktif, hentikan VM
-			if a.config.AutoShutdown {
-				log.Println("💤 Auto-shutdown enabled, exiting agentd...")
+			if autoShutdown {
+				logger.Info("auto shutdown enabled, exiting")
 				os.Exit(0)
 			}
 
 			// 🧘 Stop loop supaya gak spam deregister terus
-			log.Println("🧘 All runners removed, stopping idle monitor loop.")
+			logger.Info("all runners removed, idle monitor stopped")
 			break
 		}
 	}
@@ -95,25 +255,37 @@ func (a *Agent) MonitorIdle() {
 
 // 🧹 DeregisterAll akan hapus semua runner di VM ini dari GitHub
 func (a *Agent) DeregisterAll() {
-	log.Println("🧹 Deregistering all runners (using GitHub API)")
+	logger.Info("deregistering all runners")
 
-	for _, r := range a.runners {
-		// ambil ID dari nama (kita bisa simpan ID di struct Runner waktu spawn)
-		runnerID, err := github.GetRunnerIDByName(r.Name)
-		if err != nil {
-			log.Printf("⚠️ Cannot find GitHub runner ID for %s: %v", r.Name, err)
-			continue
-		}
+	a.mu.Lock()
+	runners := append([]*Runner(nil), a.runners...)
+	a.mu.Unlock()
 
-		if err := github.RemoveRunnerByID(runnerID); err != nil {
-			log.Printf("❌ Failed to remove runner %s (id:%d): %v", r.Name, r.ID, err)
-		} else {
-			log.Printf("🗑 Runner %s (id:%d) removed successfully via GitHub API", r.Name, r.ID)
-		}
+	for _, r := range runners {
+		unregister(r)
+	}
+}
+
+// deregisterRunner menghapus satu runner dari GitHub berdasarkan namanya
+func deregisterRunner(r *Runner) {
+	// ambil ID dari nama (kita bisa simpan ID di struct Runner waktu spawn)
+	runnerID, err := github.GetRunnerIDByName(r.Name)
+	if err != nil {
+		logger.Warn("cannot find GitHub runner ID", "runner_id", r.Name, "err", err)
+		return
+	}
+
+	if err := github.RemoveRunnerByID(runnerID); err != nil {
+		logger.Error("runner removal failed", "runner_id", r.Name, "github_runner_id", runnerID, "err", err)
+	} else {
+		logger.Info("runner removed from GitHub", "runner_id", r.Name, "github_runner_id", runnerID)
+		github.DeleteRunnerCredential(r.Name)
 	}
 }
 
 // ✅ Getter Config() untuk akses config dari luar package
 func (a *Agent) Config() Config {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	return a.config
 }
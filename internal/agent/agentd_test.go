@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrain_WaitsWhileBusyStateUnknown(t *testing.T) {
+	installFake(t, &fakeGitHub{})
+	var calls int
+	isRunnerBusy = func(string) (bool, error) {
+		calls++
+		if calls < 3 {
+			return false, errors.New("github unavailable")
+		}
+		return false, nil
+	}
+	a := &Agent{runners: []*Runner{{ID: 1, Name: "vm-agent-01"}}}
+
+	a.Drain(time.Minute)
+	if calls != 3 {
+		t.Fatalf("runner stopped after %d checks, before GitHub reported it idle", calls)
+	}
+	if len(a.runners) != 0 {
+		t.Fatalf("runners left after drain: %v", a.runners)
+	}
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/ridwandwisiswanto/tcr/internal/auth"
+	"github.com/ridwandwisiswanto/tcr/internal/github"
+	"github.com/ridwandwisiswanto/tcr/internal/pki"
+	"github.com/ridwandwisiswanto/tcr/internal/runnerlog"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
+)
+
+// ServeAPI menjalankan HTTP API kecil agentd (log runner yang di-proxy oleh
+// Tower, pembatalan job, dan /metrics). Hanya Tower yang boleh memanggil: dengan mTLS lewat
+// sertifikat role tower, tanpa mTLS lewat tanda tangan bootstrap secret
+// (auth.RequireTower). Set metrics_listen supaya Prometheus bisa scrape
+// /metrics di listener terpisah tanpa credential.
+func (a *Agent) ServeAPI() {
+	prometheus.MustRegister(collector{a})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/logs", runnerlog.Handler(a.runnerLogPath))
+	mux.HandleFunc("/job/cancel", a.handleJobCancel)
+	mux.Handle("/metrics", promhttp.Handler())
+
+	if addr := a.config.MetricsListen; addr != "" {
+		go func() {
+			metrics := http.NewServeMux()
+			metrics.Handle("/metrics", promhttp.Handler())
+			logger.Info("metrics listening", "addr", addr)
+			if err := http.ListenAndServe(addr, metrics); err != nil {
+				logger.Warn("metrics listener stopped", "err", err)
+			}
+		}()
+	}
+
+	// traceparent dari Tower (proxy log) jadi parent span di agentd
+	srv := &http.Server{Addr: a.config.APIListen, Handler: tracing.Middleware(mux)}
+	var err error
+	if a.certs != nil {
+		srv.Handler = tracing.Middleware(pki.RequirePeerRole(mux, pki.RoleTower))
+		srv.TLSConfig = pki.ServerConfig(a.certs, a.caPool, true)
+		logger.Info("API listening", "addr", a.config.APIListen, "mtls", true)
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		srv.Handler = tracing.Middleware(auth.RequireTower(mux, a.config.BootstrapSecret))
+		logger.Info("API listening", "addr", a.config.APIListen, "mtls", false)
+		err = srv.ListenAndServe()
+	}
+	if err != nil {
+		logger.Warn("API stopped", "err", err)
+	}
+}
+
+// handleJobCancel melayani POST /job/cancel {"id": "...", "runner": "<nama>"}
+// dari Tower: Runner.Worker di runner itu dihentikan, runner-nya tetap hidup
+func (a *Agent) handleJobCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID     string `json:"id"`
+		Runner string `json:"runner"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Runner == "" {
+		http.Error(w, "missing runner", http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	var pgid int
+	for _, rn := range a.runners {
+		if rn.Name == req.Runner && rn.cmd != nil && rn.cmd.Process != nil {
+			pgid = rn.cmd.Process.Pid
+		}
+	}
+	a.mu.Unlock()
+	if pgid == 0 {
+		http.Error(w, "unknown runner", http.StatusNotFound)
+		return
+	}
+
+	aborted, err := github.AbortRunnerJob(pgid, 10*time.Second)
+	if err != nil {
+		logger.Error("cannot abort job", "job_id", req.ID, "runner_id", req.Runner, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("job aborted", "job_id", req.ID, "runner_id", req.Runner, "workers", aborted)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"aborted": aborted})
+}
+
+// runnerLogPath mencari file log runner berdasarkan nama
+func (a *Agent) runnerLogPath(name string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, r := range a.runners {
+		if r.Name == name {
+			return r.LogPath, true
+		}
+	}
+	return "", false
+}
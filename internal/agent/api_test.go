@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestHandleJobCancel_StopsTheRunnerWorker(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if _, serr := os.Stat("/proc"); err != nil || serr != nil {
+		t.Skip("needs sleep and /proc")
+	}
+	b, _ := os.ReadFile(sleep)
+	worker := filepath.Join(t.TempDir(), "Runner.Worker")
+	if err := os.WriteFile(worker, b, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(worker, "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	defer cmd.Process.Kill()
+
+	a := &Agent{runners: []*Runner{{Name: "vm-agent-01", cmd: cmd}}}
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		a.handleJobCancel(rec, httptest.NewRequest("POST", "/job/cancel", bytes.NewBufferString(body)))
+		return rec
+	}
+
+	if rec := post(`{"id":"j1","runner":"other"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown runner, got %d", rec.Code)
+	}
+	rec := post(`{"id":"j1","runner":"vm-agent-01"}`)
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"aborted\":1}\n" {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body)
+	}
+	if err := <-exited; err == nil {
+		t.Fatalf("expected the worker to be terminated")
+	}
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ResourceLimits adalah batas resource per runner (0 = tidak dibatasi)
+type ResourceLimits struct {
+	CPU      float64 `json:"cpu" yaml:"cpu"`             // jumlah core, boleh pecahan (0.5)
+	MemoryMB int     `json:"memory_mb" yaml:"memory_mb"` // memory.max
+	Pids     int     `json:"pids" yaml:"pids"`           // pids.max
+}
+
+// CgroupUsage adalah pemakaian resource satu runner dari cgroup-nya
+type CgroupUsage struct {
+	MemoryMB int64 `json:"memory_mb"`
+	CPUSec   int64 `json:"cpu_sec"`
+	Pids     int64 `json:"pids"`
+	OOMKills int64 `json:"oom_kills"`
+}
+
+// parseRunnerLimits membaca format RUNNER_LIMITS:
+//
+//	default:cpu=2,memory=4096,pids=2048;gpu:cpu=8,memory=32768
+//
+// key sebelum ":" adalah nama pool/label runner.
+func parseRunnerLimits(s string) (map[string]ResourceLimits, error) {
+	out := map[string]ResourceLimits{}
+	for _, group := range strings.Split(s, ";") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+		label, spec, ok := strings.Cut(group, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid limits %q: expected <label>:<key>=<value>,...", group)
+		}
+
+		var l ResourceLimits
+		for _, kv := range strings.Split(spec, ",") {
+			k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid limit %q for %s", kv, label)
+			}
+			var err error
+			switch k {
+			case "cpu":
+				l.CPU, err = strconv.ParseFloat(v, 64)
+			case "memory":
+				l.MemoryMB, err = strconv.Atoi(v)
+			case "pids":
+				l.Pids, err = strconv.Atoi(v)
+			default:
+				err = fmt.Errorf("unknown key")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid limit %q for %s: %v", kv, label, err)
+			}
+		}
+		out[strings.TrimSpace(label)] = l
+	}
+	return out, nil
+}
+
+// limitsFor memilih limit berdasarkan label runner pertama yang punya entry,
+// fallback ke "default".
+func limitsFor(cfg Config) (ResourceLimits, bool) {
+	for _, label := range cfg.RunnerLabels {
+		if l, ok := cfg.RunnerLimits[label]; ok {
+			return l, true
+		}
+	}
+	l, ok := cfg.RunnerLimits["default"]
+	return l, ok
+}
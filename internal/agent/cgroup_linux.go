@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// Cgroup adalah slice cgroup v2 milik satu runner
+type Cgroup struct {
+	Path string
+	fd   *os.File
+}
+
+// setupCgroup membuat <cgroupRoot>/<parent>/<name> dan memasang limit
+func setupCgroup(name string, cfg Config, l ResourceLimits) (*Cgroup, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 not available: %w", err)
+	}
+
+	parent := filepath.Join(cgroupRoot, cfg.CgroupParent)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, err
+	}
+
+	// aktifkan controller dari root sampai parent supaya child bisa diberi limit
+	rel, _ := filepath.Rel(cgroupRoot, parent)
+	dir := cgroupRoot
+	for _, part := range append([]string{""}, strings.Split(rel, string(os.PathSeparator))...) {
+		dir = filepath.Join(dir, part)
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+cpu +memory +pids"), 0644); err != nil {
+			return nil, fmt.Errorf("enable controllers in %s: %w", dir, err)
+		}
+	}
+
+	path := filepath.Join(parent, name)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+
+	writes := map[string]string{}
+	if l.CPU > 0 {
+		writes["cpu.max"] = fmt.Sprintf("%d 100000", int64(l.CPU*100000))
+	}
+	if l.MemoryMB > 0 {
+		writes["memory.max"] = strconv.FormatInt(int64(l.MemoryMB)<<20, 10)
+	}
+	if l.Pids > 0 {
+		writes["pids.max"] = strconv.Itoa(l.Pids)
+	}
+	for file, v := range writes {
+		if err := os.WriteFile(filepath.Join(path, file), []byte(v), 0644); err != nil {
+			return nil, fmt.Errorf("set %s: %w", file, err)
+		}
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("cgroup created", "path", path, "cpu", l.CPU, "memory_mb", l.MemoryMB, "pids", l.Pids)
+	return &Cgroup{Path: path, fd: fd}, nil
+}
+
+// apply membuat proses cmd langsung lahir di dalam cgroup (clone3 CLONE_INTO_CGROUP),
+// jadi seluruh process tree run.sh ikut terbatasi.
+func (c *Cgroup) apply(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(c.fd.Fd())
+}
+
+// Usage membaca pemakaian resource dari file-file cgroup
+func (c *Cgroup) Usage() CgroupUsage {
+	var u CgroupUsage
+	u.MemoryMB = readCgroupInt(filepath.Join(c.Path, "memory.current")) >> 20
+	u.Pids = readCgroupInt(filepath.Join(c.Path, "pids.current"))
+	u.CPUSec = readCgroupKey(filepath.Join(c.Path, "cpu.stat"), "usage_usec") / 1e6
+	u.OOMKills = readCgroupKey(filepath.Join(c.Path, "memory.events"), "oom_kill")
+	return u
+}
+
+// Remove menghapus cgroup setelah semua proses di dalamnya keluar
+func (c *Cgroup) Remove() {
+	c.fd.Close()
+	if err := os.Remove(c.Path); err != nil {
+		logger.Warn("cannot remove cgroup", "path", c.Path, "err", err)
+	}
+}
+
+func readCgroupInt(path string) int64 {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	v, _ := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	return v
+}
+
+func readCgroupKey(path, key string) int64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && fields[0] == key {
+			v, _ := strconv.ParseInt(fields[1], 10, 64)
+			return v
+		}
+	}
+	return 0
+}
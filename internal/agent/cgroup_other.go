@@ -0,0 +1,23 @@
+//go:build !linux
+
+package agent
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Cgroup hanya didukung di Linux (cgroup v2)
+type Cgroup struct {
+	Path string
+}
+
+func setupCgroup(name string, cfg Config, l ResourceLimits) (*Cgroup, error) {
+	return nil, fmt.Errorf("cgroup v2 is only supported on linux")
+}
+
+func (c *Cgroup) apply(cmd *exec.Cmd) {}
+
+func (c *Cgroup) Usage() CgroupUsage { return CgroupUsage{} }
+
+func (c *Cgroup) Remove() {}
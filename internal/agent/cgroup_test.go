@@ -0,0 +1,29 @@
+package agent
+
+import "testing"
+
+func TestParseRunnerLimits(t *testing.T) {
+	limits, err := parseRunnerLimits("default:cpu=2,memory=4096,pids=2048; gpu:cpu=0.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := limits["default"]; got != (ResourceLimits{CPU: 2, MemoryMB: 4096, Pids: 2048}) {
+		t.Fatalf("unexpected default limits: %+v", got)
+	}
+	if got := limits["gpu"]; got.CPU != 0.5 || got.MemoryMB != 0 {
+		t.Fatalf("unexpected gpu limits: %+v", got)
+	}
+
+	cfg := Config{RunnerLabels: []string{"linux", "gpu"}, RunnerLimits: limits}
+	if l, ok := limitsFor(cfg); !ok || l.CPU != 0.5 {
+		t.Fatalf("expected gpu limits for gpu label, got %+v (%v)", l, ok)
+	}
+}
+
+func TestParseRunnerLimits_Invalid(t *testing.T) {
+	for _, s := range []string{"cpu=2", "default:cpu=two", "default:disk=10"} {
+		if _, err := parseRunnerLimits(s); err == nil {
+			t.Fatalf("expected error for %q, got nil", s)
+		}
+	}
+}
@@ -1,44 +1,173 @@
 package agent
 
 import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
 	"os"
-	"strconv"
+	"strings"
+
+	"github.com/ridwandwisiswanto/tcr/internal/config"
+	"github.com/ridwandwisiswanto/tcr/internal/github"
+	"github.com/ridwandwisiswanto/tcr/internal/logging"
 )
 
+// Config adalah schema section "agent" di file config. Tag env menandai env
+// yang meng-override nilai dari file.
 type Config struct {
-	TowerURL          string
-	RunnerDir         string
-	RepoFullName      string
-	InstanceName      string
-	MaxRunners        int
-	HeartbeatInterval int
-	IdleTimeout       int
-	RunnerVersion     string
-	AutoShutdown      bool
+	TowerURL          string                    `yaml:"tower_url" env:"TOWER_URL"`
+	RunnerDir         string                    `yaml:"runner_dir" env:"RUNNER_DIR"`
+	RepoFullName      string                    `yaml:"repo"`
+	InstanceName      string                    `yaml:"instance_name" env:"VM_NAME"`
+	MaxRunners        int                       `yaml:"max_runners" env:"MAX_RUNNERS_PER_VM"`
+	HeartbeatInterval int                       `yaml:"heartbeat_interval_sec" env:"HEARTBEAT_INTERVAL"`
+	IdleTimeout       int                       `yaml:"idle_timeout_sec" env:"VM_IDLE_TIMEOUT_SEC"`
+	RunnerVersion     string                    `yaml:"runner_version" env:"GH_RUNNER_VERSION"`
+	RunnerArch        string                    `yaml:"runner_arch" env:"GH_RUNNER_ARCH"`
+	RunnerSHA256      string                    `yaml:"runner_sha256" env:"GH_RUNNER_SHA256"`
+	RunnerMirrorURL   string                    `yaml:"runner_mirror_url" env:"GH_RUNNER_MIRROR_URL"`
+	RunnerCacheDir    string                    `yaml:"runner_cache_dir" env:"RUNNER_CACHE_DIR"`
+	AutoShutdown      bool                      `yaml:"auto_shutdown" env:"AUTO_SHUTDOWN_ON_IDLE"`
+	DrainTimeout      int                       `yaml:"drain_timeout_sec" env:"DRAIN_TIMEOUT_SEC"`
+	OnlineTimeout     int                       `yaml:"online_timeout_sec" env:"RUNNER_ONLINE_TIMEOUT_SEC"`
+	WorkspaceCleanup  bool                      `yaml:"workspace_cleanup" env:"WORKSPACE_CLEANUP"`
+	WorkspaceKeep     []string                  `yaml:"workspace_keep" env:"WORKSPACE_KEEP"`
+	WorkspaceMaxMB    int                       `yaml:"workspace_max_mb" env:"WORKSPACE_MAX_MB"`
+	WorkspaceTotalMB  int                       `yaml:"workspace_total_max_mb" env:"WORKSPACE_TOTAL_MAX_MB"`
+	DiskPressurePct   int                       `yaml:"disk_pressure_pct" env:"DISK_PRESSURE_PCT"`
+	RunnerLabels      []string                  `yaml:"runner_labels" env:"RUNNER_LABELS"`
+	RunnerLimits      map[string]ResourceLimits `yaml:"runner_limits"`
+	CgroupEnabled     bool                      `yaml:"cgroup_enabled" env:"CGROUP_ENABLED"`
+	CgroupParent      string                    `yaml:"cgroup_parent" env:"CGROUP_PARENT"`
+	LogDir            string                    `yaml:"log_dir" env:"RUNNER_LOG_DIR"`
+	LogMaxMB          int                       `yaml:"log_max_mb" env:"RUNNER_LOG_MAX_MB"`
+	LogBackups        int                       `yaml:"log_backups" env:"RUNNER_LOG_BACKUPS"`
+	APIListen         string                    `yaml:"api_listen" env:"AGENT_LISTEN_ADDR"`
+	MetricsListen     string                    `yaml:"metrics_listen" env:"AGENT_METRICS_ADDR"`
+	BootstrapSecret   string                    `yaml:"-" env:"TOWER_BOOTSTRAP_SECRET"`
+	CAFile            string                    `yaml:"ca_file" env:"TOWER_CA_FILE"`
+	TLSHosts          []string                  `yaml:"tls_hosts" env:"AGENT_TLS_HOSTS"`
+	Tracing           config.Tracing            `yaml:"tracing"`
+	Logging           config.Logging            `yaml:"logging"`
 }
 
-func LoadConfig() Config {
+// DefaultConfig mengembalikan nilai default agentd
+func DefaultConfig() Config {
 	return Config{
-		TowerURL:          getEnv("TOWER_URL", "http://localhost:8080"),
-		RunnerDir:         getEnv("RUNNER_DIR", "./actions-runner"),
-		RepoFullName:      getEnv("GITHUB_OWNER", "user") + "/" + getEnv("GITHUB_REPO", "demo"),
-		InstanceName:      getEnv("VM_NAME", "local-vm"),
-		MaxRunners:        atoi(getEnv("MAX_RUNNERS_PER_VM", "5")),
-		HeartbeatInterval: atoi(getEnv("HEARTBEAT_INTERVAL", "15")),
-		IdleTimeout:       atoi(getEnv("VM_IDLE_TIMEOUT_SEC", "120")),
-		RunnerVersion:     getEnv("GH_RUNNER_VERSION", "2.317.0"),
-		AutoShutdown:      getEnv("AUTO_SHUTDOWN_ON_IDLE", "false") == "true",
+		TowerURL:          "http://localhost:8080",
+		RunnerDir:         "./actions-runner",
+		RepoFullName:      "user/demo",
+		InstanceName:      "local-vm",
+		MaxRunners:        5,
+		HeartbeatInterval: 15,
+		IdleTimeout:       120,
+		RunnerVersion:     github.DefaultRunnerVersion,
+		RunnerArch:        RunnerArch(),
+		DrainTimeout:      300,
+		OnlineTimeout:     120,
+		WorkspaceCleanup:  true,
+		WorkspaceKeep:     []string{"_tool", "_actions"},
+		WorkspaceMaxMB:    10240,
+		DiskPressurePct:   90,
+		RunnerLimits:      map[string]ResourceLimits{},
+		CgroupEnabled:     true,
+		CgroupParent:      "tcr.slice",
+		LogMaxMB:          10,
+		LogBackups:        3,
+		APIListen:         ":8082",
+		TLSHosts:          []string{hostname()},
+		Tracing:           config.DefaultTracing(),
+		Logging:           config.DefaultLogging(),
+	}
+}
+
+// LoadConfig memuat default → file config (section "agent") → env lalu memvalidasi
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if err := config.Load(path, "agent", &cfg); err != nil {
+		return cfg, err
+	}
+
+	// repo dibentuk dari dua env
+	owner, repo, _ := strings.Cut(cfg.RepoFullName, "/")
+	if v := os.Getenv("GITHUB_OWNER"); v != "" {
+		owner = v
+	}
+	if v := os.Getenv("GITHUB_REPO"); v != "" {
+		repo = v
+	}
+	cfg.RepoFullName = owner + "/" + repo
+
+	if s := os.Getenv("RUNNER_LIMITS"); s != "" {
+		limits, err := parseRunnerLimits(s)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid configuration:\n  - RUNNER_LIMITS: %v", err)
+		}
+		cfg.RunnerLimits = limits
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (c Config) Validate() error {
+	var errs config.Errors
+	if u, err := url.Parse(c.TowerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.Addf("agent.tower_url (TOWER_URL) must be an http(s) URL, got %q", c.TowerURL)
+	}
+	errs.Check(c.RunnerDir != "", "agent.runner_dir must not be empty")
+	errs.Check(strings.Count(c.RepoFullName, "/") == 1 && !strings.HasPrefix(c.RepoFullName, "/") && !strings.HasSuffix(c.RepoFullName, "/"),
+		"agent.repo must be <owner>/<repo>, got %q", c.RepoFullName)
+	errs.Check(c.InstanceName != "", "agent.instance_name must not be empty")
+	errs.Check(c.MaxRunners > 0, "agent.max_runners must be > 0, got %d", c.MaxRunners)
+	errs.Check(c.HeartbeatInterval > 0, "agent.heartbeat_interval_sec must be > 0, got %d", c.HeartbeatInterval)
+	errs.Check(c.IdleTimeout >= 0, "agent.idle_timeout_sec must be >= 0, got %d", c.IdleTimeout)
+	errs.Check(c.RunnerVersion != "", "agent.runner_version must not be empty")
+	errs.Check(c.RunnerArch == "x64" || c.RunnerArch == "arm64" || c.RunnerArch == "arm",
+		"agent.runner_arch must be x64, arm64 or arm, got %q", c.RunnerArch)
+	if c.RunnerSHA256 != "" {
+		b, err := hex.DecodeString(c.RunnerSHA256)
+		errs.Check(err == nil && len(b) == 32, "agent.runner_sha256 must be 64 hex characters")
+	}
+	errs.Check(c.DrainTimeout >= 0, "agent.drain_timeout_sec must be >= 0, got %d", c.DrainTimeout)
+	errs.Check(c.OnlineTimeout > 0, "agent.online_timeout_sec must be > 0, got %d", c.OnlineTimeout)
+	errs.Check(c.WorkspaceMaxMB >= 0, "agent.workspace_max_mb must be >= 0, got %d", c.WorkspaceMaxMB)
+	errs.Check(c.WorkspaceTotalMB >= 0, "agent.workspace_total_max_mb must be >= 0, got %d", c.WorkspaceTotalMB)
+	errs.Check(c.DiskPressurePct > 0 && c.DiskPressurePct <= 100, "agent.disk_pressure_pct must be 1-100, got %d", c.DiskPressurePct)
+	for label, l := range c.RunnerLimits {
+		errs.Check(l.CPU >= 0 && l.MemoryMB >= 0 && l.Pids >= 0, "agent.runner_limits.%s must not be negative", label)
+	}
+	errs.Check(c.LogMaxMB > 0, "agent.log_max_mb must be > 0, got %d", c.LogMaxMB)
+	errs.Check(c.LogBackups >= 0, "agent.log_backups must be >= 0, got %d", c.LogBackups)
+	errs.Check(c.APIListen != "", "agent.api_listen must not be empty")
+	if c.CAFile != "" {
+		errs.Check(len(c.TLSHosts) > 0, "agent.tls_hosts must list at least one host when ca_file is set")
 	}
+	c.Tracing.Check(&errs, "agent.tracing")
+	c.Logging.Check(&errs, "agent.logging")
+	return errs.Err()
 }
 
-func getEnv(k, def string) string {
-	if v := os.Getenv(k); v != "" {
-		return v
+// Reload menerapkan setting yang aman diganti tanpa restart runner:
+// timeout, batas disk workspace, auto-shutdown, dan level log.
+func (a *Agent) Reload(next Config) {
+	a.mu.Lock()
+	a.config.IdleTimeout = next.IdleTimeout
+	a.config.AutoShutdown = next.AutoShutdown
+	a.config.DrainTimeout = next.DrainTimeout
+	a.config.OnlineTimeout = next.OnlineTimeout
+	a.config.WorkspaceMaxMB = next.WorkspaceMaxMB
+	a.config.WorkspaceTotalMB = next.WorkspaceTotalMB
+	a.config.DiskPressurePct = next.DiskPressurePct
+	a.config.Logging.Level, a.config.Logging.Levels = next.Logging.Level, next.Logging.Levels
+	a.mu.Unlock()
+	if err := logging.Reload(next.Logging); err != nil {
+		logger.Warn("log levels not reloaded", "err", err)
 	}
-	return def
+	logger.Info("config reloaded", "idle_timeout_sec", next.IdleTimeout, "drain_timeout_sec", next.DrainTimeout,
+		"workspace_max_mb", next.WorkspaceMaxMB, "workspace_total_max_mb", next.WorkspaceTotalMB, "disk_pressure_pct", next.DiskPressurePct, "log_level", next.Logging.Level)
 }
 
-func atoi(s string) int {
-	i, _ := strconv.Atoi(s)
-	return i
+func hostname() string {
+	h, _ := os.Hostname()
+	return h
 }
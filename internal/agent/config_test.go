@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeAgentConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tcr.yaml")
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_RepoAndRunnerLimits(t *testing.T) {
+	path := writeAgentConfig(t, `
+agent:
+  repo: acme/web
+  runner_limits:
+    default: {cpu: 1, memory_mb: 2048}
+`)
+	t.Setenv("GITHUB_OWNER", "")
+	t.Setenv("GITHUB_REPO", "api")
+	t.Setenv("RUNNER_LIMITS", "")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// hanya GITHUB_REPO di-set: owner tetap dari file
+	if cfg.RepoFullName != "acme/api" {
+		t.Fatalf("expected acme/api, got %q", cfg.RepoFullName)
+	}
+	if l := cfg.RunnerLimits["default"]; l.CPU != 1 || l.MemoryMB != 2048 {
+		t.Fatalf("file runner_limits not applied: %+v", cfg.RunnerLimits)
+	}
+
+	// RUNNER_LIMITS menggantikan seluruh runner_limits dari file
+	t.Setenv("GITHUB_OWNER", "other")
+	t.Setenv("RUNNER_LIMITS", "gpu: cpu=4,memory=8192,pids=512; default:cpu=0.5")
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]ResourceLimits{"gpu": {CPU: 4, MemoryMB: 8192, Pids: 512}, "default": {CPU: 0.5}}
+	if cfg.RepoFullName != "other/api" || !reflect.DeepEqual(cfg.RunnerLimits, want) {
+		t.Fatalf("env not applied: repo=%q limits=%+v", cfg.RepoFullName, cfg.RunnerLimits)
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	t.Setenv("GITHUB_OWNER", "")
+	t.Setenv("GITHUB_REPO", "")
+
+	cases := []struct {
+		name, body, limits, want string
+	}{
+		{"bad RUNNER_LIMITS", "agent:\n  repo: acme/web\n", "default:cpu", "RUNNER_LIMITS"},
+		{"unknown limit key", "agent:\n  repo: acme/web\n", "default:disk=1", "RUNNER_LIMITS"},
+		{"repo without owner", "agent:\n  repo: web\n", "", "agent.repo"},
+		{"tower url", "agent:\n  repo: acme/web\n  tower_url: tower:8080\n", "", "agent.tower_url"},
+		{"negative limits", "agent:\n  repo: acme/web\n  runner_limits:\n    default: {pids: -1}\n", "", "agent.runner_limits.default"},
+	}
+	for _, c := range cases {
+		t.Setenv("RUNNER_LIMITS", c.limits)
+		_, err := LoadConfig(writeAgentConfig(t, c.body))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: expected error mentioning %q, got %v", c.name, c.want, err)
+		}
+	}
+}
+
+func TestReload_AppliesOnlySafeFields(t *testing.T) {
+	cur := DefaultConfig()
+	a := &Agent{config: cur}
+
+	next := DefaultConfig()
+	next.IdleTimeout, next.AutoShutdown, next.DrainTimeout, next.OnlineTimeout = 60, true, 30, 45
+	next.WorkspaceMaxMB, next.WorkspaceTotalMB, next.DiskPressurePct = 100, 500, 80
+	next.Logging.Level = "debug"
+	// butuh restart runner atau koneksi ulang: tidak boleh ikut berubah
+	next.TowerURL = "https://other-tower:8443"
+	next.RunnerVersion = "9.9.9"
+	next.MaxRunners = 50
+	next.RunnerLabels = []string{"gpu"}
+	next.APIListen = ":9999"
+
+	a.Reload(next)
+	defer a.Reload(cur)
+
+	want := cur
+	want.IdleTimeout, want.AutoShutdown, want.DrainTimeout, want.OnlineTimeout = 60, true, 30, 45
+	want.WorkspaceMaxMB, want.WorkspaceTotalMB, want.DiskPressurePct = 100, 500, 80
+	want.Logging.Level = "debug"
+	if !reflect.DeepEqual(a.config, want) {
+		t.Fatalf("reload applied unexpected fields:\n got %+v\nwant %+v", a.config, want)
+	}
+}
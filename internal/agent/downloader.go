@@ -1,111 +1,283 @@
 package agent
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
 )
 
-func EnsureRunnerBinary(dir, version string) error {
-	if _, err := os.Stat(filepath.Join(dir, "config.sh")); err == nil {
-		log.Printf("✅ Runner binary already exists at %s", dir)
-		return nil
+var downloadClient = &http.Client{Timeout: 10 * time.Minute}
+
+// EnsureRunnerCore memastikan core runner untuk versi di config sudah
+// terekstrak di <RunnerDir>/core/<version>. Satu core dipakai bersama oleh
+// semua instance runner di VM ini.
+func EnsureRunnerCore(cfg Config) (string, error) {
+	coreDir := filepath.Join(cfg.RunnerDir, "core", cfg.RunnerVersion)
+	if _, err := os.Stat(filepath.Join(coreDir, "config.sh")); err == nil {
+		return coreDir, nil
+	}
+
+	tarball, err := FetchRunnerTarball(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(coreDir), 0755); err != nil {
+		return "", err
+	}
+
+	// Ekstrak ke folder sementara lalu rename, supaya core setengah jadi tidak pernah dipakai
+	tmpDir, err := os.MkdirTemp(filepath.Dir(coreDir), ".extract-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	f, err := os.Open(tarball)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	logger.Info("extracting runner core", "tarball", tarball, "dir", coreDir)
+	err = ExtractTarGz(f, tmpDir, ExtractOptions{
+		Progress: func(entries int, written int64) {
+			if entries%1000 == 0 {
+				logger.Debug("extracting runner core", "entries", entries, "written_mb", written>>20)
+			}
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to extract runner: %v", err)
+	}
+
+	_ = os.RemoveAll(coreDir)
+	if err := os.Rename(tmpDir, coreDir); err != nil {
+		return "", err
+	}
+
+	logger.Info("runner core installed", "version", cfg.RunnerVersion, "dir", coreDir)
+	return coreDir, nil
+}
+
+// RunnerArch memetakan GOARCH ke nama arsitektur di release actions/runner
+func RunnerArch() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x64"
+	case "arm64":
+		return "arm64"
+	case "arm":
+		return "arm"
+	}
+	return runtime.GOARCH
+}
+
+func runnerTarballName(version, arch string) string {
+	return fmt.Sprintf("actions-runner-linux-%s-%s.tar.gz", arch, version)
+}
+
+// runnerTarballURL membangun URL download, pakai mirror kalau dikonfigurasi
+func runnerTarballURL(cfg Config) string {
+	name := runnerTarballName(cfg.RunnerVersion, cfg.RunnerArch)
+	if cfg.RunnerMirrorURL != "" {
+		return fmt.Sprintf("%s/v%s/%s", strings.TrimRight(cfg.RunnerMirrorURL, "/"), cfg.RunnerVersion, name)
 	}
+	return fmt.Sprintf("https://github.com/actions/runner/releases/download/v%s/%s", cfg.RunnerVersion, name)
+}
 
-	log.Printf("⬇️ Downloading GitHub runner v%s ...", version)
-	url := fmt.Sprintf("https://github.com/actions/runner/releases/download/v%s/actions-runner-linux-x64-%s.tar.gz", version, version)
+// FetchRunnerTarball memastikan tarball runner untuk versi & arch di config
+// ada di cache bersama dan checksum-nya valid. Mengembalikan path tarball.
+//
+// Checksum yang sudah di-resolve disimpan di <tarball>.sha256, jadi cache hit
+// diverifikasi tanpa akses jaringan (mirror / GitHub API).
+func FetchRunnerTarball(cfg Config) (string, error) {
+	cacheDir := filepath.Join(cfg.RunnerCacheDir, cfg.RunnerVersion, cfg.RunnerArch)
+	target := filepath.Join(cacheDir, runnerTarballName(cfg.RunnerVersion, cfg.RunnerArch))
+	sumFile := target + ".sha256"
 
-	resp, err := http.Get(url)
+	// ✅ Cache hit — tetap verifikasi supaya file korup tidak dipakai
+	if _, err := os.Stat(target); err == nil {
+		expected := cachedRunnerChecksum(cfg, sumFile)
+		if expected == "" {
+			// cache lama tanpa file .sha256
+			if expected, err = expectedRunnerChecksum(cfg); err != nil {
+				return "", fmt.Errorf("resolve checksum: %w", err)
+			}
+		}
+		sum, err := fileSHA256(target)
+		if err == nil && sum == expected {
+			if err := writeChecksumFile(sumFile, sum); err != nil {
+				logger.Warn("cannot save runner checksum", "path", sumFile, "err", err)
+			}
+			logger.Info("runner tarball found in cache", "version", cfg.RunnerVersion, "arch", cfg.RunnerArch, "path", target)
+			return target, nil
+		}
+		logger.Warn("cached runner tarball invalid, downloading again", "path", target, "sha256", sum)
+		_ = os.Remove(target)
+		_ = os.Remove(sumFile)
+	}
+
+	expected, err := expectedRunnerChecksum(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to download runner binary: %v", err)
+		return "", fmt.Errorf("resolve checksum: %w", err)
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", err
+	}
+
+	url := runnerTarballURL(cfg)
+	logger.Info("downloading runner tarball", "version", cfg.RunnerVersion, "arch", cfg.RunnerArch, "url", url)
+
+	resp, err := downloadClient.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download runner binary: %v", err)
 	}
 	defer resp.Body.Close()
 
-	tmp := filepath.Join(dir, fmt.Sprintf("runner-%s.tar.gz", version))
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download %s responded %d", url, resp.StatusCode)
 	}
 
-	out, err := os.Create(tmp)
+	// Tulis ke file sementara di folder yang sama, rename setelah lolos verifikasi
+	tmp, err := os.CreateTemp(cacheDir, ".download-*.partial")
 	if err != nil {
-		return err
+		return "", err
 	}
-	if _, err := io.Copy(out, resp.Body); err != nil {
-		return err
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), resp.Body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to save runner binary: %v", err)
+	}
+
+	if resp.ContentLength > 0 && n != resp.ContentLength {
+		return "", fmt.Errorf("size mismatch: got %d bytes, expected %d", n, resp.ContentLength)
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if sum != expected {
+		return "", fmt.Errorf("checksum mismatch for %s: got %s, expected %s", url, sum, expected)
 	}
-	out.Close()
 
-	log.Printf("📦 Extracting %s ...", tmp)
-	cmd := exec.Command("tar", "xzf", tmp, "-C", dir)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to extract runner: %v", err)
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", err
+	}
+	if err := writeChecksumFile(sumFile, sum); err != nil {
+		logger.Warn("cannot save runner checksum", "path", sumFile, "err", err)
 	}
-	_ = os.Remove(tmp)
 
-	log.Printf("✅ Runner binary v%s extracted to %s", version, dir)
-	return nil
+	logger.Info("runner tarball cached", "version", cfg.RunnerVersion, "arch", cfg.RunnerArch, "bytes", n, "sha256", sum)
+	return target, nil
 }
 
-// EnsureRunnerBinary memastikan binary core runner tersedia di <baseDir>/core.
-// func EnsureRunnerBinary(baseDir, version string) error {
-// 	if baseDir == "" {
-// 		return fmt.Errorf("empty base dir")
-// 	}
-
-// 	coreDir := filepath.Join(baseDir, "core")
-// 	tarPath := filepath.Join(baseDir, fmt.Sprintf("runner-%s.tar.gz", version))
-// 	configPath := filepath.Join(coreDir, "config.sh")
-
-// 	// ✅ 1. Cek apakah binary core sudah tersedia
-// 	if _, err := os.Stat(configPath); err == nil {
-// 		log.Printf("✅ Shared runner binary already exists at %s", coreDir)
-// 		return nil
-// 	}
-
-// 	// ✅ 2. Pastikan folder core bersih dan ada
-// 	if err := os.MkdirAll(coreDir, 0755); err != nil {
-// 		return fmt.Errorf("failed to create core dir: %v", err)
-// 	}
-
-// 	// Hapus sisa-sisa file lama kalau ada (biar gak nested recursive)
-// 	os.RemoveAll(filepath.Join(coreDir, "core"))
-// 	os.RemoveAll(filepath.Join(coreDir, "instances"))
-
-// 	// ✅ 3. Download binary tarball dari GitHub
-// 	url := fmt.Sprintf("https://github.com/actions/runner/releases/download/v%s/actions-runner-linux-x64-%s.tar.gz", version, version)
-// 	log.Printf("⬇️ Downloading GitHub runner v%s ...", version)
-
-// 	resp, err := http.Get(url)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to download runner binary: %v", err)
-// 	}
-// 	defer resp.Body.Close()
-
-// 	out, err := os.Create(tarPath)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to create temp file: %v", err)
-// 	}
-// 	defer out.Close()
-
-// 	if _, err := io.Copy(out, resp.Body); err != nil {
-// 		return fmt.Errorf("failed to save runner binary: %v", err)
-// 	}
-
-// 	// ✅ 4. Ekstrak tarball ke folder core
-// 	log.Printf("📦 Extracting %s → %s", tarPath, coreDir)
-// 	cmd := exec.Command("tar", "xzf", tarPath, "-C", coreDir, "--strip-components=1")
-// 	if err := cmd.Run(); err != nil {
-// 		return fmt.Errorf("failed to extract runner: %v", err)
-// 	}
-
-// 	// ✅ 5. Hapus file archive setelah sukses ekstraksi
-// 	_ = os.Remove(tarPath)
-// 	log.Printf("🧹 Removed archive %s after extraction", tarPath)
-// 	log.Printf("✅ Runner binary v%s installed successfully at %s", version, coreDir)
-
-// 	return nil
-// }
+// cachedRunnerChecksum mengambil checksum tanpa jaringan: dari config, atau
+// dari file .sha256 di samping tarball. Kosong kalau belum ada.
+func cachedRunnerChecksum(cfg Config, sumFile string) string {
+	if cfg.RunnerSHA256 != "" {
+		return strings.ToLower(cfg.RunnerSHA256)
+	}
+	b, err := os.ReadFile(sumFile)
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// writeChecksumFile menyimpan checksum ke file .sha256 kalau isinya berubah
+func writeChecksumFile(path, sum string) error {
+	line := sum + "\n"
+	if b, err := os.ReadFile(path); err == nil && string(b) == line {
+		return nil
+	}
+	return os.WriteFile(path, []byte(line), 0644)
+}
+
+// expectedRunnerChecksum mengambil SHA-256 dari config, dari file .sha256
+// di mirror, atau dari release notes actions/runner (urutan prioritas).
+func expectedRunnerChecksum(cfg Config) (string, error) {
+	if cfg.RunnerSHA256 != "" {
+		return strings.ToLower(cfg.RunnerSHA256), nil
+	}
+
+	if cfg.RunnerMirrorURL != "" {
+		resp, err := downloadClient.Get(runnerTarballURL(cfg) + ".sha256")
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("mirror checksum responded %d", resp.StatusCode)
+		}
+		b, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if err != nil {
+			return "", err
+		}
+		fields := strings.Fields(string(b))
+		if len(fields) == 0 {
+			return "", fmt.Errorf("empty checksum file on mirror")
+		}
+		return strings.ToLower(fields[0]), nil
+	}
+
+	url := fmt.Sprintf("https://api.github.com/repos/actions/runner/releases/tags/v%s", cfg.RunnerVersion)
+	resp, err := downloadClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub releases API responded %d", resp.StatusCode)
+	}
+
+	var release struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("decode release: %v", err)
+	}
+	return parseReleaseChecksum(release.Body, cfg.RunnerArch)
+}
+
+// parseReleaseChecksum membaca hash dari blok
+// <!-- BEGIN SHA linux-x64 -->...<!-- END SHA linux-x64 --> di release notes
+func parseReleaseChecksum(body, arch string) (string, error) {
+	re := regexp.MustCompile(`<!-- BEGIN SHA linux-` + regexp.QuoteMeta(arch) + ` -->([0-9a-fA-F]{64})<!-- END SHA`)
+	m := re.FindStringSubmatch(body)
+	if m == nil {
+		return "", fmt.Errorf("no published checksum for linux-%s", arch)
+	}
+	return strings.ToLower(m[1]), nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
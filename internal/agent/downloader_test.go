@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseReleaseChecksum(t *testing.T) {
+	hash := "9ba4ac4a6a2b6f8f4c3ff5a4b8d0ac6fef1cd3d3c1a0c0e9c8a1b3a0c9d8e7f6"
+	body := "notes\n<!-- BEGIN SHA linux-arm64 -->" + hash[:63] + "0<!-- END SHA linux-arm64 -->\n" +
+		"<!-- BEGIN SHA linux-x64 -->" + hash + "<!-- END SHA linux-x64 -->"
+
+	got, err := parseReleaseChecksum(body, "x64")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != hash {
+		t.Fatalf("expected %s, got %s", hash, got)
+	}
+
+	if _, err := parseReleaseChecksum(body, "arm"); err == nil {
+		t.Fatalf("expected error for missing arch, got nil")
+	}
+}
+
+func TestFetchRunnerTarball_VerifiesAndCaches(t *testing.T) {
+	payload := []byte("fake runner tarball")
+	sum := sha256.Sum256(payload)
+
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write(payload)
+	}))
+	defer srv.Close()
+
+	cfg := Config{
+		RunnerVersion:   "2.317.0",
+		RunnerArch:      "x64",
+		RunnerMirrorURL: srv.URL,
+		RunnerSHA256:    hex.EncodeToString(sum[:]),
+		RunnerCacheDir:  t.TempDir(),
+	}
+
+	path, err := FetchRunnerTarball(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := FetchRunnerTarball(cfg); err != nil {
+		t.Fatalf("unexpected error on cache hit: %v", err)
+	}
+	if hits != 1 {
+		t.Fatalf("expected 1 download, got %d", hits)
+	}
+	if filepath.Base(path) != "actions-runner-linux-x64-2.317.0.tar.gz" {
+		t.Fatalf("unexpected cache path %s", path)
+	}
+}
+
+func TestFetchRunnerTarball_ChecksumMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tampered"))
+	}))
+	defer srv.Close()
+
+	cfg := Config{
+		RunnerVersion:   "2.317.0",
+		RunnerArch:      "x64",
+		RunnerMirrorURL: srv.URL,
+		RunnerSHA256:    "0000000000000000000000000000000000000000000000000000000000000000",
+		RunnerCacheDir:  t.TempDir(),
+	}
+
+	if _, err := FetchRunnerTarball(cfg); err == nil {
+		t.Fatalf("expected checksum error, got nil")
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(cfg.RunnerCacheDir, "2.317.0", "x64"))
+	if len(entries) != 0 {
+		t.Fatalf("expected no files left in cache, found %d", len(entries))
+	}
+}
+
+func TestFetchRunnerTarball_CacheHitWithoutNetwork(t *testing.T) {
+	payload := []byte("fake runner tarball")
+	sum := sha256.Sum256(payload)
+
+	hits := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[filepath.Ext(r.URL.Path)]++
+		if filepath.Ext(r.URL.Path) == ".sha256" {
+			w.Write([]byte(hex.EncodeToString(sum[:]) + "  actions-runner-linux-x64-2.317.0.tar.gz\n"))
+			return
+		}
+		w.Write(payload)
+	}))
+
+	cfg := Config{
+		RunnerVersion:   "2.317.0",
+		RunnerArch:      "x64",
+		RunnerMirrorURL: srv.URL,
+		RunnerCacheDir:  t.TempDir(),
+	}
+	path, err := FetchRunnerTarball(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b, _ := os.ReadFile(path + ".sha256"); string(b) != hex.EncodeToString(sum[:])+"\n" {
+		t.Fatalf("checksum not saved next to the tarball: %q", b)
+	}
+
+	// mirror mati: cache hit cukup dengan checksum yang tersimpan
+	srv.Close()
+	if _, err := FetchRunnerTarball(cfg); err != nil {
+		t.Fatalf("cache hit needed the network: %v", err)
+	}
+	if hits[".sha256"] != 1 || hits[".gz"] != 1 {
+		t.Fatalf("unexpected requests %v", hits)
+	}
+
+	// tarball korup tidak lolos checksum yang tersimpan
+	os.WriteFile(path, []byte("corrupted"), 0644)
+	if _, err := FetchRunnerTarball(cfg); err == nil {
+		t.Fatalf("expected corrupted cache to be rejected")
+	}
+	if _, err := os.Stat(path + ".sha256"); !os.IsNotExist(err) {
+		t.Fatalf("expected stale checksum to be removed, got %v", err)
+	}
+}
@@ -1,21 +1,125 @@
 package agent
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"net/http"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
 )
 
+// towerDirective adalah balasan Tower atas heartbeat (versi runner yang diinginkan, dsb)
+type towerDirective struct {
+	DesiredVersion string `json:"desired_version"`
+	Upgrade        bool   `json:"upgrade"`
+	BatchSize      int    `json:"batch_size"`
+
+	// runner yang workspace-nya kotor/penuh dan harus diganti instance bersih
+	ReplaceRunners []string `json:"replace_runners"`
+
+	// operator meminta VM ini di-drain lalu agentd berhenti
+	Drain bool `json:"drain"`
+}
+
 func (a *Agent) HeartbeatLoop() {
 	for {
-		data := map[string]interface{}{
-			"instance":  a.config.InstanceName,
-			"runners":   len(a.runners),
-			"timestamp": time.Now(),
+		if err := a.sendHeartbeat(a.State()); err != nil {
+			logger.Warn("heartbeat failed", "err", err)
 		}
-		b, _ := json.Marshal(data)
-		http.Post(a.config.TowerURL+"/vm/heartbeat", "application/json", bytes.NewBuffer(b))
 		time.Sleep(time.Duration(a.config.HeartbeatInterval) * time.Second)
 	}
 }
+
+// sendHeartbeat mengirim status VM ke Tower dan menjalankan directive dari balasannya
+func (a *Agent) sendHeartbeat(state string) (err error) {
+	ctx, span := tracing.Tracer().Start(context.Background(), "agent.heartbeat", trace.WithAttributes(
+		attribute.String("tcr.agent.id", a.config.InstanceName),
+		attribute.String("tcr.agent.state", state),
+	))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+
+	a.mu.Lock()
+	count := len(a.runners)
+	version := a.config.RunnerVersion
+	failed := a.upgradeFailed
+	disk := a.disk
+	usage := map[string]*CgroupUsage{}
+	names := make([]string, 0, len(a.runners))
+	for _, r := range a.runners {
+		names = append(names, r.Name)
+		if u := r.Usage(); u != nil {
+			usage[r.Name] = u
+		}
+	}
+	a.mu.Unlock()
+
+	data := map[string]interface{}{
+		"instance":        a.config.InstanceName,
+		"runners":         count,
+		"runner_names":    names,
+		"state":           state,
+		"runner_version":  version,
+		"cached_versions": a.cachedVersions(),
+		"upgrade_failed":  failed,
+		"disk":            disk,
+		"cgroups":         usage,
+		"api_port":        apiPort(a.config.APIListen),
+		"labels":          a.config.RunnerLabels,
+		"timestamp":       time.Now(),
+	}
+	b, _ := json.Marshal(data)
+	resp, err := towerClient(a.config).DoContext(ctx, "POST", "/vm/heartbeat", b)
+	if err != nil {
+		heartbeats.WithLabelValues("error").Inc()
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		heartbeats.WithLabelValues("error").Inc()
+		return fmt.Errorf("tower responded %d", resp.StatusCode)
+	}
+	heartbeats.WithLabelValues("ok").Inc()
+
+	var d towerDirective
+	if err := json.NewDecoder(resp.Body).Decode(&d); err == nil && state != StateStopped {
+		a.handleDirective(d)
+	}
+	return nil
+}
+
+// apiPort mengambil port dari AGENT_LISTEN_ADDR (":8082" → "8082")
+func apiPort(addr string) string {
+	if _, port, err := net.SplitHostPort(addr); err == nil {
+		return port
+	}
+	return addr
+}
+
+// cachedVersions mengembalikan versi runner yang core-nya sudah siap di VM ini
+func (a *Agent) cachedVersions() []string {
+	versions := []string{}
+	entries, _ := os.ReadDir(filepath.Join(a.config.RunnerDir, "core"))
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(a.config.RunnerDir, "core", e.Name(), "config.sh")); err == nil {
+			versions = append(versions, e.Name())
+		}
+	}
+	return versions
+}
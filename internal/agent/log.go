@@ -0,0 +1,5 @@
+package agent
+
+import "github.com/ridwandwisiswanto/tcr/internal/logging"
+
+var logger = logging.For("agent")
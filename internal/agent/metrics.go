@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	heartbeats = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tcr_agent_heartbeats_total",
+			Help: "Heartbeats sent to tower by result (ok, error)",
+		},
+		[]string{"result"},
+	)
+	runnerSpawns = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tcr_agent_runner_spawns_total",
+			Help: "Runner instances spawned by result (ok, error)",
+		},
+		[]string{"result"},
+	)
+	upgrades = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tcr_agent_upgrades_total",
+			Help: "Rolling runner upgrades by result (success, rollback, incomplete)",
+		},
+		[]string{"result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(heartbeats, runnerSpawns, upgrades)
+}
+
+func result(err error, ok, failed string) string {
+	if err != nil {
+		return failed
+	}
+	return ok
+}
+
+func observeSpawn(err error) {
+	runnerSpawns.WithLabelValues(result(err, "ok", "error")).Inc()
+}
+
+var (
+	descInfo = prometheus.NewDesc("tcr_agent_info",
+		"Agent identity; always 1", []string{"instance", "runner_version"}, nil)
+	descState = prometheus.NewDesc("tcr_agent_state",
+		"1 for the current agent state", []string{"state"}, nil)
+	descRunners = prometheus.NewDesc("tcr_agent_runners",
+		"Runner instances managed by this agent", nil, nil)
+	descMaxRunners = prometheus.NewDesc("tcr_agent_max_runners",
+		"Configured maximum runners on this VM", nil, nil)
+	descDiskUsed = prometheus.NewDesc("tcr_agent_disk_used_percent",
+		"Used percentage of the filesystem holding the runner directory", nil, nil)
+	descDiskPressure = prometheus.NewDesc("tcr_agent_disk_pressure",
+		"1 when disk usage is above disk_pressure_pct or workspaces exceed workspace_total_max_mb", nil, nil)
+	descWorkspace = prometheus.NewDesc("tcr_agent_workspace_mb",
+		"Size of the runner _work directory in MB", []string{"runner"}, nil)
+	descMemory = prometheus.NewDesc("tcr_agent_runner_memory_mb",
+		"Current cgroup memory usage of a runner", []string{"runner"}, nil)
+	descCPU = prometheus.NewDesc("tcr_agent_runner_cpu_seconds_total",
+		"CPU time consumed by a runner cgroup", []string{"runner"}, nil)
+	descPids = prometheus.NewDesc("tcr_agent_runner_pids",
+		"Processes in a runner cgroup", []string{"runner"}, nil)
+	descOOM = prometheus.NewDesc("tcr_agent_runner_oom_kills_total",
+		"OOM kills in a runner cgroup", []string{"runner"}, nil)
+)
+
+// collector membaca state agent saat scrape (sama dengan isi heartbeat)
+type collector struct {
+	a *Agent
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{descInfo, descState, descRunners, descMaxRunners,
+		descDiskUsed, descDiskPressure, descWorkspace, descMemory, descCPU, descPids, descOOM} {
+		ch <- d
+	}
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	state := c.a.State()
+
+	c.a.mu.Lock()
+	cfg := c.a.config
+	disk := c.a.disk
+	count := len(c.a.runners)
+	usage := map[string]*CgroupUsage{}
+	for _, r := range c.a.runners {
+		if u := r.Usage(); u != nil {
+			usage[r.Name] = u
+		}
+	}
+	c.a.mu.Unlock()
+
+	gauge := func(d *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v, labels...)
+	}
+	counter := func(d *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v, labels...)
+	}
+
+	gauge(descInfo, 1, cfg.InstanceName, cfg.RunnerVersion)
+	gauge(descState, 1, state)
+	gauge(descRunners, float64(count))
+	gauge(descMaxRunners, float64(cfg.MaxRunners))
+	gauge(descDiskUsed, disk.UsedPercent)
+	pressure := 0.0
+	if disk.Pressure {
+		pressure = 1
+	}
+	gauge(descDiskPressure, pressure)
+	for name, mb := range disk.RunnerMB {
+		gauge(descWorkspace, float64(mb), name)
+	}
+	for name, u := range usage {
+		gauge(descMemory, float64(u.MemoryMB), name)
+		counter(descCPU, float64(u.CPUSec), name)
+		gauge(descPids, float64(u.Pids), name)
+		counter(descOOM, float64(u.OOMKills), name)
+	}
+}
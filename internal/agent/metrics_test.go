@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollectorReportsAgentState(t *testing.T) {
+	a := &Agent{config: Config{InstanceName: "vm-1", RunnerVersion: "2.330.0", MaxRunners: 3}}
+	a.runners = []*Runner{{Name: "vm-1-agent-01"}}
+	a.disk = DiskReport{UsedPercent: 42, Pressure: true, RunnerMB: map[string]int64{"vm-1-agent-01": 512}}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collector{a})
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]float64{}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			got[mf.GetName()] = m.GetGauge().GetValue()
+		}
+	}
+	want := map[string]float64{
+		"tcr_agent_info":              1,
+		"tcr_agent_runners":           1,
+		"tcr_agent_max_runners":       3,
+		"tcr_agent_disk_used_percent": 42,
+		"tcr_agent_disk_pressure":     1,
+		"tcr_agent_workspace_mb":      512,
+	}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("%s = %v, want %v", name, got[name], v)
+		}
+	}
+}
@@ -4,30 +4,53 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/runnerlog"
 )
 
 type Runner struct {
 	ID        int
 	Name      string
 	Dir       string
+	Version   string
 	LastJobAt time.Time
+
+	cmd      *exec.Cmd
+	done     chan struct{}
+	cgroup   *Cgroup
+	oomKills int64
+	LogPath  string
 }
 
+// SpawnRunner membuat 1 instance runner baru berdasarkan shared core/<version>
 func SpawnRunner(id int, cfg Config) (*Runner, error) {
+	r, err := spawnRunner(id, cfg)
+	observeSpawn(err)
+	return r, err
+}
+
+func spawnRunner(id int, cfg Config) (*Runner, error) {
 	name := fmt.Sprintf("%s-agent-%02d", cfg.InstanceName, id)
-	dir := filepath.Join(cfg.RunnerDir, fmt.Sprintf("runner-%02d", id))
-	_ = os.RemoveAll(dir)
-	_ = os.MkdirAll(dir, 0755)
+	instanceDir := filepath.Join(cfg.RunnerDir, "instances", fmt.Sprintf("runner-%02d", id))
 
-	// Download binary langsung ke folder instance
-	if err := EnsureRunnerBinary(dir, cfg.RunnerVersion); err != nil {
-		return nil, fmt.Errorf("install binary: %w", err)
+	coreDir, err := EnsureRunnerCore(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("install core: %w", err)
+	}
+
+	// instance selalu dibuat ulang dari core (bersih, tanpa .runner lama)
+	_ = os.RemoveAll(instanceDir)
+	if err := CreateInstanceFromCore(coreDir, instanceDir); err != nil {
+		return nil, fmt.Errorf("create instance: %w", err)
+	}
+	if err := InstallWorkspaceHook(instanceDir, cfg); err != nil {
+		return nil, fmt.Errorf("workspace hook: %w", err)
 	}
 
 	token, err := FetchTokenFromTower(cfg)
@@ -35,95 +58,105 @@ func SpawnRunner(id int, cfg Config) (*Runner, error) {
 		return nil, fmt.Errorf("get token: %w", err)
 	}
 
-	configPath := filepath.Join(dir, "config.sh")
-	cmd := exec.Command(configPath,
+	// 📝 Output config.sh & run.sh masuk ke file log runner sendiri (di-rotate)
+	logw, err := runnerlog.New(filepath.Join(cfg.LogDir, name+".log"), name, int64(cfg.LogMaxMB)<<20, cfg.LogBackups)
+	if err != nil {
+		return nil, fmt.Errorf("open runner log: %w", err)
+	}
+
+	configPath := filepath.Join(instanceDir, "config.sh")
+	args := []string{
 		"--unattended",
 		"--url", fmt.Sprintf("https://github.com/%s", cfg.RepoFullName),
 		"--token", token,
 		"--name", name,
 		"--replace",
-	)
-	cmd.Dir = dir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	}
+	if len(cfg.RunnerLabels) > 0 {
+		args = append(args, "--labels", strings.Join(cfg.RunnerLabels, ","))
+	}
+	cmd := exec.Command(configPath, args...)
+	cmd.Dir = instanceDir
+	cmd.Stdout = logw
+	cmd.Stderr = logw
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("config.sh failed: %w", err)
+		logw.Close()
+		return nil, fmt.Errorf("config.sh failed (see %s): %w", logw.Path(), err)
 	}
 
 	runCmd := exec.Command("./run.sh")
-	runCmd.Dir = dir
-	runCmd.Stdout = os.Stdout
-	runCmd.Stderr = os.Stderr
+	runCmd.Dir = instanceDir
+	runCmd.Stdout = logw
+	runCmd.Stderr = logw
+	// process group sendiri supaya Runner.Listener ikut berhenti saat Stop()
+	runCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	// 📏 Batasi CPU/memory/pids runner lewat cgroup v2 (kalau dikonfigurasi)
+	var cg *Cgroup
+	if limits, ok := limitsFor(cfg); ok && cfg.CgroupEnabled {
+		cg, err = setupCgroup(fmt.Sprintf("runner-%02d", id), cfg, limits)
+		if err != nil {
+			logger.Warn("runner runs without resource limits", "runner_id", name, "err", err)
+		} else {
+			cg.apply(runCmd)
+		}
+	}
+
 	if err := runCmd.Start(); err != nil {
+		if cg != nil {
+			cg.Remove()
+		}
+		logw.Close()
 		return nil, fmt.Errorf("run.sh start failed: %w", err)
 	}
 
-	log.Printf("🏃 Runner %s started (dir=%s)", name, dir)
-	return &Runner{ID: id, Name: name, Dir: dir, LastJobAt: time.Now()}, nil
+	r := &Runner{
+		ID:        id,
+		Name:      name,
+		Dir:       instanceDir,
+		Version:   cfg.RunnerVersion,
+		LastJobAt: time.Now(),
+		cmd:       runCmd,
+		done:      make(chan struct{}),
+		cgroup:    cg,
+		LogPath:   logw.Path(),
+	}
+	go func() {
+		_ = runCmd.Wait()
+		if cg != nil {
+			cg.Remove()
+		}
+		logw.Close()
+		close(r.done)
+	}()
+
+	logger.Info("runner started", "runner_id", name, "dir", instanceDir, "core", coreDir)
+	return r, nil
 }
 
-// SpawnRunner membuat 1 instance runner baru berdasarkan shared core/
-// func SpawnRunner(id int, cfg Config) (*Runner, error) {
-// 	name := fmt.Sprintf("%s-agent-%02d", cfg.InstanceName, id)
-// 	coreDir := filepath.Join(cfg.RunnerDir, "core")
-// 	instanceDir := filepath.Join(cfg.RunnerDir, "instances", fmt.Sprintf("runner-%02d", id))
-
-// 	// pastikan folder instance ada
-// 	if err := os.MkdirAll(instanceDir, 0755); err != nil {
-// 		return nil, fmt.Errorf("failed to create instance dir: %w", err)
-// 	}
-
-// 	// ambil token dari tower
-// 	token, err := FetchTokenFromTower(cfg)
-// 	if err != nil {
-// 		return nil, fmt.Errorf("get token: %w", err)
-// 	}
-
-// 	// 🔗 gunakan coreDir sebagai template instance baru
-// 	if err := CreateInstanceFromCore(coreDir, instanceDir); err != nil {
-// 		return nil, fmt.Errorf("copy core files: %w", err)
-// 	}
-
-// 	// jalankan konfigurasi runner
-// 	os.Remove(filepath.Join(instanceDir, ".runner"))
-// 	configPath := filepath.Join(instanceDir, "config.sh")
-// 	cmd := exec.Command(configPath,
-// 		"--unattended",
-// 		"--url", fmt.Sprintf("https://github.com/%s", cfg.RepoFullName),
-// 		"--token", token,
-// 		"--name", name,
-// 		"--replace",
-// 	)
-// 	cmd.Dir = instanceDir
-// 	cmd.Stdout = os.Stdout
-// 	cmd.Stderr = os.Stderr
-
-// 	if err := cmd.Run(); err != nil {
-// 		return nil, fmt.Errorf("config.sh failed: %w", err)
-// 	}
-
-// 	// 🚀 Jalankan runner-nya
-// 	runCmd := exec.Command("./run.sh")
-// 	runCmd.Dir = instanceDir
-// 	runCmd.Stdout = os.Stdout
-// 	runCmd.Stderr = os.Stderr
-// 	if err := runCmd.Start(); err != nil {
-// 		return nil, fmt.Errorf("run.sh start failed: %w", err)
-// 	}
-
-// 	log.Printf("🏃 Runner %s started (dir=%s)", name, instanceDir)
-// 	return &Runner{
-// 		ID:        id,
-// 		Name:      name,
-// 		Dir:       instanceDir,
-// 		LastJobAt: time.Now(),
-// 	}, nil
-// }
+// Stop menghentikan proses run.sh beserta child process-nya.
+// SIGTERM dulu, lalu SIGKILL kalau belum keluar setelah timeout.
+func (r *Runner) Stop(timeout time.Duration) {
+	if r.cmd == nil || r.cmd.Process == nil {
+		return
+	}
+
+	pgid := -r.cmd.Process.Pid
+	_ = syscall.Kill(pgid, syscall.SIGTERM)
+
+	select {
+	case <-r.done:
+		logger.Info("runner stopped", "runner_id", r.Name)
+	case <-time.After(timeout):
+		logger.Warn("runner did not exit in time, killing", "runner_id", r.Name, "timeout", timeout)
+		_ = syscall.Kill(pgid, syscall.SIGKILL)
+		<-r.done
+	}
+}
 
 // FetchTokenFromTower meminta token registrasi dari Tower
 func FetchTokenFromTower(cfg Config) (string, error) {
-	url := fmt.Sprintf("%s/github/token", cfg.TowerURL)
-	resp, err := http.Post(url, "application/json", nil)
+	resp, err := towerClient(cfg).Do("POST", "/github/token", nil)
 	if err != nil {
 		return "", fmt.Errorf("failed fetch token: %w", err)
 	}
@@ -146,6 +179,19 @@ func FetchTokenFromTower(cfg Config) (string, error) {
 	return data.Token, nil
 }
 
+// Usage mengembalikan pemakaian resource runner dari cgroup-nya (nil kalau tanpa cgroup)
+func (r *Runner) Usage() *CgroupUsage {
+	if r.cgroup == nil {
+		return nil
+	}
+	u := r.cgroup.Usage()
+	if u.OOMKills > r.oomKills {
+		logger.Warn("runner OOM killed", "runner_id", r.Name, "oom_kills", u.OOMKills)
+		r.oomKills = u.OOMKills
+	}
+	return &u
+}
+
 // AllRunnersIdle memeriksa apakah semua runner idle dalam durasi tertentu
 func AllRunnersIdle(runners []*Runner, idleTimeout int) bool {
 	if len(runners) == 0 {
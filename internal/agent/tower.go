@@ -0,0 +1,20 @@
+package agent
+
+import (
+	"sync"
+
+	"github.com/ridwandwisiswanto/tcr/internal/auth"
+)
+
+var (
+	tower     *auth.Client
+	towerOnce sync.Once
+)
+
+// towerClient mengembalikan client Tower (enroll sebagai agent dengan bootstrap secret)
+func towerClient(cfg Config) *auth.Client {
+	towerOnce.Do(func() {
+		tower = auth.NewClient(cfg.TowerURL, cfg.InstanceName, auth.RoleAgent, cfg.BootstrapSecret)
+	})
+	return tower
+}
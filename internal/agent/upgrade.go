@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/github"
+)
+
+// upgradeBusyRounds: berapa kali runner yang masih sibuk dicoba lagi di akhir
+// rolling upgrade sebelum upgrade dihentikan (dicoba lagi di directive berikutnya)
+const upgradeBusyRounds = 3
+
+// dependensi GitHub dan proses runner; diganti di test
+var (
+	isRunnerBusy    = github.IsRunnerBusy
+	getRunnerStatus = github.GetRunnerStatus
+	respawnRunner   = SpawnRunner
+	unregister      = deregisterRunner
+
+	runnerPollInterval = 5 * time.Second
+)
+
+// handleDirective menjalankan instruksi Tower dari balasan heartbeat: mengganti
+// runner yang workspace-nya kotor, menyiapkan (prefetch) versi runner yang
+// diminta, dan memulai rolling upgrade kalau Tower sudah memberi giliran.
+func (a *Agent) handleDirective(d towerDirective) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if d.Drain && !a.draining {
+		timeout := time.Duration(a.config.DrainTimeout) * time.Second
+		logger.Info("drain requested by tower", "timeout_sec", a.config.DrainTimeout)
+		go func() {
+			a.Drain(timeout)
+			os.Exit(0)
+		}()
+		return
+	}
+
+	if len(d.ReplaceRunners) > 0 && !a.draining && !a.upgrading && !a.replacing {
+		a.replacing = true
+		go a.replaceDirtyRunners(d.ReplaceRunners)
+	}
+
+	if d.DesiredVersion == "" || d.DesiredVersion == a.config.RunnerVersion {
+		a.upgradeFailed = ""
+		return
+	}
+	if a.draining || a.upgrading || a.replacing || a.prefetching || a.upgradeFailed == d.DesiredVersion {
+		return
+	}
+
+	target := a.config
+	target.RunnerVersion = d.DesiredVersion
+
+	if !contains(a.cachedVersions(), d.DesiredVersion) {
+		a.prefetching = true
+		go func() {
+			logger.Info("prefetching runner version", "version", d.DesiredVersion)
+			if _, err := EnsureRunnerCore(target); err != nil {
+				logger.Warn("runner prefetch failed", "version", d.DesiredVersion, "err", err)
+			}
+			a.mu.Lock()
+			a.prefetching = false
+			a.mu.Unlock()
+		}()
+		return
+	}
+
+	if !d.Upgrade {
+		return
+	}
+
+	batch := d.BatchSize
+	if batch <= 0 {
+		batch = 1
+	}
+	a.upgrading = true
+	go a.RollingUpgrade(d.DesiredVersion, batch)
+}
+
+// RollingUpgrade mengganti runner di VM ini ke versi baru secara bertahap
+// (batch demi batch). Runner yang masih menjalankan job dilewati dan dicoba
+// lagi setelah batch lain selesai. Kalau runner baru gagal online, semua
+// runner yang sudah diganti dikembalikan ke versi lama.
+func (a *Agent) RollingUpgrade(version string, batchSize int) error {
+	a.mu.Lock()
+	oldCfg := a.config
+	var runners []*Runner
+	for _, r := range a.runners {
+		// sisa upgrade sebelumnya yang terhenti karena runner sibuk
+		if r.Version != version {
+			runners = append(runners, r)
+		}
+	}
+	a.mu.Unlock()
+
+	newCfg := oldCfg
+	newCfg.RunnerVersion = version
+
+	logger.Info("rolling upgrade started", "from", oldCfg.RunnerVersion, "to", version, "runners", len(runners), "batch_size", batchSize)
+
+	upgraded, busy, err := a.replaceInBatches(runners, newCfg, batchSize)
+	if err != nil {
+		logger.Error("upgrade failed, rolling back", "version", version, "rollback_runners", len(upgraded), "err", err)
+		if _, left, rbErr := a.replaceInBatches(upgraded, oldCfg, len(upgraded)); rbErr != nil || len(left) > 0 {
+			logger.Error("rollback failed", "version", oldCfg.RunnerVersion, "busy_runners", len(left), "err", rbErr)
+		}
+
+		a.mu.Lock()
+		a.upgrading = false
+		a.upgradeFailed = version
+		a.mu.Unlock()
+		upgrades.WithLabelValues("rollback").Inc()
+		return err
+	}
+	if len(busy) > 0 {
+		// bukan kegagalan: directive berikutnya melanjutkan runner yang tersisa
+		a.mu.Lock()
+		a.upgrading = false
+		a.mu.Unlock()
+		upgrades.WithLabelValues("incomplete").Inc()
+		logger.Warn("rolling upgrade paused, runners still busy", "version", version, "busy_runners", len(busy))
+		return fmt.Errorf("%d runner(s) still busy", len(busy))
+	}
+
+	a.mu.Lock()
+	a.config.RunnerVersion = version
+	a.upgrading = false
+	a.mu.Unlock()
+
+	GCRunnerCores(oldCfg.RunnerDir, version)
+	upgrades.WithLabelValues("success").Inc()
+	logger.Info("rolling upgrade complete", "version", version)
+	return nil
+}
+
+// replaceInBatches mengganti runners per batch. Runner yang masih sibuk
+// dipindah ke putaran berikutnya, maksimal upgradeBusyRounds putaran; yang
+// tetap sibuk dikembalikan sebagai busy.
+func (a *Agent) replaceInBatches(runners []*Runner, cfg Config, batchSize int) (replaced, busy []*Runner, err error) {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	pending := runners
+	for round := 0; round < upgradeBusyRounds && len(pending) > 0; round++ {
+		busy = nil
+		for i := 0; i < len(pending); i += batchSize {
+			end := i + batchSize
+			if end > len(pending) {
+				end = len(pending)
+			}
+			done, skipped, err := a.replaceRunners(pending[i:end], cfg)
+			replaced = append(replaced, done...)
+			busy = append(busy, skipped...)
+			if err != nil {
+				return replaced, busy, err
+			}
+		}
+		pending = busy
+	}
+	return replaced, busy, nil
+}
+
+// replaceRunners men-drain runner lama, lalu spawn ulang dengan cfg dan
+// menunggu semuanya online di GitHub. Runner yang tidak idle sampai
+// DrainTimeout tidak dihentikan, tapi dikembalikan sebagai busy.
+func (a *Agent) replaceRunners(old []*Runner, cfg Config) (replaced, busy []*Runner, err error) {
+	for _, r := range old {
+		if !a.waitIdle(r, time.Duration(cfg.DrainTimeout)*time.Second) {
+			busy = append(busy, r)
+			continue
+		}
+		r.Stop(10 * time.Second)
+		unregister(r)
+
+		nr, err := respawnRunner(r.ID, cfg)
+		if err != nil {
+			// runner lama (sudah stop) ikut dikembalikan supaya bisa di-spawn ulang saat rollback
+			replaced = append(replaced, r)
+			return replaced, busy, fmt.Errorf("spawn %s: %w", r.Name, err)
+		}
+		a.swapRunner(r, nr)
+		replaced = append(replaced, nr)
+	}
+
+	timeout := time.Duration(cfg.OnlineTimeout) * time.Second
+	for _, r := range replaced {
+		if err := waitOnline(r, timeout); err != nil {
+			return replaced, busy, err
+		}
+	}
+	return replaced, busy, nil
+}
+
+// waitIdle menunggu runner selesai menjalankan job, maksimal sampai timeout.
+// Error dari GitHub API dianggap sibuk: runner tidak boleh dihentikan hanya
+// karena statusnya tidak bisa dibaca. false kalau runner belum idle.
+func (a *Agent) waitIdle(r *Runner, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		busy, err := isRunnerBusy(r.Name)
+		if err != nil {
+			logger.Warn("cannot check runner busy state", "runner_id", r.Name, "err", err)
+		} else if !busy {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			break
+		}
+		time.Sleep(runnerPollInterval)
+	}
+	logger.Warn("runner still busy, skipping for now", "runner_id", r.Name, "timeout", timeout)
+	return false
+}
+
+// waitOnline menunggu runner terlihat online di GitHub
+func waitOnline(r *Runner, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		status, _, err := getRunnerStatus(r.Name)
+		if err == nil && status == "online" {
+			return nil
+		}
+		time.Sleep(runnerPollInterval)
+	}
+	return fmt.Errorf("runner %s (v%s) not online after %s", r.Name, r.Version, timeout)
+}
+
+// swapRunner mengganti entry runner lama di daftar agent
+func (a *Agent) swapRunner(old, nr *Runner) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, r := range a.runners {
+		if r == old {
+			a.runners[i] = nr
+			return
+		}
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
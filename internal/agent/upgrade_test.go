@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitHub mengganti dependensi GitHub dan spawn runner selama satu test
+type fakeGitHub struct {
+	mu      sync.Mutex
+	busy    map[string]int  // sisa pemeriksaan runner masih sibuk; -1 = error API terus
+	offline map[string]bool // "nama@versi" yang tidak pernah online
+	spawned []string
+}
+
+func installFake(t *testing.T, f *fakeGitHub) {
+	prevBusy, prevStatus, prevSpawn, prevUnreg, prevPoll := isRunnerBusy, getRunnerStatus, respawnRunner, unregister, runnerPollInterval
+	t.Cleanup(func() {
+		isRunnerBusy, getRunnerStatus, respawnRunner, unregister, runnerPollInterval = prevBusy, prevStatus, prevSpawn, prevUnreg, prevPoll
+	})
+	runnerPollInterval = time.Millisecond
+
+	isRunnerBusy = func(name string) (bool, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch n := f.busy[name]; {
+		case n < 0:
+			return false, errors.New("github unavailable")
+		case n > 0:
+			f.busy[name] = n - 1
+			return true, nil
+		}
+		return false, nil
+	}
+	respawnRunner = func(id int, cfg Config) (*Runner, error) {
+		name := runnerName(id)
+		f.mu.Lock()
+		f.spawned = append(f.spawned, name+"@"+cfg.RunnerVersion)
+		f.mu.Unlock()
+		return &Runner{ID: id, Name: name, Version: cfg.RunnerVersion}, nil
+	}
+	unregister = func(*Runner) {}
+	getRunnerStatus = func(name string) (string, bool, error) {
+		return "online", false, nil
+	}
+}
+
+func runnerName(id int) string {
+	return []string{"", "vm-agent-01", "vm-agent-02", "vm-agent-03"}[id]
+}
+
+// statusFor membuat getRunnerStatus yang tahu versi runner lewat daftar agent
+func (f *fakeGitHub) statusFor(a *Agent) func(string) (string, bool, error) {
+	return func(name string) (string, bool, error) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		for _, r := range a.runners {
+			if r.Name == name && f.offline[name+"@"+r.Version] {
+				return "offline", false, nil
+			}
+		}
+		return "online", false, nil
+	}
+}
+
+func newUpgradeAgent(t *testing.T) *Agent {
+	a := &Agent{config: Config{RunnerVersion: "1.0.0", RunnerDir: t.TempDir(), OnlineTimeout: 1}}
+	for id := 1; id <= 3; id++ {
+		a.runners = append(a.runners, &Runner{ID: id, Name: runnerName(id), Version: "1.0.0"})
+	}
+	a.upgrading = true
+	return a
+}
+
+func versions(a *Agent) map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := map[string]string{}
+	for _, r := range a.runners {
+		out[r.Name] = r.Version
+	}
+	return out
+}
+
+func TestRollingUpgrade_RollsBackWhenRunnerNeverComesOnline(t *testing.T) {
+	f := &fakeGitHub{busy: map[string]int{}, offline: map[string]bool{"vm-agent-02@2.0.0": true}}
+	installFake(t, f)
+	a := newUpgradeAgent(t)
+	getRunnerStatus = f.statusFor(a)
+
+	if err := a.RollingUpgrade("2.0.0", 1); err == nil {
+		t.Fatal("expected upgrade error")
+	}
+	for name, v := range versions(a) {
+		if v != "1.0.0" {
+			t.Errorf("%s left on %s after rollback", name, v)
+		}
+	}
+	if a.upgrading || a.upgradeFailed != "2.0.0" || a.config.RunnerVersion != "1.0.0" {
+		t.Fatalf("unexpected state upgrading=%v failed=%q version=%q", a.upgrading, a.upgradeFailed, a.config.RunnerVersion)
+	}
+	// runner 3 belum disentuh saat batch 2 gagal
+	for _, s := range f.spawned {
+		if s == "vm-agent-03@2.0.0" {
+			t.Fatalf("upgrade continued after a failed batch: %v", f.spawned)
+		}
+	}
+}
+
+func TestRollingUpgrade_DefersBusyRunners(t *testing.T) {
+	f := &fakeGitHub{busy: map[string]int{"vm-agent-01": 1, "vm-agent-03": -1}}
+	installFake(t, f)
+	a := newUpgradeAgent(t)
+
+	// runner 1 sibuk di putaran pertama; status runner 3 tidak bisa dibaca
+	if err := a.RollingUpgrade("2.0.0", 1); err == nil {
+		t.Fatal("expected upgrade to report runners still busy")
+	}
+	want := []string{"vm-agent-02@2.0.0", "vm-agent-01@2.0.0"}
+	if len(f.spawned) != 2 || f.spawned[0] != want[0] || f.spawned[1] != want[1] {
+		t.Fatalf("expected busy runner upgraded in a later batch, got %v", f.spawned)
+	}
+	if v := versions(a)["vm-agent-03"]; v != "1.0.0" {
+		t.Fatalf("runner with unknown busy state was replaced (now %s)", v)
+	}
+	if a.upgrading || a.upgradeFailed != "" || a.config.RunnerVersion != "1.0.0" {
+		t.Fatalf("unexpected state upgrading=%v failed=%q version=%q", a.upgrading, a.upgradeFailed, a.config.RunnerVersion)
+	}
+
+	// directive berikutnya hanya melanjutkan runner yang tersisa
+	f.busy["vm-agent-03"] = 0
+	f.spawned = nil
+	a.upgrading = true
+	if err := a.RollingUpgrade("2.0.0", 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.spawned) != 1 || f.spawned[0] != "vm-agent-03@2.0.0" || a.config.RunnerVersion != "2.0.0" {
+		t.Fatalf("expected only vm-agent-03 upgraded, got %v (version %s)", f.spawned, a.config.RunnerVersion)
+	}
+}
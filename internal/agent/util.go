@@ -1,11 +1,14 @@
 package agent
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // CreateInstanceFromCore membuat runner instance baru dengan symlink langsung ke core
@@ -89,7 +92,7 @@ func CreateInstanceFromCore(coreDir, instanceDir string) error {
 		if err := os.Symlink(src, dst); err != nil {
 			return fmt.Errorf("failed to symlink %s: %v", folder, err)
 		}
-		log.Printf("🔗 Linked %s → %s", dst, src)
+		logger.Debug("linked runner core dir", "dst", dst, "src", src)
 	}
 
 	// 2️⃣ Copy file penting dari core
@@ -107,10 +110,46 @@ func CreateInstanceFromCore(coreDir, instanceDir string) error {
 		}
 	}
 
-	log.Printf("✅ Instance created at %s (linked to core)", instanceDir)
+	logger.Info("runner instance created", "dir", instanceDir)
 	return nil
 }
 
+// GCRunnerCores menghapus core versi lama yang tidak lagi di-link oleh instance manapun.
+// Core untuk versi yang sedang dipakai (keep) tidak pernah dihapus.
+func GCRunnerCores(runnerDir, keep string) {
+	coresDir := filepath.Join(runnerDir, "core")
+	entries, err := os.ReadDir(coresDir)
+	if err != nil {
+		return
+	}
+
+	// kumpulkan core yang masih direferensikan lewat symlink bin/
+	inUse := map[string]bool{}
+	instances, _ := os.ReadDir(filepath.Join(runnerDir, "instances"))
+	for _, inst := range instances {
+		target, err := os.Readlink(filepath.Join(runnerDir, "instances", inst.Name(), "bin"))
+		if err != nil {
+			continue
+		}
+		inUse[filepath.Dir(target)] = true
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == keep || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(coresDir, e.Name())
+		if inUse[dir] {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warn("failed to remove unused runner core", "dir", dir, "err", err)
+			continue
+		}
+		logger.Info("removed unused runner core", "dir", dir)
+	}
+}
+
 // package agent
 
 // import (
@@ -187,43 +226,199 @@ func CreateInstanceFromCore(coreDir, instanceDir string) error {
 // 	})
 // }
 
-// func ExtractTarGz(r io.Reader, dest string) error {
-// 	gzr, err := gzip.NewReader(r)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	defer gzr.Close()
-// 	tr := tar.NewReader(gzr)
+// ExtractOptions mengatur perilaku ExtractTarGz
+type ExtractOptions struct {
+	// StripComponents membuang N komponen awal path (seperti tar --strip-components)
+	StripComponents int
+	// Progress dipanggil setelah tiap entry diekstrak (opsional)
+	Progress func(entries int, written int64)
+}
 
-// 	for {
-// 		hdr, err := tr.Next()
-// 		if err == io.EOF {
-// 			break
-// 		}
-// 		if err != nil {
-// 			return err
-// 		}
+// ExtractTarGz mengekstrak archive tar.gz ke dest tanpa shell out ke `tar`.
+// Entry (atau target symlink/hardlink) yang keluar dari dest akan ditolak,
+// termasuk entry yang path-nya melewati symlink dari entry sebelumnya.
+func ExtractTarGz(r io.Reader, dest string, opts ExtractOptions) error {
+	dest, err := filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
 
-// 		target := filepath.Join(dest, hdr.Name)
-// 		if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
-// 			return fmt.Errorf("illegal file path: %s", target)
-// 		}
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+	tr := tar.NewReader(gzr)
 
-// 		switch hdr.Typeflag {
-// 		case tar.TypeDir:
-// 			os.MkdirAll(target, os.FileMode(hdr.Mode))
-// 		case tar.TypeReg:
-// 			os.MkdirAll(filepath.Dir(target), 0755)
-// 			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, os.FileMode(hdr.Mode))
-// 			if err != nil {
-// 				return err
-// 			}
-// 			if _, err := io.Copy(f, tr); err != nil {
-// 				f.Close()
-// 				return err
-// 			}
-// 			f.Close()
-// 		}
-// 	}
-// 	return nil
-// }
+	type dirMeta struct {
+		path  string
+		mode  os.FileMode
+		mtime time.Time
+	}
+	var dirs []dirMeta
+	var entries int
+	var written int64
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name, ok := stripComponents(hdr.Name, opts.StripComponents)
+		if !ok {
+			continue
+		}
+
+		target, err := safeJoin(dest, name)
+		if err != nil {
+			return err
+		}
+		// MkdirAll/OpenFile mengikuti symlink, jadi cek nama saja tidak cukup
+		check := filepath.Dir(target)
+		if hdr.Typeflag == tar.TypeDir {
+			check = target
+		}
+		if err := noSymlinkUnder(dest, check); err != nil {
+			return err
+		}
+		mode := hdr.FileInfo().Mode().Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			// mode asli dipasang di akhir supaya dir read-only tetap bisa diisi
+			dirs = append(dirs, dirMeta{target, mode, hdr.ModTime})
+
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode)
+			if err != nil {
+				return err
+			}
+			n, err := io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+			written += n
+			_ = os.Chmod(target, mode)
+			_ = os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+
+		case tar.TypeSymlink:
+			// target symlink harus tetap di dalam dest
+			linkTarget := hdr.Linkname
+			if filepath.IsAbs(linkTarget) {
+				return fmt.Errorf("illegal absolute symlink: %s -> %s", hdr.Name, linkTarget)
+			}
+			if _, err := safeJoin(dest, filepath.Join(filepath.Dir(name), linkTarget)); err != nil {
+				return fmt.Errorf("illegal symlink: %s -> %s", hdr.Name, linkTarget)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(linkTarget, target); err != nil {
+				return err
+			}
+
+		case tar.TypeLink:
+			linkName, ok := stripComponents(hdr.Linkname, opts.StripComponents)
+			if !ok {
+				return fmt.Errorf("illegal hardlink: %s -> %s", hdr.Name, hdr.Linkname)
+			}
+			src, err := safeJoin(dest, linkName)
+			if err != nil || noSymlinkUnder(dest, filepath.Dir(src)) != nil {
+				return fmt.Errorf("illegal hardlink: %s -> %s", hdr.Name, hdr.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Link(src, target); err != nil {
+				return err
+			}
+
+		default:
+			// device, fifo, dll tidak dibutuhkan runner — skip
+			continue
+		}
+
+		entries++
+		if opts.Progress != nil {
+			opts.Progress(entries, written)
+		}
+	}
+
+	// pasang mode & mtime direktori dari yang terdalam
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Chmod(dirs[i].path, dirs[i].mode)
+		_ = os.Chtimes(dirs[i].path, dirs[i].mtime, dirs[i].mtime)
+	}
+
+	return nil
+}
+
+// stripComponents membuang n komponen awal dari path di archive.
+// ok=false kalau path habis setelah di-strip (entry di-skip).
+func stripComponents(name string, n int) (string, bool) {
+	var parts []string
+	for _, p := range strings.Split(filepath.ToSlash(name), "/") {
+		if p != "" && p != "." {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) <= n {
+		return "", false
+	}
+	return filepath.Join(parts[n:]...), true
+}
+
+// safeJoin menggabungkan dest dan name, error kalau hasilnya keluar dari dest
+func safeJoin(dest, name string) (string, error) {
+	target := filepath.Join(dest, name)
+	if target != dest && !strings.HasPrefix(target, dest+string(os.PathSeparator)) {
+		return "", fmt.Errorf("illegal file path: %s", name)
+	}
+	return target, nil
+}
+
+// noSymlinkUnder menolak path yang salah satu komponennya di bawah dest
+// adalah symlink; komponen yang belum ada berarti aman (akan dibuat sebagai dir)
+func noSymlinkUnder(dest, path string) error {
+	rel, err := filepath.Rel(dest, path)
+	if err != nil {
+		return err
+	}
+	cur := dest
+	for _, p := range strings.Split(rel, string(os.PathSeparator)) {
+		if p == "." {
+			continue
+		}
+		cur = filepath.Join(cur, p)
+		fi, err := os.Lstat(cur)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("illegal path through symlink: %s", strings.TrimPrefix(cur, dest+string(os.PathSeparator)))
+		}
+	}
+	return nil
+}
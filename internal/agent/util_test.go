@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	hdr  tar.Header
+	body string
+}
+
+// helper untuk buat archive tar.gz di memory
+func makeTarGz(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	for _, e := range entries {
+		hdr := e.hdr
+		hdr.Size = int64(len(e.body))
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatalf("write body: %v", err)
+		}
+	}
+	tw.Close()
+	gzw.Close()
+	return buf
+}
+
+func TestExtractTarGz_ModesAndStrip(t *testing.T) {
+	archive := makeTarGz(t, []tarEntry{
+		{hdr: tar.Header{Name: "pkg/", Typeflag: tar.TypeDir, Mode: 0755}},
+		{hdr: tar.Header{Name: "pkg/run.sh", Typeflag: tar.TypeReg, Mode: 0755}, body: "#!/bin/sh\n"},
+		{hdr: tar.Header{Name: "pkg/bin/lib.so", Typeflag: tar.TypeReg, Mode: 0644}, body: "lib"},
+		{hdr: tar.Header{Name: "pkg/bin/lib.so.1", Typeflag: tar.TypeSymlink, Linkname: "lib.so"}},
+	})
+
+	dest := t.TempDir()
+	entries := 0
+	err := ExtractTarGz(archive, dest, ExtractOptions{
+		StripComponents: 1,
+		Progress:        func(n int, _ int64) { entries = n },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fi, err := os.Stat(filepath.Join(dest, "run.sh"))
+	if err != nil {
+		t.Fatalf("run.sh not extracted: %v", err)
+	}
+	if fi.Mode().Perm() != 0755 {
+		t.Fatalf("expected mode 0755, got %v", fi.Mode().Perm())
+	}
+	if link, err := os.Readlink(filepath.Join(dest, "bin", "lib.so.1")); err != nil || link != "lib.so" {
+		t.Fatalf("expected symlink to lib.so, got %q (%v)", link, err)
+	}
+	if entries != 3 {
+		t.Fatalf("expected 3 entries reported, got %d", entries)
+	}
+}
+
+func TestExtractTarGz_RejectsTraversal(t *testing.T) {
+	cases := map[string][]tarEntry{
+		"dotdot": {
+			{hdr: tar.Header{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644}, body: "x"},
+		},
+		"absolute symlink": {
+			{hdr: tar.Header{Name: "passwd", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+		},
+		"escaping symlink": {
+			{hdr: tar.Header{Name: "a/link", Typeflag: tar.TypeSymlink, Linkname: "../../outside"}},
+		},
+		"escaping hardlink": {
+			{hdr: tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "../outside"}},
+		},
+	}
+
+	for name, entries := range cases {
+		t.Run(name, func(t *testing.T) {
+			parent := t.TempDir()
+			dest := filepath.Join(parent, "dest")
+			if err := ExtractTarGz(makeTarGz(t, entries), dest, ExtractOptions{}); err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			if _, err := os.Lstat(filepath.Join(parent, "evil")); err == nil {
+				t.Fatalf("file escaped destination")
+			}
+		})
+	}
+}
+
+func TestExtractTarGz_RejectsWritesThroughSymlinks(t *testing.T) {
+	// tiap symlink lolos cek nama, tapi a/b/x/evil sebenarnya dua level di atas dest
+	archive := makeTarGz(t, []tarEntry{
+		{hdr: tar.Header{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755}},
+		{hdr: tar.Header{Name: "a/b", Typeflag: tar.TypeSymlink, Linkname: ".."}},
+		{hdr: tar.Header{Name: "a/b/x", Typeflag: tar.TypeSymlink, Linkname: "../.."}},
+		{hdr: tar.Header{Name: "a/b/x/evil", Typeflag: tar.TypeReg, Mode: 0644}, body: "x"},
+	})
+
+	parent := t.TempDir()
+	dest := filepath.Join(parent, "x", "dest")
+	if err := ExtractTarGz(archive, dest, ExtractOptions{}); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	for _, p := range []string{filepath.Join(parent, "evil"), filepath.Join(parent, "x", "evil"), filepath.Join(dest, "evil")} {
+		if _, err := os.Lstat(p); err == nil {
+			t.Fatalf("file written through symlink: %s", p)
+		}
+	}
+}
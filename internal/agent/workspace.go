@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// folder di _work yang selalu dipertahankan karena dipakai runner sendiri
+var workspaceAlwaysKeep = []string{"_temp", "_PipelineMapping"}
+
+const workspaceHookName = "tcr-job-completed.sh"
+
+// InstallWorkspaceHook memasang hook ACTIONS_RUNNER_HOOK_JOB_COMPLETED di instance
+// yang menghapus isi _work setelah tiap job, kecuali folder di WorkspaceKeep.
+func InstallWorkspaceHook(instanceDir string, cfg Config) error {
+	if !cfg.WorkspaceCleanup {
+		return nil
+	}
+
+	hookPath := filepath.Join(instanceDir, workspaceHookName)
+	if err := os.WriteFile(hookPath, []byte(workspaceHookScript(instanceDir, cfg.WorkspaceKeep)), 0755); err != nil {
+		return fmt.Errorf("write hook: %w", err)
+	}
+
+	// runner membaca .env di instance saat start
+	f, err := os.OpenFile(filepath.Join(instanceDir, ".env"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open .env: %w", err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "\nACTIONS_RUNNER_HOOK_JOB_COMPLETED=%s\n", hookPath)
+	return err
+}
+
+func workspaceHookScript(instanceDir string, keep []string) string {
+	patterns := append(append([]string{}, workspaceAlwaysKeep...), keep...)
+	return fmt.Sprintf(`#!/bin/sh
+# generated by agentd — wipe _work after each job (keep: %[2]s)
+cd %[1]q 2>/dev/null || exit 0
+for entry in * .[!.]*; do
+  [ -e "$entry" ] || continue
+  case "$entry" in
+    %[3]s) continue ;;
+  esac
+  rm -rf -- "$entry"
+done
+exit 0
+`, filepath.Join(instanceDir, "_work"), strings.Join(patterns, ","), strings.Join(patterns, "|"))
+}
+
+// DiskReport adalah ringkasan pemakaian disk runner yang dikirim ke Tower.
+// DirtyRunners diganti Tower dengan instance bersih: runner yang melewati
+// WorkspaceMaxMB, ditambah workspace terbesar selama Pressure.
+type DiskReport struct {
+	UsedPercent  float64          `json:"used_percent"`
+	Pressure     bool             `json:"pressure"`
+	WorkspaceMB  int64            `json:"workspace_mb"`
+	RunnerMB     map[string]int64 `json:"runner_mb"`
+	DirtyRunners []string         `json:"dirty_runners"`
+}
+
+// WorkspaceMonitor mengukur pemakaian disk secara berkala dan menyimpan hasilnya
+// untuk dilaporkan lewat heartbeat.
+func (a *Agent) WorkspaceMonitor() {
+	for {
+		report := a.refreshDisk()
+		if report.Pressure || len(report.DirtyRunners) > 0 {
+			logger.Warn("disk pressure", "used_percent", report.UsedPercent, "workspace_mb", report.WorkspaceMB, "dirty_runners", report.DirtyRunners)
+		}
+		time.Sleep(60 * time.Second)
+	}
+}
+
+// refreshDisk mengukur ulang disk dan menyimpan hasilnya untuk heartbeat
+func (a *Agent) refreshDisk() DiskReport {
+	report := a.measureDisk()
+	a.mu.Lock()
+	a.disk = report
+	a.mu.Unlock()
+	return report
+}
+
+func (a *Agent) measureDisk() DiskReport {
+	a.mu.Lock()
+	runners := append([]*Runner(nil), a.runners...)
+	cfg := a.config
+	a.mu.Unlock()
+
+	report := DiskReport{RunnerMB: map[string]int64{}, DirtyRunners: []string{}}
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(cfg.RunnerDir, &st); err == nil && st.Blocks > 0 {
+		used := st.Blocks - st.Bfree
+		report.UsedPercent = float64(used) * 100 / float64(used+st.Bavail)
+	}
+	for _, r := range runners {
+		report.RunnerMB[r.Name] = dirSize(filepath.Join(r.Dir, "_work")) >> 20
+	}
+	report.pickDirty(cfg)
+	return report
+}
+
+// pickDirty mengisi WorkspaceMB, Pressure dan DirtyRunners dari RunnerMB.
+// Selama disk di atas DiskPressurePct, workspace terbesar ikut dibersihkan;
+// kalau total workspace melewati WorkspaceTotalMB, workspace terbesar
+// dibersihkan sampai sisanya di bawah batas.
+func (d *DiskReport) pickDirty(cfg Config) {
+	names := make([]string, 0, len(d.RunnerMB))
+	d.WorkspaceMB = 0
+	for name, mb := range d.RunnerMB {
+		names = append(names, name)
+		d.WorkspaceMB += mb
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if d.RunnerMB[names[i]] != d.RunnerMB[names[j]] {
+			return d.RunnerMB[names[i]] > d.RunnerMB[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	diskFull := d.UsedPercent >= float64(cfg.DiskPressurePct)
+	overTotal := cfg.WorkspaceTotalMB > 0 && d.WorkspaceMB > int64(cfg.WorkspaceTotalMB)
+	d.Pressure = diskFull || overTotal
+
+	remaining := d.WorkspaceMB
+	for _, name := range names {
+		mb := d.RunnerMB[name]
+		switch {
+		case cfg.WorkspaceMaxMB > 0 && mb > int64(cfg.WorkspaceMaxMB):
+		case mb > 0 && diskFull && len(d.DirtyRunners) == 0:
+		case mb > 0 && overTotal && remaining > int64(cfg.WorkspaceTotalMB):
+		default:
+			continue
+		}
+		d.DirtyRunners = append(d.DirtyRunners, name)
+		remaining -= mb
+	}
+}
+
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// replaceDirtyRunners mengganti runner yang diminta Tower dengan instance bersih
+func (a *Agent) replaceDirtyRunners(names []string) {
+	// handleDirective sudah memasang replacing; harus dilepas di semua jalur
+	defer func() {
+		a.mu.Lock()
+		a.replacing = false
+		a.mu.Unlock()
+	}()
+
+	a.mu.Lock()
+	var dirty []*Runner
+	for _, r := range a.runners {
+		if contains(names, r.Name) {
+			dirty = append(dirty, r)
+		}
+	}
+	cfg := a.config
+	a.mu.Unlock()
+
+	if len(dirty) == 0 {
+		return
+	}
+
+	logger.Info("replacing dirty runners requested by tower", "runners", len(dirty))
+	_, busy, err := a.replaceRunners(dirty, cfg)
+	if err != nil {
+		logger.Warn("replacing dirty runners failed", "err", err)
+	}
+	if len(busy) > 0 {
+		logger.Info("dirty runners still busy, replacing on a later report", "busy_runners", len(busy))
+	}
+
+	// instance baru memakai nama yang sama: ukur ulang supaya heartbeat
+	// berikutnya tidak melaporkan runner yang baru dibersihkan sebagai kotor
+	a.refreshDisk()
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiskReport_PickDirty(t *testing.T) {
+	sizes := func() map[string]int64 {
+		return map[string]int64{"r1": 300, "r2": 900, "r3": 500, "r4": 0}
+	}
+	cases := []struct {
+		name     string
+		used     float64
+		cfg      Config
+		pressure bool
+		dirty    []string
+	}{
+		{"per-runner limit only", 10, Config{DiskPressurePct: 90, WorkspaceMaxMB: 400}, false, []string{"r2", "r3"}},
+		{"disk full cleans the largest", 95, Config{DiskPressurePct: 90}, true, []string{"r2"}},
+		{"total limit cleans until under", 10, Config{DiskPressurePct: 90, WorkspaceTotalMB: 600}, true, []string{"r2", "r3"}},
+		{"total limit not reached", 10, Config{DiskPressurePct: 90, WorkspaceTotalMB: 2000}, false, nil},
+	}
+	for _, c := range cases {
+		d := DiskReport{UsedPercent: c.used, RunnerMB: sizes()}
+		d.pickDirty(c.cfg)
+		if d.Pressure != c.pressure || !reflect.DeepEqual(d.DirtyRunners, c.dirty) || d.WorkspaceMB != 1700 {
+			t.Errorf("%s: got pressure=%v dirty=%v total=%d", c.name, d.Pressure, d.DirtyRunners, d.WorkspaceMB)
+		}
+	}
+}
+
+func TestReplaceDirtyRunners_ResetsFlagAndRemeasures(t *testing.T) {
+	dir := t.TempDir()
+	a := &Agent{config: Config{RunnerDir: dir, DiskPressurePct: 100, WorkspaceMaxMB: 1}}
+	a.runners = []*Runner{{Name: "vm-agent-01", Dir: dir}}
+	a.disk = DiskReport{DirtyRunners: []string{"vm-agent-01"}}
+
+	// tidak ada nama yang cocok: flag tetap harus dilepas
+	a.replacing = true
+	a.replaceDirtyRunners([]string{"gone"})
+	if a.replacing {
+		t.Fatalf("replacing flag left set, later upgrades would be skipped forever")
+	}
+
+	a.refreshDisk()
+	if len(a.disk.DirtyRunners) != 0 {
+		t.Fatalf("expected clean workspace after re-measure, got %v", a.disk.DirtyRunners)
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ridwandwisiswanto/tcr/internal/audit"
+)
+
+// auditFilter membaca filter audit dari query string (sama untuk list dan export)
+func auditFilter(r *http.Request) (audit.Filter, Query, error) {
+	q, err := parseQuery(r)
+	if err != nil {
+		return audit.Filter{}, q, err
+	}
+	v := r.URL.Query()
+	f := audit.Filter{
+		Actor:  v.Get("actor"),
+		Action: v.Get("action"),
+		Target: v.Get("target"),
+		Result: v.Get("result"),
+		Since:  q.Since,
+		Until:  q.Until,
+	}
+	if f.Result != "" && f.Result != audit.ResultOK && f.Result != audit.ResultError {
+		return f, q, BadRequest("result must be %q or %q", audit.ResultOK, audit.ResultError)
+	}
+	return f, q, nil
+}
+
+func listAudit(r *http.Request) (interface{}, error) {
+	f, q, err := auditFilter(r)
+	if err != nil {
+		return nil, err
+	}
+	var items []audit.Entry
+	if err := audit.Each(f, func(e audit.Entry) error {
+		items = append(items, e)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return paginate(items, q, map[string]sorter[audit.Entry]{
+		"time":   func(a, b audit.Entry) bool { return a.ID < b.ID },
+		"actor":  func(a, b audit.Entry) bool { return a.Actor < b.Actor },
+		"action": func(a, b audit.Entry) bool { return a.Action < b.Action },
+	}, "time")
+}
+
+// exportAudit menulis entry yang cocok sebagai JSON lines (satu entry per
+// baris, terlama dulu) langsung dari file audit, tanpa paging
+func exportAudit(w http.ResponseWriter, r *http.Request) {
+	f, _, err := auditFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	enc := json.NewEncoder(w)
+	if err := audit.Each(f, func(e audit.Entry) error { return enc.Encode(e) }); err != nil {
+		// header sudah terkirim; cukup catat di log
+		logger.Error("audit export failed", "err", err)
+	}
+}
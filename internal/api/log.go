@@ -0,0 +1,5 @@
+package api
+
+import "github.com/ridwandwisiswanto/tcr/internal/logging"
+
+var logger = logging.For("api")
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// OpenAPI membangun dokumen OpenAPI 3 dari route yang terdaftar; schema
+// response diturunkan dari tipe Go (tag json) lewat reflection.
+func (rt *Router) OpenAPI() map[string]interface{} {
+	schemas := map[string]interface{}{
+		"Error": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"error": schemaOf(reflect.TypeOf(Error{}), nil),
+			},
+		},
+	}
+
+	paths := map[string]interface{}{}
+	for _, route := range rt.routes {
+		ok := map[string]interface{}{"description": "No Content"}
+		status := "204"
+		if route.Response != nil {
+			status = "200"
+			contentType := "application/json"
+			if route.Stream != nil {
+				contentType = "text/event-stream"
+			}
+			if route.ContentType != "" {
+				contentType = route.ContentType
+			}
+			ok = map[string]interface{}{
+				"description": "OK",
+				"content": map[string]interface{}{
+					contentType: map[string]interface{}{
+						"schema": schemaOf(reflect.TypeOf(route.Response), schemas),
+					},
+				},
+			}
+		}
+		op := map[string]interface{}{
+			"summary":     route.Summary,
+			"operationId": operationID(route),
+			"responses": map[string]interface{}{
+				status: ok,
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"},
+						},
+					},
+				},
+			},
+		}
+
+		if route.Request != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": schemaOf(reflect.TypeOf(route.Request), schemas),
+					},
+				},
+			}
+		}
+
+		var params []interface{}
+		for _, seg := range strings.Split(route.Path, "/") {
+			if strings.HasPrefix(seg, "{") {
+				params = append(params, map[string]interface{}{
+					"name": strings.Trim(seg, "{}"), "in": "path", "required": true,
+					"schema": map[string]interface{}{"type": "string"},
+				})
+			}
+		}
+		query := route.Params
+		if route.List {
+			query = append(query,
+				Param{Name: "limit", Type: "integer", Description: "page size (default 50, max 500)"},
+				Param{Name: "offset", Type: "integer", Description: "items to skip"},
+				Param{Name: "sort", Type: "string", Description: "field to sort by, prefix with - for descending"},
+			)
+		}
+		for _, p := range query {
+			schema := map[string]interface{}{"type": p.Type}
+			if p.Format != "" {
+				schema["format"] = p.Format
+			}
+			params = append(params, map[string]interface{}{
+				"name": p.Name, "in": "query", "description": p.Description, "schema": schema,
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		path := rt.Prefix + route.Path
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   rt.Title,
+			"version": rt.Version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearer": []interface{}{}}},
+	}
+}
+
+func operationID(r Route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(r.Method))
+	for _, seg := range strings.Split(r.Path, "/") {
+		seg = strings.Trim(seg, "{}")
+		seg = strings.TrimSuffix(seg, ".json")
+		if seg == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(seg[:1]) + seg[1:])
+	}
+	return b.String()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf mengubah tipe Go menjadi JSON schema. Struct bernama didaftarkan
+// di components (kalau schemas tidak nil) dan dirujuk dengan $ref.
+func schemaOf(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		name := schemaName(t)
+		if schemas != nil && name != "" {
+			if _, ok := schemas[name]; !ok {
+				schemas[name] = nil // cegah rekursi
+				schemas[name] = structSchema(t, schemas)
+			}
+			return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+		}
+		return structSchema(t, schemas)
+	}
+	return map[string]interface{}{}
+}
+
+func structSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	props := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = schemaOf(f.Type, schemas)
+	}
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+// schemaName: "Job" untuk Job, "JobPage" untuk Page[Job]
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.Index(name, "["); i >= 0 {
+		inner := name[i+1 : len(name)-1]
+		if j := strings.LastIndex(inner, "."); j >= 0 {
+			inner = inner[j+1:]
+		}
+		return inner + name[:i]
+	}
+	return name
+}
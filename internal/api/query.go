@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// Page adalah envelope untuk semua list endpoint
+type Page[T any] struct {
+	Items  []T `json:"items"`
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// Query berisi parameter list umum: filter, rentang waktu, paging dan sorting
+type Query struct {
+	Repo   string
+	Status string
+	Label  string
+	Type   string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+	Sort   string
+	Desc   bool
+}
+
+// parseQuery membaca query string; nilai yang salah menjadi error 400
+func parseQuery(r *http.Request) (Query, error) {
+	v := r.URL.Query()
+	q := Query{
+		Repo:   v.Get("repo"),
+		Status: v.Get("status"),
+		Label:  v.Get("label"),
+		Type:   v.Get("type"),
+		Limit:  defaultLimit,
+	}
+
+	var err error
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil || q.Limit < 1 || q.Limit > maxLimit {
+			return q, BadRequest("limit must be between 1 and %d", maxLimit)
+		}
+	}
+	if s := v.Get("offset"); s != "" {
+		if q.Offset, err = strconv.Atoi(s); err != nil || q.Offset < 0 {
+			return q, BadRequest("offset must be a non-negative integer")
+		}
+	}
+	for name, dst := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if s := v.Get(name); s != "" {
+			if *dst, err = time.Parse(time.RFC3339, s); err != nil {
+				return q, BadRequest("%s must be an RFC3339 timestamp", name)
+			}
+		}
+	}
+	q.Sort = v.Get("sort")
+	if strings.HasPrefix(q.Sort, "-") {
+		q.Sort, q.Desc = q.Sort[1:], true
+	}
+	return q, nil
+}
+
+// inRange true kalau t ada di [Since, Until]
+func (q Query) inRange(t time.Time) bool {
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && t.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// sorter membandingkan dua item untuk satu field sort
+type sorter[T any] func(a, b T) bool
+
+// paginate mengurutkan (field default def) lalu memotong items sesuai limit/offset
+func paginate[T any](items []T, q Query, sorters map[string]sorter[T], def string) (Page[T], error) {
+	field := q.Sort
+	if field == "" {
+		field = def
+	}
+	less, ok := sorters[field]
+	if !ok {
+		fields := make([]string, 0, len(sorters))
+		for f := range sorters {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		return Page[T]{}, BadRequest("cannot sort by %q (allowed: %s)", field, strings.Join(fields, ", "))
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if q.Desc {
+			return less(items[j], items[i])
+		}
+		return less(items[i], items[j])
+	})
+
+	p := Page[T]{Items: []T{}, Total: len(items), Offset: q.Offset, Limit: q.Limit}
+	if q.Offset < len(items) {
+		end := q.Offset + q.Limit
+		if end > len(items) {
+			end = len(items)
+		}
+		p.Items = items[q.Offset:end]
+	}
+	return p, nil
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
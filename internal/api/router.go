@@ -0,0 +1,179 @@
+// Package api menyediakan REST admin API berversi towerd (/api/v1) beserta
+// dokumen OpenAPI yang dibangun dari daftar route yang terdaftar.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ridwandwisiswanto/tcr/internal/controller"
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+	"github.com/ridwandwisiswanto/tcr/internal/github"
+)
+
+// Param adalah query parameter yang diterima sebuah route (untuk OpenAPI)
+type Param struct {
+	Name        string
+	Type        string // string | integer | boolean
+	Format      string // opsional, misal date-time
+	Description string
+}
+
+// Route adalah satu operasi API. Handle mengembalikan nilai yang di-encode
+// sebagai JSON, atau *Error untuk balasan error.
+type Route struct {
+	Method   string
+	Path     string // relatif terhadap prefix, misal "/jobs/{id}"
+	Summary  string
+	Params   []Param
+	List     bool        // menerima limit/offset/sort
+	Request  interface{} // body JSON yang diterima (opsional, untuk OpenAPI)
+	Response interface{} // nilai contoh untuk schema OpenAPI; nil = 204
+	Handle   func(r *http.Request) (interface{}, error)
+
+	// Stream menulis balasan sendiri (misal Server-Sent Events); kalau di-set,
+	// Handle tidak dipakai dan Response menjadi schema tiap event
+	Stream http.HandlerFunc
+	// ContentType balasan Stream di OpenAPI kalau bukan text/event-stream
+	ContentType string
+}
+
+// Error adalah body error yang konsisten di seluruh API:
+//
+//	{"error": {"status": 404, "code": "not_found", "message": "..."}}
+type Error struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func errorf(status int, code, format string, args ...interface{}) *Error {
+	return &Error{Status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// NotFound dan BadRequest dipakai handler untuk error umum
+func NotFound(format string, args ...interface{}) *Error {
+	return errorf(http.StatusNotFound, "not_found", format, args...)
+}
+
+func BadRequest(format string, args ...interface{}) *Error {
+	return errorf(http.StatusBadRequest, "invalid_parameter", format, args...)
+}
+
+// Router mencocokkan path dengan segmen {param} dan menulis semua balasan sebagai JSON
+type Router struct {
+	Prefix  string
+	Title   string
+	Version string
+	routes  []Route
+}
+
+func NewRouter(prefix, title, version string) *Router {
+	return &Router{Prefix: strings.TrimRight(prefix, "/"), Title: title, Version: version}
+}
+
+func (rt *Router) Handle(route Route) {
+	rt.routes = append(rt.routes, route)
+}
+
+func (rt *Router) Routes() []Route {
+	return append([]Route(nil), rt.routes...)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, rt.Prefix)
+	if path == "" {
+		path = "/"
+	}
+
+	methodMismatch := false
+	for _, route := range rt.routes {
+		vars, ok := match(route.Path, path)
+		if !ok {
+			continue
+		}
+		if route.Method != r.Method {
+			methodMismatch = true
+			continue
+		}
+		for k, v := range vars {
+			r.SetPathValue(k, v)
+		}
+		if route.Stream != nil {
+			route.Stream(w, r)
+			return
+		}
+
+		v, err := route.Handle(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if v == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+		return
+	}
+
+	if methodMismatch {
+		writeError(w, errorf(http.StatusMethodNotAllowed, "method_not_allowed", "%s not allowed on %s", r.Method, r.URL.Path))
+		return
+	}
+	writeError(w, NotFound("no route for %s", r.URL.Path))
+}
+
+// match membandingkan pattern "/jobs/{id}" dengan path "/jobs/42"
+func match(pattern, path string) (map[string]string, bool) {
+	ps := strings.Split(strings.Trim(pattern, "/"), "/")
+	xs := strings.Split(strings.Trim(path, "/"), "/")
+	if len(ps) != len(xs) {
+		return nil, false
+	}
+	vars := map[string]string{}
+	for i, p := range ps {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			if xs[i] == "" {
+				return nil, false
+			}
+			vars[p[1:len(p)-1]] = xs[i]
+			continue
+		}
+		if p != xs[i] {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+	case errors.Is(err, controller.ErrNotFound), errors.Is(err, github.ErrDeliveryNotFound):
+		e = errorf(http.StatusNotFound, "not_found", "%v", err)
+	case errors.Is(err, controller.ErrConflict), errors.Is(err, github.ErrReplayFailed),
+		errors.Is(err, github.ErrReconcileDisabled):
+		e = errorf(http.StatusConflict, "conflict", "%v", err)
+	case errors.Is(err, controller.ErrInvalid):
+		e = errorf(http.StatusBadRequest, "invalid_parameter", "%v", err)
+	case errors.Is(err, core.ErrQueueFull):
+		e = errorf(http.StatusServiceUnavailable, "queue_full", "%v", err)
+	default:
+		logger.Error("api request failed", "err", err)
+		e = errorf(http.StatusInternalServerError, "internal", "internal error")
+	}
+	writeJSON(w, e.Status, map[string]*Error{"error": e})
+}
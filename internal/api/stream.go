@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/events"
+)
+
+// keepAlive menjaga koneksi SSE tetap hidup di balik proxy yang memutus koneksi idle
+const keepAlive = 15 * time.Second
+
+// streamEvents mengirim event sebagai Server-Sent Events:
+//
+//	id: 42
+//	event: job.status
+//	data: {"id":42,"type":"job.status",...}
+//
+// Client yang menyambung ulang dengan Last-Event-ID (otomatis oleh
+// EventSource) mendapat replay event yang terlewat dari ring buffer.
+func streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, errorf(http.StatusInternalServerError, "internal", "streaming not supported"))
+		return
+	}
+
+	q := r.URL.Query()
+	filter := events.ParseFilter(q.Get("type"), q.Get("subject"))
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = q.Get("last_event_id")
+	}
+	var since int64
+	if lastID != "" {
+		n, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil || n < 0 {
+			writeError(w, BadRequest("invalid last event id %q", lastID))
+			return
+		}
+		since = n
+	}
+
+	// subscribe dulu sebelum replay supaya tidak ada event yang jatuh di antaranya
+	sub := events.Subscribe(filter, 256)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	if lastID != "" {
+		for _, ev := range events.Since(since, filter) {
+			writeEvent(w, ev)
+			since = ev.ID
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				// terlalu lambat; client menyambung ulang dan mengejar lewat Last-Event-ID
+				return
+			}
+			if ev.ID <= since {
+				continue // sudah terkirim lewat replay
+			}
+			writeEvent(w, ev)
+			flusher.Flush()
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, ev events.Event) {
+	data, _ := json.Marshal(ev)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+}
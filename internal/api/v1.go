@@ -0,0 +1,648 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/audit"
+	"github.com/ridwandwisiswanto/tcr/internal/controller"
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+	"github.com/ridwandwisiswanto/tcr/internal/events"
+	"github.com/ridwandwisiswanto/tcr/internal/github"
+)
+
+// Job adalah representasi job di API v1
+type Job struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`
+	Repo      string    `json:"repo"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Labels    []string  `json:"labels"`
+	CreatedAt time.Time `json:"created_at"`
+
+	RunnerID    string      `json:"runner_id,omitempty"`
+	RunID       int64       `json:"run_id,omitempty"`
+	GitHubJobID int64       `json:"github_job_id,omitempty"`
+	TraceID     string      `json:"trace_id,omitempty"` // trace OpenTelemetry job di collector
+	History     []JobAction `json:"history"`
+
+	QueuedAt     time.Time  `json:"queued_at"`
+	DispatchedAt *time.Time `json:"dispatched_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	WaitSeconds  float64    `json:"wait_seconds"` // antre sampai dispatch (atau sampai sekarang)
+}
+
+// QueueStats meringkas antrean job: berapa yang menunggu dan berapa lama
+type QueueStats struct {
+	Queued     int `json:"queued"`
+	Dispatched int `json:"dispatched"`
+	Finished   int `json:"finished"`
+
+	OldestQueuedSeconds float64 `json:"oldest_queued_seconds"`
+
+	// wait time job yang di-dispatch dalam window terakhir
+	WindowSeconds  int     `json:"window_seconds"`
+	WaitSamples    int     `json:"wait_samples"`
+	WaitAvgSeconds float64 `json:"wait_avg_seconds"`
+	WaitP50Seconds float64 `json:"wait_p50_seconds"`
+	WaitP95Seconds float64 `json:"wait_p95_seconds"`
+	WaitMaxSeconds float64 `json:"wait_max_seconds"`
+
+	// buffer intake antara webhook dan scheduler
+	Intake core.IntakeStats `json:"intake"`
+}
+
+// JobAction adalah satu aksi operator pada job (cancel / requeue)
+type JobAction struct {
+	Action string    `json:"action"`
+	By     string    `json:"by"`
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// JobActionRequest adalah body (opsional) POST /jobs/{id}/cancel dan /requeue
+type JobActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Runner adalah runner (runnerd) yang heartbeat ke towerd
+type Runner struct {
+	ID       string    `json:"id"`
+	Address  string    `json:"address"`
+	Port     string    `json:"port"`
+	Status   string    `json:"status"` // idle | busy | draining | offline
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Agent adalah VM yang menjalankan agentd
+type Agent struct {
+	ID              string    `json:"id"`
+	Address         string    `json:"address"`
+	State           string    `json:"state"`
+	Active          bool      `json:"active"`
+	Runners         int       `json:"runners"`
+	Labels          []string  `json:"labels"`
+	RunnerVersion   string    `json:"runner_version"`
+	CachedVersions  []string  `json:"cached_versions"`
+	DiskUsedPercent float64   `json:"disk_used_percent"`
+	DiskPressure    bool      `json:"disk_pressure"`
+	DirtyRunners    []string  `json:"dirty_runners"`
+	DrainRequested  bool      `json:"drain_requested"`
+	LastSeen        time.Time `json:"last_seen"`
+}
+
+// Pool mengelompokkan agent berdasarkan label runner ("default" kalau tanpa label)
+type Pool struct {
+	Name         string   `json:"name"`
+	Agents       int      `json:"agents"`
+	ActiveAgents int      `json:"active_agents"`
+	Runners      int      `json:"runners"`
+	AgentIDs     []string `json:"agent_ids"`
+}
+
+// ScaleRequest adalah body POST /pools/scale
+type ScaleRequest struct {
+	Direction string `json:"direction"` // up | down
+	Count     int    `json:"count"`
+}
+
+const defaultPool = "default"
+
+func jobView(j core.Job) Job {
+	labels := j.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	queuedAt := j.QueuedAt
+	if queuedAt.IsZero() {
+		queuedAt = j.CreatedAt
+	}
+	history := make([]JobAction, 0, len(j.Actions))
+	for _, a := range j.Actions {
+		history = append(history, JobAction{Action: a.Action, By: a.By, Reason: a.Reason, At: a.At})
+	}
+	return Job{
+		ID:        j.ID,
+		Action:    j.Action,
+		Repo:      j.RepoOwner + "/" + j.RepoName,
+		Name:      j.JobName,
+		Status:    j.Status,
+		Labels:    labels,
+		CreatedAt: j.CreatedAt,
+
+		RunnerID:    j.RunnerID,
+		RunID:       j.RunID,
+		GitHubJobID: j.GitHubJobID,
+		TraceID:     j.TraceID,
+		History:     history,
+
+		QueuedAt:     queuedAt,
+		DispatchedAt: optionalTime(j.DispatchedAt),
+		FinishedAt:   optionalTime(j.FinishedAt),
+		WaitSeconds:  j.Wait(time.Now()).Seconds(),
+	}
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func runnerView(r controller.Runner) Runner {
+	status := "idle"
+	switch {
+	case time.Since(r.LastSeen) > 30*time.Second:
+		status = "offline"
+	case r.Draining:
+		status = "draining"
+	case r.IsBusy:
+		status = "busy"
+	}
+	return Runner{ID: r.ID, Address: r.Address, Port: r.Port, Status: status, LastSeen: r.LastSeen}
+}
+
+func agentView(a controller.Agent) Agent {
+	v := Agent{
+		ID:              a.ID,
+		Address:         a.Address,
+		State:           a.State,
+		Active:          a.IsActive,
+		Runners:         a.Runners,
+		Labels:          a.Labels,
+		RunnerVersion:   a.RunnerVersion,
+		CachedVersions:  a.CachedVersions,
+		DiskUsedPercent: a.DiskUsedPercent,
+		DiskPressure:    a.DiskPressure,
+		DirtyRunners:    a.DirtyRunners,
+		DrainRequested:  a.DrainRequested,
+		LastSeen:        a.LastSeen,
+	}
+	for _, s := range []*[]string{&v.Labels, &v.CachedVersions, &v.DirtyRunners} {
+		if *s == nil {
+			*s = []string{}
+		}
+	}
+	return v
+}
+
+// NewV1 membangun router /api/v1
+func NewV1() *Router {
+	rt := NewRouter("/api/v1", "tcr tower admin API", "1.0.0")
+
+	timeParams := []Param{
+		{Name: "since", Type: "string", Format: "date-time", Description: "only items at or after this time"},
+		{Name: "until", Type: "string", Format: "date-time", Description: "only items at or before this time"},
+	}
+
+	rt.Handle(Route{
+		Method: "GET", Path: "/jobs", Summary: "List jobs", List: true,
+		Params: append([]Param{
+			{Name: "repo", Type: "string", Description: "owner/name"},
+			{Name: "status", Type: "string", Description: "queued, dispatched, done, ..."},
+			{Name: "label", Type: "string", Description: "runner label requested by the job"},
+			{Name: "trace_id", Type: "string", Description: "OpenTelemetry trace ID of the job"},
+		}, timeParams...),
+		Response: Page[Job]{},
+		Handle:   listJobs,
+	})
+	rt.Handle(Route{
+		Method: "GET", Path: "/queue", Summary: "Queue depth and wait time statistics",
+		Params: []Param{
+			{Name: "window", Type: "integer", Description: "seconds of dispatched jobs to include in wait stats (default 3600)"},
+		},
+		Response: QueueStats{},
+		Handle:   queueStats,
+	})
+	rt.Handle(Route{
+		Method: "GET", Path: "/jobs/{id}", Summary: "Get a job",
+		Response: Job{},
+		Handle: func(r *http.Request) (interface{}, error) {
+			id := r.PathValue("id")
+			for _, j := range controller.GetJobs() {
+				if j.ID == id {
+					return jobView(j), nil
+				}
+			}
+			return nil, NotFound("job %s not found", id)
+		},
+	})
+	rt.Handle(Route{
+		Method: "POST", Path: "/jobs/{id}/cancel", Summary: "Cancel a job, abort it on its runner and free the runner",
+		Request:  JobActionRequest{},
+		Response: Job{},
+		Handle:   jobAction(controller.CancelJob),
+	})
+	rt.Handle(Route{
+		Method: "POST", Path: "/jobs/{id}/requeue", Summary: "Put a finished or cancelled job back in the queue",
+		Request:  JobActionRequest{},
+		Response: Job{},
+		Handle:   jobAction(controller.RequeueJob),
+	})
+
+	rt.Handle(Route{
+		Method: "GET", Path: "/runners", Summary: "List runners", List: true,
+		Params: append([]Param{
+			{Name: "status", Type: "string", Description: "idle, busy, draining or offline"},
+		}, timeParams...),
+		Response: Page[Runner]{},
+		Handle:   listRunners,
+	})
+	rt.Handle(Route{
+		Method: "GET", Path: "/runners/{id}", Summary: "Get a runner",
+		Response: Runner{},
+		Handle: func(r *http.Request) (interface{}, error) {
+			id := r.PathValue("id")
+			for _, rn := range controller.ListRunners() {
+				if rn.ID == id {
+					return runnerView(rn), nil
+				}
+			}
+			return nil, NotFound("runner %s not found", id)
+		},
+	})
+
+	rt.Handle(Route{
+		Method: "POST", Path: "/runners/{id}/drain", Summary: "Stop dispatching new jobs to a runner",
+		Response: Runner{},
+		Handle: func(r *http.Request) (interface{}, error) {
+			rn, err := controller.DrainRunner(r.PathValue("id"), controller.Actor(r))
+			if err != nil {
+				return nil, err
+			}
+			return runnerView(rn), nil
+		},
+	})
+	rt.Handle(Route{
+		Method: "DELETE", Path: "/runners/{id}", Summary: "Remove an idle runner from towerd and GitHub",
+		Handle: func(r *http.Request) (interface{}, error) {
+			return nil, controller.RemoveRunner(r.PathValue("id"), controller.Actor(r))
+		},
+	})
+
+	rt.Handle(Route{
+		Method: "GET", Path: "/agents", Summary: "List agents", List: true,
+		Params: append([]Param{
+			{Name: "status", Type: "string", Description: "agent state: running, draining, upgrading, stopped"},
+			{Name: "label", Type: "string", Description: "runner label served by the agent"},
+		}, timeParams...),
+		Response: Page[Agent]{},
+		Handle:   listAgents,
+	})
+	rt.Handle(Route{
+		Method: "GET", Path: "/agents/{id}", Summary: "Get an agent",
+		Response: Agent{},
+		Handle: func(r *http.Request) (interface{}, error) {
+			id := r.PathValue("id")
+			for _, a := range controller.ListAgents() {
+				if a.ID == id {
+					return agentView(a), nil
+				}
+			}
+			return nil, NotFound("agent %s not found", id)
+		},
+	})
+
+	rt.Handle(Route{
+		Method: "POST", Path: "/agents/{id}/drain", Summary: "Drain an agent VM and stop agentd",
+		Response: Agent{},
+		Handle: func(r *http.Request) (interface{}, error) {
+			a, err := controller.DrainAgent(r.PathValue("id"), controller.Actor(r))
+			if err != nil {
+				return nil, err
+			}
+			return agentView(a), nil
+		},
+	})
+	rt.Handle(Route{
+		Method: "DELETE", Path: "/agents/{id}", Summary: "Forget an agent",
+		Handle: func(r *http.Request) (interface{}, error) {
+			return nil, controller.RemoveAgent(r.PathValue("id"), controller.Actor(r))
+		},
+	})
+
+	rt.Handle(Route{
+		Method: "GET", Path: "/pools", Summary: "List pools (agents grouped by runner label)", List: true,
+		Params:   []Param{{Name: "label", Type: "string", Description: "only this pool"}},
+		Response: Page[Pool]{},
+		Handle:   listPools,
+	})
+	rt.Handle(Route{
+		Method: "GET", Path: "/pools/capacity", Summary: "Pool capacity summary",
+		Response: controller.Capacity{},
+		Handle: func(r *http.Request) (interface{}, error) {
+			return controller.GetCapacity(), nil
+		},
+	})
+
+	rt.Handle(Route{
+		Method: "POST", Path: "/pools/scale", Summary: "Scale the pool up or down",
+		Request:  ScaleRequest{},
+		Response: ScaleRequest{},
+		Handle: func(r *http.Request) (interface{}, error) {
+			var req ScaleRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				return nil, BadRequest("invalid JSON body: %v", err)
+			}
+			if err := controller.Scale(req.Direction, req.Count, controller.Actor(r)); err != nil {
+				return nil, err
+			}
+			return req, nil
+		},
+	})
+
+	rt.Handle(Route{
+		Method: "GET", Path: "/events", Summary: "List recent events", List: true,
+		Params: append([]Param{
+			{Name: "type", Type: "string", Description: "event types or prefixes, comma separated (job.*, runner.busy, ...)"},
+			{Name: "subject", Type: "string", Description: "job/runner/agent ids, comma separated"},
+		}, timeParams...),
+		Response: Page[events.Event]{},
+		Handle:   listEvents,
+	})
+	rt.Handle(Route{
+		Method: "GET", Path: "/events/stream", Summary: "Stream events as Server-Sent Events",
+		Params: []Param{
+			{Name: "type", Type: "string", Description: "event types or prefixes, comma separated (job.*, runner.busy, ...)"},
+			{Name: "subject", Type: "string", Description: "job/runner/agent ids, comma separated"},
+			{Name: "last_event_id", Type: "integer", Description: "replay stored events after this id (same as the Last-Event-ID header)"},
+		},
+		Response: events.Event{},
+		Stream:   streamEvents,
+	})
+
+	auditParams := append([]Param{
+		{Name: "actor", Type: "string", Description: "who performed the action (admin, agent/runner id, poller, bootstrap)"},
+		{Name: "action", Type: "string", Description: "action or prefix (runner.remove, token.*, ...)"},
+		{Name: "target", Type: "string", Description: "job/runner/agent id, token kind, version, ..."},
+		{Name: "result", Type: "string", Description: "ok or error"},
+	}, timeParams...)
+	rt.Handle(Route{
+		Method: "GET", Path: "/audit", Summary: "Query the audit log of administrative and scaling actions", List: true,
+		Params:   auditParams,
+		Response: Page[audit.Entry]{},
+		Handle:   listAudit,
+	})
+	rt.Handle(Route{
+		Method: "GET", Path: "/audit/export", Summary: "Export matching audit entries as JSON lines",
+		Params:      auditParams,
+		Response:    audit.Entry{},
+		Stream:      exportAudit,
+		ContentType: "application/x-ndjson",
+	})
+
+	rt.Handle(Route{
+		Method: "GET", Path: "/webhooks/deliveries", Summary: "List stored webhook deliveries (without headers and body)", List: true,
+		Params: append([]Param{
+			{Name: "status", Type: "string", Description: "delivery status: received, processed, ignored, dropped, failed"},
+			{Name: "event", Type: "string", Description: "GitHub event (workflow_job, ...)"},
+			{Name: "repo", Type: "string", Description: "owner/name"},
+			{Name: "source", Type: "string", Description: "webhook or reconcile"},
+		}, timeParams...),
+		Response: Page[github.Delivery]{},
+		Handle:   listDeliveries,
+	})
+	rt.Handle(Route{
+		Method: "GET", Path: "/webhooks/deliveries/{id}", Summary: "Get a stored webhook delivery with headers and raw body",
+		Response: github.Delivery{},
+		Handle:   getDelivery,
+	})
+	rt.Handle(Route{
+		Method: "POST", Path: "/webhooks/deliveries/{id}/replay", Summary: "Re-process a stored webhook delivery",
+		Response: github.Delivery{},
+		Handle:   replayDelivery,
+	})
+	rt.Handle(Route{
+		Method: "POST", Path: "/webhooks/replay", Summary: "Re-process stored deliveries by id or by status",
+		Request:  ReplayRequest{},
+		Response: []ReplayResult{},
+		Handle:   replayDeliveries,
+	})
+	rt.Handle(Route{
+		Method: "POST", Path: "/webhooks/reconcile", Summary: "Recover missed deliveries from GitHub's webhook deliveries API",
+		Params:   []Param{{Name: "since", Type: "string", Description: "RFC3339; default: since the previous reconciliation"}},
+		Response: github.ReconcileResult{},
+		Handle:   reconcileDeliveries,
+	})
+
+	rt.Handle(Route{
+		Method: "GET", Path: "/openapi.json", Summary: "OpenAPI document for this API",
+		Response: map[string]interface{}{},
+		Handle: func(r *http.Request) (interface{}, error) {
+			return rt.OpenAPI(), nil
+		},
+	})
+	return rt
+}
+
+// jobAction menjalankan cancel/requeue atas nama pemanggil; body boleh kosong
+func jobAction(action func(id, by, reason string) (core.Job, error)) func(r *http.Request) (interface{}, error) {
+	return func(r *http.Request) (interface{}, error) {
+		var req JobActionRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+				return nil, BadRequest("invalid JSON body: %v", err)
+			}
+		}
+		j, err := action(r.PathValue("id"), controller.Actor(r), req.Reason)
+		if err != nil {
+			return nil, err
+		}
+		return jobView(j), nil
+	}
+}
+
+func listJobs(r *http.Request) (interface{}, error) {
+	q, err := parseQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	traceID := r.URL.Query().Get("trace_id")
+	var items []Job
+	for _, j := range controller.GetJobs() {
+		v := jobView(j)
+		if (q.Repo != "" && !strings.EqualFold(v.Repo, q.Repo)) ||
+			(q.Status != "" && v.Status != q.Status) ||
+			(q.Label != "" && !hasLabel(v.Labels, q.Label)) ||
+			(traceID != "" && !strings.EqualFold(v.TraceID, traceID)) ||
+			!q.inRange(v.CreatedAt) {
+			continue
+		}
+		items = append(items, v)
+	}
+	return paginate(items, q, map[string]sorter[Job]{
+		"created_at": func(a, b Job) bool { return a.CreatedAt.Before(b.CreatedAt) },
+		"status":     func(a, b Job) bool { return a.Status < b.Status },
+		"repo":       func(a, b Job) bool { return a.Repo < b.Repo },
+		"name":       func(a, b Job) bool { return a.Name < b.Name },
+		"wait":       func(a, b Job) bool { return a.WaitSeconds < b.WaitSeconds },
+	}, "created_at")
+}
+
+func queueStats(r *http.Request) (interface{}, error) {
+	window := 3600
+	if v := r.URL.Query().Get("window"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, BadRequest("window must be a positive number of seconds, got %q", v)
+		}
+		window = n
+	}
+
+	now := time.Now()
+	since := now.Add(-time.Duration(window) * time.Second)
+	stats := QueueStats{WindowSeconds: window, Intake: core.JobQueue.Stats()}
+	var waits []float64
+	for _, j := range controller.GetJobs() {
+		switch {
+		case j.Status == "queued":
+			stats.Queued++
+			if w := j.Wait(now).Seconds(); w > stats.OldestQueuedSeconds {
+				stats.OldestQueuedSeconds = w
+			}
+		case !j.FinishedAt.IsZero():
+			stats.Finished++
+		case !j.DispatchedAt.IsZero():
+			stats.Dispatched++
+		}
+		if !j.DispatchedAt.IsZero() && j.DispatchedAt.After(since) {
+			waits = append(waits, j.Wait(now).Seconds())
+		}
+	}
+
+	if len(waits) > 0 {
+		sort.Float64s(waits)
+		var sum float64
+		for _, w := range waits {
+			sum += w
+		}
+		stats.WaitSamples = len(waits)
+		stats.WaitAvgSeconds = sum / float64(len(waits))
+		stats.WaitP50Seconds = percentile(waits, 0.50)
+		stats.WaitP95Seconds = percentile(waits, 0.95)
+		stats.WaitMaxSeconds = waits[len(waits)-1]
+	}
+	return stats, nil
+}
+
+// percentile dari slice yang sudah urut (nearest rank)
+func percentile(sorted []float64, p float64) float64 {
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func listRunners(r *http.Request) (interface{}, error) {
+	q, err := parseQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	var items []Runner
+	for _, rn := range controller.ListRunners() {
+		v := runnerView(rn)
+		if (q.Status != "" && v.Status != q.Status) || !q.inRange(v.LastSeen) {
+			continue
+		}
+		items = append(items, v)
+	}
+	return paginate(items, q, map[string]sorter[Runner]{
+		"id":        func(a, b Runner) bool { return a.ID < b.ID },
+		"status":    func(a, b Runner) bool { return a.Status < b.Status },
+		"last_seen": func(a, b Runner) bool { return a.LastSeen.Before(b.LastSeen) },
+	}, "id")
+}
+
+func listAgents(r *http.Request) (interface{}, error) {
+	q, err := parseQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	var items []Agent
+	for _, a := range controller.ListAgents() {
+		v := agentView(a)
+		if (q.Status != "" && v.State != q.Status) ||
+			(q.Label != "" && !hasLabel(v.Labels, q.Label)) ||
+			!q.inRange(v.LastSeen) {
+			continue
+		}
+		items = append(items, v)
+	}
+	return paginate(items, q, map[string]sorter[Agent]{
+		"id":        func(a, b Agent) bool { return a.ID < b.ID },
+		"state":     func(a, b Agent) bool { return a.State < b.State },
+		"runners":   func(a, b Agent) bool { return a.Runners < b.Runners },
+		"last_seen": func(a, b Agent) bool { return a.LastSeen.Before(b.LastSeen) },
+	}, "id")
+}
+
+func listPools(r *http.Request) (interface{}, error) {
+	q, err := parseQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]*Pool{}
+	for _, a := range controller.ListAgents() {
+		labels := a.Labels
+		if len(labels) == 0 {
+			labels = []string{defaultPool}
+		}
+		for _, l := range labels {
+			p, ok := byName[l]
+			if !ok {
+				p = &Pool{Name: l, AgentIDs: []string{}}
+				byName[l] = p
+			}
+			p.Agents++
+			p.AgentIDs = append(p.AgentIDs, a.ID)
+			if a.IsActive {
+				p.ActiveAgents++
+				p.Runners += a.Runners
+			}
+		}
+	}
+
+	var items []Pool
+	for _, p := range byName {
+		if q.Label != "" && !strings.EqualFold(p.Name, q.Label) {
+			continue
+		}
+		sort.Strings(p.AgentIDs)
+		items = append(items, *p)
+	}
+	return paginate(items, q, map[string]sorter[Pool]{
+		"name":    func(a, b Pool) bool { return a.Name < b.Name },
+		"runners": func(a, b Pool) bool { return a.Runners < b.Runners },
+	}, "name")
+}
+
+func listEvents(r *http.Request) (interface{}, error) {
+	q, err := parseQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	filter := events.ParseFilter(q.Type, r.URL.Query().Get("subject"))
+
+	var items []events.Event
+	for _, ev := range events.Recent() {
+		if !filter.Match(ev) || !q.inRange(ev.Time) {
+			continue
+		}
+		items = append(items, ev)
+	}
+	return paginate(items, q, map[string]sorter[events.Event]{
+		"time": func(a, b events.Event) bool { return a.ID < b.ID },
+		"type": func(a, b events.Event) bool { return a.Type < b.Type },
+	}, "time")
+}
@@ -0,0 +1,328 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/ridwandwisiswanto/tcr/internal/audit"
+	"github.com/ridwandwisiswanto/tcr/internal/controller"
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+	"github.com/ridwandwisiswanto/tcr/internal/events"
+	"github.com/ridwandwisiswanto/tcr/internal/github"
+)
+
+func get(t *testing.T, h http.Handler, method, url string, out interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: invalid JSON %q: %v", method, url, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestV1_JobsFilterSortPaginate(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, j := range []core.Job{
+		{ID: "a", RepoOwner: "acme", RepoName: "web", Status: "queued", Labels: []string{"gpu"}},
+		{ID: "b", RepoOwner: "acme", RepoName: "web", Status: "done"},
+		{ID: "c", RepoOwner: "acme", RepoName: "api", Status: "queued"},
+		{ID: "d", RepoOwner: "acme", RepoName: "web", Status: "queued"},
+	} {
+		j.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		controller.AddJob(j)
+	}
+	h := NewV1()
+
+	var page Page[Job]
+	if code := get(t, h, "GET", "/api/v1/jobs?repo=acme/web&status=queued&sort=-created_at&limit=1", &page); code != 200 {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if page.Total != 2 || len(page.Items) != 1 || page.Items[0].ID != "d" {
+		t.Fatalf("unexpected page %+v", page)
+	}
+
+	get(t, h, "GET", "/api/v1/jobs?label=gpu", &page)
+	if page.Total != 1 || page.Items[0].ID != "a" {
+		t.Fatalf("label filter failed: %+v", page)
+	}
+
+	get(t, h, "GET", "/api/v1/jobs?since=2025-01-01T01:30:00Z&until=2025-01-01T02:30:00Z", &page)
+	if page.Total != 1 || page.Items[0].ID != "c" {
+		t.Fatalf("time range filter failed: %+v", page)
+	}
+
+	var job Job
+	if code := get(t, h, "GET", "/api/v1/jobs/b", &job); code != 200 || job.Repo != "acme/web" {
+		t.Fatalf("get job failed: %d %+v", code, job)
+	}
+}
+
+func TestV1_QueueStats(t *testing.T) {
+	now := time.Now()
+	for i, j := range []core.Job{
+		{ID: "w1", Status: "done", QueuedAt: now.Add(-10 * time.Minute), DispatchedAt: now.Add(-9 * time.Minute), FinishedAt: now.Add(-8 * time.Minute)},
+		{ID: "w2", Status: "running", QueuedAt: now.Add(-5 * time.Minute), DispatchedAt: now.Add(-2 * time.Minute)},
+		{ID: "w3", Status: "queued", QueuedAt: now.Add(-90 * time.Second)},
+	} {
+		j.CreatedAt = j.QueuedAt
+		j.RepoOwner, j.RepoName = "stats", "repo"+string(rune('a'+i))
+		controller.AddJob(j)
+	}
+	h := NewV1()
+
+	var stats QueueStats
+	if code := get(t, h, "GET", "/api/v1/queue?window=1800", &stats); code != 200 {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if stats.Queued < 1 || stats.OldestQueuedSeconds < 89 {
+		t.Fatalf("queued stats wrong: %+v", stats)
+	}
+	if stats.WaitSamples < 2 || stats.WaitMaxSeconds < 179 || stats.WaitP50Seconds < 59 {
+		t.Fatalf("wait stats wrong: %+v", stats)
+	}
+
+	var job Job
+	get(t, h, "GET", "/api/v1/jobs/w2", &job)
+	if job.DispatchedAt == nil || job.FinishedAt != nil || job.WaitSeconds < 179 || job.WaitSeconds > 181 {
+		t.Fatalf("job timing wrong: %+v", job)
+	}
+}
+
+func TestV1_JobCancelRequeue(t *testing.T) {
+	controller.AddJob(core.Job{ID: "cx", RepoOwner: "acme", RepoName: "web", Status: "queued", CreatedAt: time.Now()})
+	h := NewV1()
+
+	var job Job
+	if code := get(t, h, "POST", "/api/v1/jobs/cx/cancel", &job); code != 200 || job.Status != "cancelled" {
+		t.Fatalf("cancel failed: %d %+v", code, job)
+	}
+	if len(job.History) != 1 || job.History[0].Action != "cancel" || job.History[0].By == "" {
+		t.Fatalf("cancel not recorded: %+v", job.History)
+	}
+
+	// laporan runner yang datang terlambat tidak menimpa status cancelled
+	controller.UpdateJobStatus("cx", "success")
+	var body struct {
+		Error Error `json:"error"`
+	}
+	if code := get(t, h, "POST", "/api/v1/jobs/cx/cancel", &body); code != 409 || body.Error.Code != "conflict" {
+		t.Fatalf("expected 409 conflict on second cancel, got %d %+v", code, body)
+	}
+
+	if code := get(t, h, "POST", "/api/v1/jobs/cx/requeue", &job); code != 200 || job.Status != "queued" || len(job.History) != 2 {
+		t.Fatalf("requeue failed: %d %+v", code, job)
+	}
+	if code := get(t, h, "POST", "/api/v1/jobs/cx/requeue", &body); code != 409 {
+		t.Fatalf("expected 409 when requeueing a queued job, got %d", code)
+	}
+	if code := get(t, h, "POST", "/api/v1/jobs/nope/cancel", &body); code != 404 {
+		t.Fatalf("expected 404 for unknown job, got %d", code)
+	}
+}
+
+func TestV1_JobTrace(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	// job dari poller belum membawa trace context; AddJob memulai trace-nya
+	controller.AddJob(core.Job{ID: "tr", RepoOwner: "acme", RepoName: "api", Status: "queued", CreatedAt: time.Now()})
+	h := NewV1()
+
+	var job Job
+	if code := get(t, h, "GET", "/api/v1/jobs/tr", &job); code != 200 || len(job.TraceID) != 32 {
+		t.Fatalf("job has no trace ID: %d %+v", code, job)
+	}
+	var page Page[Job]
+	get(t, h, "GET", "/api/v1/jobs?trace_id="+job.TraceID, &page)
+	if page.Total != 1 || page.Items[0].ID != "tr" {
+		t.Fatalf("trace_id filter returned %+v", page)
+	}
+
+	get(t, h, "POST", "/api/v1/jobs/tr/cancel", nil)
+	stages := map[string]bool{}
+	for _, s := range rec.Ended() {
+		if s.SpanContext().TraceID().String() == job.TraceID {
+			stages[s.Name()] = true
+		}
+	}
+	if !stages["job.enqueue"] || !stages["job.cancel"] {
+		t.Fatalf("expected enqueue and cancel spans in trace %s, got %v", job.TraceID, stages)
+	}
+}
+
+func TestV1_Audit(t *testing.T) {
+	log, err := audit.Open(t.TempDir() + "/audit.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit.SetDefault(log)
+	defer audit.SetDefault(&audit.Log{})
+	defer log.Close()
+
+	controller.AddJob(core.Job{ID: "au", RepoOwner: "acme", RepoName: "web", Status: "queued", CreatedAt: time.Now()})
+	h := NewV1()
+	get(t, h, "POST", "/api/v1/jobs/au/cancel", nil)
+	get(t, h, "DELETE", "/api/v1/runners/ghost", nil)
+
+	var page Page[audit.Entry]
+	if code := get(t, h, "GET", "/api/v1/audit?action=job.*", &page); code != 200 || page.Total != 1 {
+		t.Fatalf("audit query: %d %+v", code, page)
+	}
+	if e := page.Items[0]; e.Action != audit.JobCancel || e.Target != "au" || e.Result != audit.ResultOK || e.Params["previous"] != "queued" {
+		t.Fatalf("unexpected cancel entry: %+v", e)
+	}
+	get(t, h, "GET", "/api/v1/audit?result=error", &page)
+	if page.Total != 1 || page.Items[0].Action != audit.RunnerRemove || !strings.Contains(page.Items[0].Error, "not found") {
+		t.Fatalf("failed removal not audited: %+v", page)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/audit/export", nil))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if rec.Header().Get("Content-Type") != "application/x-ndjson" || len(lines) != 2 {
+		t.Fatalf("export: %s %q", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	var first audit.Entry
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.ID != 1 {
+		t.Fatalf("export line is not an entry: %q %v", lines[0], err)
+	}
+}
+
+func TestV1_WebhookDeliveries(t *testing.T) {
+	prev := github.Deliveries
+	github.Deliveries = &github.DeliveryStore{Retention: time.Hour}
+	prevQueue := core.JobQueue
+	core.JobQueue = core.NewIntake(10, 0.8)
+	defer func() { github.Deliveries, core.JobQueue = prev, prevQueue }()
+
+	body := `{"action":"queued","workflow_job":{"id":1,"name":"build"},"repository":{"name":"web","owner":{"login":"acme"}}}`
+	github.Deliveries.Save(github.Delivery{ID: "dd-1", Event: "workflow_job", Source: github.SourceWebhook,
+		Status: github.DeliveryDropped, ReceivedAt: time.Now(), Body: json.RawMessage(body)})
+	github.Deliveries.Save(github.Delivery{ID: "dd-2", Event: "workflow_job", Source: github.SourceWebhook,
+		Status: github.DeliveryProcessed, ReceivedAt: time.Now()})
+	h := NewV1()
+
+	var page Page[github.Delivery]
+	if code := get(t, h, "GET", "/api/v1/webhooks/deliveries?status=dropped", &page); code != 200 || page.Total != 1 || page.Items[0].Body != nil {
+		t.Fatalf("list deliveries: %d %+v", code, page)
+	}
+	var d github.Delivery
+	if code := get(t, h, "GET", "/api/v1/webhooks/deliveries/dd-1", &d); code != 200 || string(d.Body) != body {
+		t.Fatalf("get delivery: %d %+v", code, d)
+	}
+	if code := get(t, h, "GET", "/api/v1/webhooks/deliveries/nope", nil); code != 404 {
+		t.Fatalf("expected 404 for unknown delivery, got %d", code)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/webhooks/replay", strings.NewReader(`{"status":"dropped"}`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var results []ReplayResult
+	json.Unmarshal(rec.Body.Bytes(), &results)
+	if rec.Code != 200 || len(results) != 1 || results[0].ID != "dd-1" || results[0].Status != github.DeliveryProcessed {
+		t.Fatalf("bulk replay: %d %s", rec.Code, rec.Body.String())
+	}
+	if j := core.JobQueue.Take(); j.ID != results[0].JobID || j.RepoName != "web" {
+		t.Fatalf("unexpected replayed job %+v", j)
+	}
+	if code := get(t, h, "POST", "/api/v1/webhooks/deliveries/nope/replay", nil); code != 404 {
+		t.Fatalf("expected 404 replaying unknown delivery, got %d", code)
+	}
+}
+
+func TestV1_EventStream(t *testing.T) {
+	first := events.Publish(events.JobQueued, "s1", nil)
+	events.Publish(events.RunnerBusy, "r1", nil)
+
+	srv := httptest.NewServer(NewV1())
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL+"/api/v1/events/stream?type=job.*", nil)
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(first.ID-1, 10))
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	// event live setelah replay
+	go events.Publish(events.JobStatus, "s1", map[string]string{"status": "dispatched"})
+
+	var got []string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() && len(got) < 2 {
+		if typ, ok := strings.CutPrefix(sc.Text(), "event: "); ok {
+			got = append(got, typ)
+		}
+	}
+	if len(got) != 2 || got[0] != events.JobQueued || got[1] != events.JobStatus {
+		t.Fatalf("unexpected stream %v", got)
+	}
+}
+
+func TestV1_ErrorBodies(t *testing.T) {
+	h := NewV1()
+	var body struct {
+		Error Error `json:"error"`
+	}
+
+	cases := []struct {
+		method, url string
+		status      int
+		code        string
+	}{
+		{"GET", "/api/v1/jobs/missing", 404, "not_found"},
+		{"GET", "/api/v1/jobs?limit=0", 400, "invalid_parameter"},
+		{"GET", "/api/v1/jobs?sort=bogus", 400, "invalid_parameter"},
+		{"POST", "/api/v1/jobs", 405, "method_not_allowed"},
+		{"DELETE", "/api/v1/runners/ghost", 404, "not_found"},
+		{"GET", "/api/v1/nope", 404, "not_found"},
+	}
+	for _, c := range cases {
+		body.Error = Error{}
+		if code := get(t, h, c.method, c.url, &body); code != c.status || body.Error.Code != c.code || body.Error.Status != c.status {
+			t.Errorf("%s %s: got %d %+v, want %d %s", c.method, c.url, code, body.Error, c.status, c.code)
+		}
+	}
+}
+
+func TestV1_OpenAPI(t *testing.T) {
+	h := NewV1()
+	var doc struct {
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if code := get(t, h, "GET", "/api/v1/openapi.json", &doc); code != 200 {
+		t.Fatalf("unexpected status %d", code)
+	}
+	for _, r := range h.Routes() {
+		if _, ok := doc.Paths["/api/v1"+r.Path][map[string]string{"GET": "get", "POST": "post", "DELETE": "delete"}[r.Method]]; !ok {
+			t.Errorf("route %s %s missing from OpenAPI document", r.Method, r.Path)
+		}
+	}
+	for _, name := range []string{"Job", "JobPage", "Runner", "Agent", "Pool", "Event", "Error"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s missing", name)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/controller"
+	"github.com/ridwandwisiswanto/tcr/internal/github"
+)
+
+// ReplayRequest adalah body POST /webhooks/replay: delivery dengan ID yang
+// disebut, atau semua delivery dengan Status tertentu (misal "dropped")
+type ReplayRequest struct {
+	IDs    []string `json:"ids,omitempty"`
+	Status string   `json:"status,omitempty"`
+}
+
+// ReplayResult adalah hasil replay satu delivery
+type ReplayResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	JobID  string `json:"job_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func listDeliveries(r *http.Request) (interface{}, error) {
+	q, err := parseQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	v := r.URL.Query()
+	event, source := v.Get("event"), v.Get("source")
+	var items []github.Delivery
+	for _, d := range github.Deliveries.List() {
+		if (q.Status != "" && d.Status != q.Status) ||
+			(q.Repo != "" && !strings.EqualFold(d.Repo, q.Repo)) ||
+			(event != "" && d.Event != event) ||
+			(source != "" && d.Source != source) ||
+			!q.inRange(d.ReceivedAt) {
+			continue
+		}
+		items = append(items, d)
+	}
+	return paginate(items, q, map[string]sorter[github.Delivery]{
+		"received_at": func(a, b github.Delivery) bool { return a.ReceivedAt.Before(b.ReceivedAt) },
+		"status":      func(a, b github.Delivery) bool { return a.Status < b.Status },
+		"repo":        func(a, b github.Delivery) bool { return a.Repo < b.Repo },
+	}, "received_at")
+}
+
+func getDelivery(r *http.Request) (interface{}, error) {
+	id := r.PathValue("id")
+	d, ok, err := github.Deliveries.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, NotFound("delivery %s not found", id)
+	}
+	return d, nil
+}
+
+func replayDelivery(r *http.Request) (interface{}, error) {
+	return github.ReplayDelivery(r.PathValue("id"), controller.Actor(r))
+}
+
+// replayDeliveries me-replay beberapa delivery sekaligus; kegagalan satu
+// delivery tidak menghentikan yang lain dan dilaporkan per item
+func replayDeliveries(r *http.Request) (interface{}, error) {
+	var req ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return nil, BadRequest("invalid JSON body: %v", err)
+	}
+	if len(req.IDs) == 0 && req.Status == "" {
+		return nil, BadRequest("ids or status is required")
+	}
+	ids := req.IDs
+	if len(ids) == 0 {
+		for _, d := range github.Deliveries.List() {
+			if d.Status == req.Status {
+				ids = append(ids, d.ID)
+			}
+		}
+	}
+
+	by := controller.Actor(r)
+	results := []ReplayResult{}
+	for _, id := range ids {
+		d, err := github.ReplayDelivery(id, by)
+		res := ReplayResult{ID: id, Status: d.Status, JobID: d.JobID}
+		if err != nil {
+			res.Error = err.Error()
+			if errors.Is(err, github.ErrDeliveryNotFound) {
+				res.Status = "not_found"
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func reconcileDeliveries(r *http.Request) (interface{}, error) {
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, BadRequest("since must be an RFC3339 timestamp")
+		}
+		since = t
+	}
+	return github.Reconcile.Run(since, controller.Actor(r))
+}
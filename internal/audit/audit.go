@@ -0,0 +1,286 @@
+// Package audit adalah audit log append-only towerd. Setiap aksi admin,
+// token yang dibagikan, resize provider dan penghapusan runner dicatat
+// sebagai satu baris JSON (JSON lines) berisi actor, action, target,
+// parameter dan hasilnya. File tidak pernah ditulis ulang, hanya ditambah.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/logging"
+)
+
+// Action yang dicatat towerd
+const (
+	JobCancel          = "job.cancel"
+	JobRequeue         = "job.requeue"
+	RunnerDrain        = "runner.drain"
+	RunnerRemove       = "runner.remove"
+	GitHubRunnerDelete = "github.runner.delete"
+	AgentDrain         = "agent.drain"
+	AgentRemove        = "agent.remove"
+	PoolScale          = "pool.scale"
+	ProviderResize     = "provider.resize"
+	RolloutVersion     = "rollout.version"
+	TokenIssue         = "token.issue"
+	CredentialIssue    = "credential.issue"
+	WebhookReplay      = "webhook.replay"
+	WebhookReconcile   = "webhook.reconcile"
+)
+
+// Hasil aksi
+const (
+	ResultOK    = "ok"
+	ResultError = "error"
+)
+
+type Entry struct {
+	ID     int64                  `json:"id"`
+	Time   time.Time              `json:"time"`
+	Actor  string                 `json:"actor"`
+	Action string                 `json:"action"`
+	Target string                 `json:"target,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+	Result string                 `json:"result"` // ok | error
+	Error  string                 `json:"error,omitempty"`
+}
+
+// memCapacity adalah jumlah entry yang disimpan kalau audit log tanpa file
+const memCapacity = 1000
+
+// Log menulis entry ke file JSON lines; tanpa path entry hanya disimpan di
+// memori (dipakai test dan towerd yang belum memanggil SetDefault)
+type Log struct {
+	mu     sync.Mutex
+	path   string
+	f      *os.File
+	nextID int64
+	mem    []Entry
+}
+
+// Open membuka (atau membuat) file audit di path dan melanjutkan penomoran
+// ID dari entry terakhir. Path kosong berarti audit log di memori.
+func Open(path string) (*Log, error) {
+	l := &Log{path: path}
+	if path == "" {
+		return l, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	err := l.scan(func(e Entry) error {
+		if e.ID > l.nextID {
+			l.nextID = e.ID
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	if l.f, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600); err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	// baris terakhir terpotong (crash saat menulis): tutup dulu supaya entry
+	// berikutnya mulai di baris baru
+	if last, err := lastByte(path); err == nil && last != '\n' {
+		if _, err := l.f.Write([]byte{'\n'}); err != nil {
+			l.f.Close()
+			return nil, fmt.Errorf("audit: %w", err)
+		}
+	}
+	return l, nil
+}
+
+func lastByte(path string) (byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return '\n', err
+	}
+	b := make([]byte, 1)
+	_, err = f.ReadAt(b, info.Size()-1)
+	return b[0], err
+}
+
+// Path adalah lokasi file audit ("" kalau di memori)
+func (l *Log) Path() string { return l.path }
+
+// Close menutup file audit
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// Append memberi ID dan waktu lalu menulis e sebagai satu baris. Gagal
+// menulis tidak menggagalkan aksi yang diaudit, hanya dilaporkan di log.
+func (l *Log) Append(e Entry) Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	e.ID = l.nextID
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	e.Error = logging.Redact(e.Error)
+	// redaksi di salinan: map Params milik pemanggil tidak ikut diubah
+	if e.Params != nil {
+		params := make(map[string]interface{}, len(e.Params))
+		for k, v := range e.Params {
+			if s, ok := v.(string); ok {
+				v = logging.Redact(s)
+			}
+			params[k] = v
+		}
+		e.Params = params
+	}
+
+	if l.path == "" {
+		if len(l.mem) == memCapacity {
+			copy(l.mem, l.mem[1:])
+			l.mem = l.mem[:memCapacity-1]
+		}
+		l.mem = append(l.mem, e)
+		return e
+	}
+
+	line, err := json.Marshal(e)
+	if err == nil {
+		if l.f == nil {
+			err = os.ErrClosed
+		} else if _, err = l.f.Write(append(line, '\n')); err == nil {
+			err = l.f.Sync()
+		}
+	}
+	if err != nil {
+		logger.Error("cannot write audit entry", "action", e.Action, "actor", e.Actor, "target", e.Target, "err", err)
+	}
+	return e
+}
+
+// Each memanggil fn untuk setiap entry yang cocok dengan f, terlama dulu.
+// File dibaca langsung, jadi seluruh riwayat bisa di-query tanpa disimpan
+// di memori; baris yang rusak (misal terpotong saat crash) dilewati.
+func (l *Log) Each(f Filter, fn func(Entry) error) error {
+	if l.path == "" {
+		l.mu.Lock()
+		mem := append([]Entry(nil), l.mem...)
+		l.mu.Unlock()
+		for _, e := range mem {
+			if !f.Match(e) {
+				continue
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return l.scan(func(e Entry) error {
+		if !f.Match(e) {
+			return nil
+		}
+		return fn(e)
+	})
+}
+
+func (l *Log) scan(fn func(Entry) error) error {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	sc := bufio.NewScanner(file)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) != nil || e.ID == 0 {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// Filter memilih entry untuk query dan export. Action berisi nama persis
+// ("runner.remove") atau prefix ("runner", "runner.*"); field kosong berarti semua.
+type Filter struct {
+	Actor  string
+	Action string
+	Target string
+	Result string
+	Since  time.Time
+	Until  time.Time
+}
+
+func (f Filter) Match(e Entry) bool {
+	if (f.Actor != "" && e.Actor != f.Actor) ||
+		(f.Target != "" && e.Target != f.Target) ||
+		(f.Result != "" && e.Result != f.Result) ||
+		(!f.Since.IsZero() && e.Time.Before(f.Since)) ||
+		(!f.Until.IsZero() && e.Time.After(f.Until)) {
+		return false
+	}
+	if f.Action == "" {
+		return true
+	}
+	prefix := strings.TrimSuffix(strings.TrimSuffix(f.Action, "*"), ".")
+	return e.Action == prefix || strings.HasPrefix(e.Action, prefix+".")
+}
+
+var (
+	stdMu sync.RWMutex
+	std   = &Log{}
+)
+
+// SetDefault memasang audit log yang dipakai Record dan Each
+func SetDefault(l *Log) {
+	stdMu.Lock()
+	std = l
+	stdMu.Unlock()
+}
+
+// Default mengembalikan audit log yang aktif
+func Default() *Log {
+	stdMu.RLock()
+	defer stdMu.RUnlock()
+	return std
+}
+
+// Record mencatat satu aksi di audit log default. err nil berarti berhasil;
+// params tidak boleh berisi token atau secret (string tetap diredaksi).
+func Record(actor, action, target string, params map[string]interface{}, err error) Entry {
+	e := Entry{Actor: actor, Action: action, Target: target, Params: params, Result: ResultOK}
+	if err != nil {
+		e.Result, e.Error = ResultError, err.Error()
+	}
+	if actor == "" {
+		e.Actor = "unknown"
+	}
+	return Default().Append(e)
+}
+
+// Each membaca audit log default, lihat (*Log).Each
+func Each(f Filter, fn func(Entry) error) error {
+	return Default().Each(f, fn)
+}
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func collect(t *testing.T, l *Log, f Filter) []Entry {
+	t.Helper()
+	var out []Entry
+	if err := l.Each(f, func(e Entry) error { out = append(out, e); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestAppendReopenAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetDefault(l)
+	defer SetDefault(&Log{})
+
+	Record("admin", RunnerRemove, "runner-1", nil, nil)
+	Record("admin", PoolScale, "manual", map[string]interface{}{"direction": "up", "count": 2}, errors.New("would exceed max_runners_total"))
+	Record("agent-7", TokenIssue, "registration", map[string]interface{}{"reason": "token=ghs_secret"}, nil)
+	l.Close()
+
+	// baris terpotong (crash saat menulis) dilewati, ID tetap berlanjut
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"id":4,"actor":"adm`)
+	f.Close()
+
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if e := l.Append(Entry{Actor: "admin", Action: RunnerDrain, Target: "runner-2", Result: ResultOK}); e.ID != 4 {
+		t.Fatalf("id after reopen = %d, want 4", e.ID)
+	}
+
+	if all := collect(t, l, Filter{}); len(all) != 4 || all[3].Target != "runner-2" {
+		t.Fatalf("entries after reopen: %+v", all)
+	}
+	runner := collect(t, l, Filter{Action: "runner.*"})
+	if len(runner) != 2 || runner[0].Target != "runner-1" || runner[1].Action != RunnerDrain {
+		t.Fatalf("runner.* = %+v", runner)
+	}
+	failed := collect(t, l, Filter{Result: ResultError})
+	if len(failed) != 1 || failed[0].Action != PoolScale || !strings.Contains(failed[0].Error, "max_runners_total") {
+		t.Fatalf("failed = %+v", failed)
+	}
+	tokens := collect(t, l, Filter{Actor: "agent-7"})
+	if len(tokens) != 1 || strings.Contains(tokens[0].Params["reason"].(string), "ghs_secret") {
+		t.Fatalf("token entry not redacted: %+v", tokens)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("audit file mode = %v, %v", info.Mode(), err)
+	}
+}
+
+func TestMemoryLog(t *testing.T) {
+	l, _ := Open("")
+	for i := 0; i < memCapacity+5; i++ {
+		l.Append(Entry{Actor: "admin", Action: AgentDrain, Result: ResultOK})
+	}
+	all := collect(t, l, Filter{})
+	if len(all) != memCapacity || all[0].ID != 6 {
+		t.Fatalf("kept %d entries starting at %d", len(all), all[0].ID)
+	}
+}
+
+func TestAppendRedactsWithoutTouchingCallerParams(t *testing.T) {
+	l, _ := Open("")
+	params := map[string]interface{}{"reason": "token=ghp_abcdefghijklmnopqrstuvwx", "count": 2}
+	e := l.Append(Entry{Actor: "admin", Action: AgentDrain, Params: params})
+
+	if strings.Contains(e.Params["reason"].(string), "ghp_") || e.Params["count"] != 2 {
+		t.Fatalf("unexpected stored params %v", e.Params)
+	}
+	if params["reason"] != "token=ghp_abcdefghijklmnopqrstuvwx" {
+		t.Fatalf("caller params modified: %v", params)
+	}
+}
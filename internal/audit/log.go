@@ -0,0 +1,5 @@
+package audit
+
+import "github.com/ridwandwisiswanto/tcr/internal/logging"
+
+var logger = logging.For("audit")
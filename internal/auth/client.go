@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/pki"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
+)
+
+// Client dipakai agentd/runnerd untuk memanggil towerd: enroll dengan
+// bootstrap secret, simpan credential, dan perbarui sebelum expired atau
+// saat towerd membalas 401.
+type Client struct {
+	TowerURL  string
+	ID        string
+	Role      string
+	Bootstrap string
+	HTTP      *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewClient(towerURL, id, role, bootstrap string) *Client {
+	return &Client{
+		TowerURL:  towerURL,
+		ID:        id,
+		Role:      role,
+		Bootstrap: bootstrap,
+		HTTP:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Do mengirim request ke towerd dengan credential; body di-buffer supaya
+// bisa dikirim ulang setelah re-enroll.
+func (c *Client) Do(method, path string, body []byte) (*http.Response, error) {
+	return c.DoContext(context.Background(), method, path, body)
+}
+
+// DoContext sama dengan Do, dengan trace context dari ctx dikirim sebagai
+// header traceparent
+func (c *Client) DoContext(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	resp, err := c.send(ctx, method, path, body, false)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		return c.send(ctx, method, path, body, true)
+	}
+	return resp, err
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte, force bool) (*http.Response, error) {
+	token, err := c.Token(force)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.TowerURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	tracing.Inject(ctx, req.Header)
+	return c.HTTP.Do(req)
+}
+
+// Token mengembalikan credential aktif, enroll ulang kalau perlu
+func (c *Client) Token(force bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !force && c.token != "" && time.Until(c.expiresAt) > time.Minute {
+		return c.token, nil
+	}
+
+	data, err := c.enroll("")
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expiresAt = data.Token, data.ExpiresAt
+	return c.token, nil
+}
+
+type enrollResponse struct {
+	Token       string    `json:"token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Certificate string    `json:"certificate"`
+	CA          string    `json:"ca"`
+}
+
+func (c *Client) enroll(csr string) (*enrollResponse, error) {
+	b, _ := json.Marshal(map[string]string{"id": c.ID, "role": c.Role, "csr": csr})
+	req, _ := http.NewRequest("POST", c.TowerURL+"/enroll", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Bootstrap)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("enroll: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("enroll: tower responded %d: %s", resp.StatusCode, msg)
+	}
+
+	var data enrollResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("enroll: decode: %w", err)
+	}
+	return &data, nil
+}
+
+// EnableMTLS mengaktifkan TLS ke towerd (server diverifikasi terhadap pool)
+// dan meminta sertifikat client lewat enrollment. Sertifikat diperbarui
+// otomatis sebelum expired. Rotator yang dikembalikan juga dipakai untuk
+// listener milik agent/runner sendiri; hosts masuk ke SAN sertifikat (towerd
+// menambahkan IP asal enrollment, alamat yang dipakainya untuk memanggil balik).
+func (c *Client) EnableMTLS(pool *x509.CertPool, hosts []string) (*pki.Rotator, error) {
+	transport := &http.Transport{TLSClientConfig: pki.ClientConfig(nil, pool, pki.RoleTower)}
+	c.HTTP.Transport = transport
+
+	rot, err := pki.NewRotator(c.ID, func() (*tls.Certificate, error) {
+		keyPEM, csrPEM, err := pki.NewKeyAndCSR(c.ID, hosts)
+		if err != nil {
+			return nil, err
+		}
+		data, err := c.enroll(string(csrPEM))
+		if err != nil {
+			return nil, err
+		}
+		pair, err := tls.X509KeyPair([]byte(data.Certificate), keyPEM)
+		if err != nil {
+			return nil, err
+		}
+		return &pair, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.HTTP.Transport = &http.Transport{TLSClientConfig: pki.ClientConfig(rot, pool, pki.RoleTower)}
+	return rot, nil
+}
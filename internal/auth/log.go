@@ -0,0 +1,5 @@
+package auth
+
+import "github.com/ridwandwisiswanto/tcr/internal/logging"
+
+var logger = logging.For("auth")
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/audit"
+	"github.com/ridwandwisiswanto/tcr/internal/pki"
+)
+
+type ctxKey struct{}
+
+// enrollActor adalah actor audit untuk enrollment: pemanggil hanya dikenal
+// lewat bootstrap secret, id yang diminta menjadi target
+const enrollActor = "bootstrap"
+
+// Policy menentukan path mana yang publik dan mana yang boleh dipanggil
+// agent/runner. Path lain hanya untuk admin.
+type Policy struct {
+	Public  []string
+	Clients []string
+}
+
+// Server adalah sisi towerd: enrollment dan verifikasi credential
+type Server struct {
+	Issuer     *Issuer
+	Bootstrap  string // secret untuk enroll agent/runner
+	AdminToken string // token statis untuk operator (opsional)
+	TTL        time.Duration
+
+	// mTLS (opsional): tanda tangani CSR yang dikirim saat enroll
+	CA      *pki.CA
+	CertTTL time.Duration
+}
+
+// EnrollHandler melayani POST /enroll {"id": "...", "role": "agent|runner"}
+// dengan header Authorization: Bearer <bootstrap secret>. Credential yang
+// diterbitkan (dan enrollment yang ditolak) dicatat di audit log.
+func (s *Server) EnrollHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.Bootstrap == "" || !equal(bearer(r), s.Bootstrap) {
+		logger.Warn("enrollment rejected", "remote_addr", r.RemoteAddr)
+		audit.Record(enrollActor, audit.CredentialIssue, "", map[string]interface{}{"remote_addr": r.RemoteAddr}, errors.New("unauthorized"))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ID   string `json:"id"`
+		Role string `json:"role"`
+		CSR  string `json:"csr"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+	if req.Role != RoleAgent && req.Role != RoleRunner {
+		http.Error(w, "invalid role", http.StatusBadRequest)
+		return
+	}
+
+	token, exp := s.Issuer.Issue(req.ID, req.Role, s.TTL)
+	resp := map[string]interface{}{
+		"token":      token,
+		"expires_at": exp,
+	}
+
+	if req.CSR != "" {
+		if s.CA == nil {
+			http.Error(w, "mTLS is not enabled on tower", http.StatusBadRequest)
+			return
+		}
+		// towerd memanggil balik agent/runner di IP asal heartbeat, jadi IP
+		// asal enrollment yang masuk ke sertifikat
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		cert, err := s.CA.SignCSR([]byte(req.CSR), req.ID, req.Role, net.ParseIP(host), s.CertTTL)
+		if err != nil {
+			http.Error(w, "invalid csr: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp["certificate"] = string(cert)
+		resp["ca"] = string(s.CA.CertPEM)
+	}
+
+	logger.Info("client enrolled", "role", req.Role, "id", req.ID, "expires_at", exp.Format(time.RFC3339), "certificate", req.CSR != "")
+	audit.Record(enrollActor, audit.CredentialIssue, req.ID, map[string]interface{}{
+		"role":        req.Role,
+		"expires_at":  exp,
+		"certificate": req.CSR != "",
+		"remote_addr": r.RemoteAddr,
+	}, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Protect membungkus handler: request tanpa credential valid ditolak 401,
+// agent/runner yang memanggil path di luar Policy.Clients ditolak 403.
+func (s *Server) Protect(next http.Handler, p Policy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if matchPath(r.URL.Path, p.Public) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, ok := s.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="towerd"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if claims.Role != RoleAdmin && !matchPath(r.URL.Path, p.Clients) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, claims)))
+	})
+}
+
+func (s *Server) authenticate(r *http.Request) (Claims, bool) {
+	// client cert yang sudah diverifikasi terhadap CA internal
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cn, role := pki.Identity(r.TLS.VerifiedChains[0][0])
+		if role == RoleAgent || role == RoleRunner {
+			return Claims{Subject: cn, Role: role}, true
+		}
+	}
+
+	token := bearer(r)
+	if token == "" {
+		return Claims{}, false
+	}
+	if s.AdminToken != "" && equal(token, s.AdminToken) {
+		return Claims{Subject: "admin", Role: RoleAdmin}, true
+	}
+	c, err := s.Issuer.Verify(token)
+	if err != nil {
+		return Claims{}, false
+	}
+	return c, true
+}
+
+// FromRequest mengambil claims yang dipasang oleh Protect
+func FromRequest(r *http.Request) (Claims, bool) {
+	c, ok := r.Context().Value(ctxKey{}).(Claims)
+	return c, ok
+}
+
+// matchPath: entry yang diakhiri "/" dianggap prefix
+func matchPath(path string, list []string) bool {
+	for _, p := range list {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+func bearer(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(h, "Bearer ")
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIssuer_VerifyRejectsTamperedAndExpired(t *testing.T) {
+	iss := NewIssuer("k")
+	token, _ := iss.Issue("vm-1", RoleAgent, time.Hour)
+
+	c, err := iss.Verify(token)
+	if err != nil || c.Subject != "vm-1" || c.Role != RoleAgent {
+		t.Fatalf("expected valid claims, got %+v (%v)", c, err)
+	}
+
+	if _, err := NewIssuer("other").Verify(token); err == nil {
+		t.Fatalf("expected error for token signed with another key")
+	}
+
+	body, sig, _ := strings.Cut(token, ".")
+	if _, err := iss.Verify(body + "x." + sig); err == nil {
+		t.Fatalf("expected error for tampered token")
+	}
+
+	expired, _ := iss.Issue("vm-1", RoleAgent, -time.Second)
+	if _, err := iss.Verify(expired); err == nil {
+		t.Fatalf("expected error for expired token")
+	}
+}
+
+func TestServer_ProtectAndEnroll(t *testing.T) {
+	s := &Server{Issuer: NewIssuer("k"), Bootstrap: "boot", AdminToken: "adm", TTL: time.Hour}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/enroll", s.EnrollHandler)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	mux.HandleFunc("/github/webhook", ok)
+	mux.HandleFunc("/vm/heartbeat", ok)
+	mux.HandleFunc("/jobs", ok)
+
+	srv := httptest.NewServer(s.Protect(mux, Policy{
+		Public:  []string{"/github/webhook", "/enroll"},
+		Clients: []string{"/vm/heartbeat"},
+	}))
+	defer srv.Close()
+
+	bad := NewClient(srv.URL, "vm-1", RoleAgent, "wrong")
+	if _, err := bad.Do("POST", "/vm/heartbeat", nil); err == nil {
+		t.Fatalf("expected enrollment with wrong bootstrap secret to fail")
+	}
+
+	c := NewClient(srv.URL, "vm-1", RoleAgent, "boot")
+	cases := []struct {
+		client *Client
+		path   string
+		want   int
+	}{
+		{c, "/vm/heartbeat", http.StatusOK},
+		{c, "/jobs", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		resp, err := tc.client.Do("GET", tc.path, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.want, resp.StatusCode)
+		}
+	}
+
+	for path, want := range map[string]int{"/github/webhook": http.StatusOK, "/jobs": http.StatusUnauthorized} {
+		resp, _ := http.Get(srv.URL + path)
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("anonymous %s: expected %d, got %d", path, want, resp.StatusCode)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", srv.URL+"/jobs", nil)
+	req.Header.Set("Authorization", "Bearer adm")
+	resp, _ := http.DefaultClient.Do(req)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("admin /jobs: expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestRequireTower(t *testing.T) {
+	h := RequireTower(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), "boot")
+	call := func(r *http.Request) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	signed := httptest.NewRequest("GET", "/logs?runner=r1", nil)
+	SignTower(signed, "boot")
+	if code := call(signed); code != http.StatusOK {
+		t.Fatalf("expected signed request to pass, got %d", code)
+	}
+
+	// tanda tangan terikat ke path+query dan secret
+	moved := httptest.NewRequest("GET", "/logs?runner=r2", nil)
+	moved.Header.Set(TowerHeader, signed.Header.Get(TowerHeader))
+	other := httptest.NewRequest("GET", "/logs", nil)
+	SignTower(other, "wrong")
+	stale := httptest.NewRequest("GET", "/logs", nil)
+	stale.Header.Set(TowerHeader, "1.x")
+	for _, r := range []*http.Request{moved, other, stale, httptest.NewRequest("GET", "/logs", nil)} {
+		if code := call(r); code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for %s %q, got %d", r.URL, r.Header.Get(TowerHeader), code)
+		}
+	}
+}
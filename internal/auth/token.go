@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	RoleAgent  = "agent"
+	RoleRunner = "runner"
+	RoleAdmin  = "admin"
+)
+
+// Claims adalah isi credential yang ditandatangani towerd
+type Claims struct {
+	Subject   string `json:"sub"`
+	Role      string `json:"role"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Issuer menandatangani dan memverifikasi token HMAC-SHA256 berbentuk
+// base64url(claims).base64url(signature)
+type Issuer struct {
+	key []byte
+}
+
+// NewIssuer membuat issuer dengan key yang diberikan; key kosong → random
+// (token lama jadi tidak valid setelah towerd restart, agent akan enroll ulang)
+func NewIssuer(key string) *Issuer {
+	if key == "" {
+		b := make([]byte, 32)
+		rand.Read(b)
+		return &Issuer{key: b}
+	}
+	return &Issuer{key: []byte(key)}
+}
+
+func (i *Issuer) Issue(subject, role string, ttl time.Duration) (string, time.Time) {
+	exp := time.Now().Add(ttl)
+	payload, _ := json.Marshal(Claims{Subject: subject, Role: role, ExpiresAt: exp.Unix()})
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + i.sign(body), exp
+}
+
+func (i *Issuer) Verify(token string) (Claims, error) {
+	var c Claims
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return c, errors.New("malformed token")
+	}
+	if !hmac.Equal([]byte(sig), []byte(i.sign(body))) {
+		return c, errors.New("invalid token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return c, errors.New("malformed token")
+	}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return c, errors.New("malformed token")
+	}
+	if time.Now().Unix() >= c.ExpiresAt {
+		return c, errors.New("token expired")
+	}
+	return c, nil
+}
+
+func (i *Issuer) sign(body string) string {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
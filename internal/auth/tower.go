@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TowerHeader membawa tanda tangan request towerd → agentd/runnerd kalau mTLS
+// tidak aktif: "<unix>.<hmac>" dengan bootstrap secret sebagai key. Secret
+// tidak pernah dikirim, jadi host yang menyamar sebagai agent tidak
+// mendapat apa pun yang bisa dipakai ulang di luar request itu.
+const TowerHeader = "X-Tcr-Tower"
+
+// towerSkew adalah selisih jam maksimal antara towerd dan agent/runner
+const towerSkew = 5 * time.Minute
+
+// SignTower menandatangani request keluar towerd
+func SignTower(r *http.Request, secret string) {
+	if secret == "" {
+		return
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set(TowerHeader, ts+"."+towerMAC(secret, ts, r))
+}
+
+// RequireTower menolak request yang tidak ditandatangani towerd dengan
+// secret. Dipakai di API agentd/runnerd saat mTLS tidak aktif.
+func RequireTower(next http.Handler, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !verifyTower(r, secret) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func verifyTower(r *http.Request, secret string) bool {
+	ts, sig, ok := strings.Cut(r.Header.Get(TowerHeader), ".")
+	if secret == "" || !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if d := time.Since(time.Unix(unix, 0)); d > towerSkew || d < -towerSkew {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(towerMAC(secret, ts, r)))
+}
+
+func towerMAC(secret, ts string, r *http.Request) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "\n" + r.Method + "\n" + r.URL.RequestURI()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
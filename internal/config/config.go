@@ -0,0 +1,167 @@
+// Package config memuat konfigurasi towerd dan agentd dari file YAML
+// (section "tower" dan "agent"), lalu env meng-override nilai dari file.
+//
+// Urutan prioritas: default di kode < file config < environment.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// DefaultPathEnv adalah env untuk lokasi file config kalau --config tidak diberikan
+const DefaultPathEnv = "TCR_CONFIG"
+
+// Load mengisi out (pointer ke struct yang sudah berisi default) dari
+// section file config lalu dari env berdasarkan tag `env:"NAME"`.
+// path kosong berarti hanya env yang dipakai.
+func Load(path, section string, out any) error {
+	if path != "" {
+		if err := loadFile(path, section, out); err != nil {
+			return err
+		}
+	}
+	return ApplyEnv(out)
+}
+
+func loadFile(path, section string, out any) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", "":
+	default:
+		return fmt.Errorf("config %s: unsupported format (use .yaml or .yml)", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config %s: %v", path, err)
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("config %s: %v", path, err)
+	}
+	part, ok := doc[section]
+	if !ok {
+		return nil
+	}
+
+	// round-trip section lewat yaml supaya UnmarshalStrict bisa menolak key yang salah ketik
+	raw, err := yaml.Marshal(part)
+	if err != nil {
+		return fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := yaml.UnmarshalStrict(raw, out); err != nil {
+		return fmt.Errorf("config %s [%s]: %v", path, section, err)
+	}
+	return nil
+}
+
+// ApplyEnv meng-override field yang punya tag `env` dengan nilai env yang
+// di-set. Nilai yang tidak bisa di-parse dilaporkan sebagai error, bukan 0.
+func ApplyEnv(out any) error {
+	var errs Errors
+	applyEnv(reflect.ValueOf(out).Elem(), &errs)
+	return errs.Err()
+}
+
+func applyEnv(v reflect.Value, errs *Errors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, f := t.Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			applyEnv(f, errs)
+			continue
+		}
+
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw, ok := os.LookupEnv(name)
+		if !ok || raw == "" {
+			continue
+		}
+
+		switch f.Kind() {
+		case reflect.String:
+			f.SetString(raw)
+		case reflect.Int:
+			n, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil {
+				errs.Addf("%s=%q is not an integer", name, raw)
+				continue
+			}
+			f.SetInt(int64(n))
+		case reflect.Float64:
+			n, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+			if err != nil {
+				errs.Addf("%s=%q is not a number", name, raw)
+				continue
+			}
+			f.SetFloat(n)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(strings.TrimSpace(raw))
+			if err != nil {
+				errs.Addf("%s=%q is not a boolean (true/false)", name, raw)
+				continue
+			}
+			f.SetBool(b)
+		case reflect.Slice:
+			if f.Type().Elem().Kind() != reflect.String {
+				errs.Addf("%s: unsupported list type %s", name, f.Type())
+				continue
+			}
+			f.Set(reflect.ValueOf(SplitList(raw)))
+		default:
+			errs.Addf("%s: unsupported type %s", name, f.Type())
+		}
+	}
+}
+
+// SplitList memecah "a, b,c" menjadi []string{"a","b","c"}
+func SplitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Print menulis config efektif sebagai YAML (untuk --print-config).
+// Field dengan tag yaml:"-" (secret) tidak ikut tercetak.
+func Print(section string, v any) (string, error) {
+	out, err := yaml.Marshal(map[string]any{section: v})
+	return string(out), err
+}
+
+// Errors mengumpulkan semua masalah validasi supaya dilaporkan sekaligus
+type Errors []string
+
+func (e *Errors) Addf(format string, args ...any) {
+	*e = append(*e, fmt.Sprintf(format, args...))
+}
+
+// Check menambahkan pesan kalau ok false
+func (e *Errors) Check(ok bool, format string, args ...any) {
+	if !ok {
+		e.Addf(format, args...)
+	}
+}
+
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(e, "\n  - "))
+}
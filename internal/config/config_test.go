@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tcr.yaml")
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadTower_FileThenEnv(t *testing.T) {
+	path := writeConfig(t, `
+tower:
+  mode: polling
+  metrics_listen: ":9100"
+  scaling:
+    scale_step_max: 5
+    max_runners_total: 40
+  tls:
+    hosts: [tower.internal]
+agent:
+  max_runners: 3
+`)
+	t.Setenv("MAX_RUNNERS_TOTAL", "60")
+
+	cfg, err := LoadTower(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Mode != "polling" || cfg.Scaling.ScaleStepMax != 5 || cfg.MetricsListen != ":9100" {
+		t.Fatalf("file values not applied: %+v", cfg)
+	}
+	if cfg.Scaling.MaxRunnersTotal != 60 {
+		t.Fatalf("env should override file, got %d", cfg.Scaling.MaxRunnersTotal)
+	}
+	if cfg.Scaling.PollIntervalSec != 30 || cfg.Listen != ":8080" {
+		t.Fatalf("defaults lost: %+v", cfg)
+	}
+	if len(cfg.TLS.Hosts) != 1 || cfg.TLS.Hosts[0] != "tower.internal" {
+		t.Fatalf("unexpected hosts %v", cfg.TLS.Hosts)
+	}
+}
+
+func TestLoadTower_Errors(t *testing.T) {
+	t.Setenv("MODE", "polling")
+	t.Setenv("SCALE_STEP_MAX", "three")
+	if _, err := LoadTower(""); err == nil || !strings.Contains(err.Error(), `SCALE_STEP_MAX="three" is not an integer`) {
+		t.Fatalf("expected integer parse error, got %v", err)
+	}
+
+	t.Setenv("SCALE_STEP_MAX", "")
+	path := writeConfig(t, "tower:\n  scalling:\n    scale_step_max: 2\n")
+	if _, err := LoadTower(path); err == nil || !strings.Contains(err.Error(), "scalling") {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+
+	path = writeConfig(t, "tower:\n  mode: polling\n  scaling:\n    poll_interval_sec: 0\n  spawn:\n    method: gcp_mig\n")
+	_, err := LoadTower(path)
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, want := range []string{"poll_interval_sec must be > 0", "gcp_project is required", "gcp_mig_name is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+}
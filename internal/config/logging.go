@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Logging mengatur logger terstruktur (internal/logging) semua binary.
+// Levels berisi override per komponen dalam bentuk "component=level",
+// misal "github=debug" atau "controller=warn"; level bisa di-hot-reload.
+type Logging struct {
+	Level  string   `yaml:"level" env:"LOG_LEVEL"`
+	Format string   `yaml:"format" env:"LOG_FORMAT"` // text | json
+	Levels []string `yaml:"levels" env:"LOG_LEVELS"`
+}
+
+func DefaultLogging() Logging {
+	return Logging{Level: "info", Format: "text"}
+}
+
+// ParseLogLevel menerima debug, info, warn (warning) dan error
+func ParseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (debug, info, warn, error)", s)
+}
+
+// ComponentLevels mem-parse Levels menjadi map komponen → level
+func (l Logging) ComponentLevels() (map[string]slog.Level, error) {
+	out := map[string]slog.Level{}
+	for _, entry := range l.Levels {
+		name, level, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("log level override %q must be component=level", entry)
+		}
+		lv, err := ParseLogLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("log level override %q: %w", entry, err)
+		}
+		out[name] = lv
+	}
+	return out, nil
+}
+
+// Check memvalidasi section logging; section adalah prefix nama field di pesan error
+func (l Logging) Check(errs *Errors, section string) {
+	if _, err := ParseLogLevel(l.Level); err != nil {
+		errs.Addf("%s.level (LOG_LEVEL): %v", section, err)
+	}
+	errs.Check(l.Format == "text" || l.Format == "json",
+		"%s.format (LOG_FORMAT) must be \"text\" or \"json\", got %q", section, l.Format)
+	if _, err := l.ComponentLevels(); err != nil {
+		errs.Addf("%s.levels (LOG_LEVELS): %v", section, err)
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+// Tower adalah schema config towerd (section "tower"). Secret seperti
+// GITHUB_TOKEN dan GITHUB_WEBHOOK_SECRET tidak ada di sini; lihat internal/secrets.
+type Tower struct {
+	Listen string `yaml:"listen" env:"TOWER_LISTEN_ADDR"`
+	Mode   string `yaml:"mode" env:"MODE"`
+	// MetricsListen membuka /metrics tanpa credential di listener terpisah
+	// untuk Prometheus; /metrics di Listen tetap hanya untuk admin
+	MetricsListen string  `yaml:"metrics_listen" env:"TOWER_METRICS_ADDR"`
+	Scaling       Scaling `yaml:"scaling"`
+	Spawn         Spawn   `yaml:"spawn"`
+	Rollout       Rollout `yaml:"rollout"`
+	TLS           TLS     `yaml:"tls"`
+	Tracing       Tracing `yaml:"tracing"`
+	Logging       Logging `yaml:"logging"`
+	Audit         Audit   `yaml:"audit"`
+	Webhook       Webhook `yaml:"webhook"`
+	Queue         Queue   `yaml:"queue"`
+}
+
+// Scaling bisa di-hot-reload tanpa restart towerd
+type Scaling struct {
+	PollIntervalSec int `yaml:"poll_interval_sec" env:"POLL_INTERVAL_SECONDS"`
+	ScaleStepMax    int `yaml:"scale_step_max" env:"SCALE_STEP_MAX"`
+	MaxRunnersTotal int `yaml:"max_runners_total" env:"MAX_RUNNERS_TOTAL"`
+}
+
+type Spawn struct {
+	Method                    string `yaml:"method" env:"SPAWN_METHOD"`
+	AgentRegistrationEndpoint string `yaml:"agent_registration_endpoint" env:"AGENT_REGISTRATION_ENDPOINT"`
+	GCPProject                string `yaml:"gcp_project" env:"GCP_PROJECT"`
+	GCPMigName                string `yaml:"gcp_mig_name" env:"GCP_MIG_NAME"`
+}
+
+// Rollout bisa di-hot-reload tanpa restart towerd
+type Rollout struct {
+	BatchSize int `yaml:"batch_size" env:"RUNNER_UPGRADE_BATCH"`
+	MaxAgents int `yaml:"max_agents" env:"RUNNER_UPGRADE_MAX_AGENTS"`
+}
+
+// Audit menentukan file JSON lines audit log; kosong berarti hanya di memori
+// (hilang saat restart). Tidak ikut hot-reload.
+type Audit struct {
+	Path string `yaml:"path" env:"TOWER_AUDIT_LOG"`
+}
+
+// Webhook mengatur penyimpanan delivery webhook mentah (untuk inspeksi dan
+// replay) dan rekonsiliasi dengan API webhook deliveries GitHub. DeliveriesDir
+// kosong berarti delivery hanya disimpan di memori; HookID 0 mematikan
+// rekonsiliasi. Tidak ikut hot-reload.
+type Webhook struct {
+	DeliveriesDir        string `yaml:"deliveries_dir" env:"TOWER_WEBHOOK_DELIVERIES_DIR"`
+	RetentionHours       int    `yaml:"retention_hours" env:"TOWER_WEBHOOK_RETENTION_HOURS"`
+	HookID               int    `yaml:"hook_id" env:"GITHUB_WEBHOOK_ID"`
+	ReconcileIntervalSec int    `yaml:"reconcile_interval_sec" env:"TOWER_WEBHOOK_RECONCILE_SEC"`
+}
+
+// Queue mengatur buffer intake job antara webhook dan scheduler. Dir kosong
+// berarti buffer hanya di memori. Kalau penuh, webhook dibalas 503 dengan
+// Retry-After. Capacity dan HighWatermark ikut hot-reload.
+type Queue struct {
+	Capacity      int     `yaml:"capacity" env:"TOWER_QUEUE_CAPACITY"`
+	Dir           string  `yaml:"dir" env:"TOWER_QUEUE_DIR"`
+	HighWatermark float64 `yaml:"high_watermark" env:"TOWER_QUEUE_HIGH_WATERMARK"`
+	RetryAfterSec int     `yaml:"retry_after_sec" env:"TOWER_QUEUE_RETRY_AFTER_SEC"`
+}
+
+type TLS struct {
+	Enabled      bool     `yaml:"enabled" env:"TOWER_TLS"`
+	PKIDir       string   `yaml:"pki_dir" env:"TOWER_PKI_DIR"`
+	CertTTLHours int      `yaml:"cert_ttl_hours" env:"TOWER_CERT_TTL_HOURS"`
+	Hosts        []string `yaml:"hosts" env:"TOWER_TLS_HOSTS"`
+}
+
+// DefaultTower mengembalikan nilai default yang dulu tersebar di getEnv/atoiEnv
+func DefaultTower() Tower {
+	return Tower{
+		Listen: ":8080",
+		Scaling: Scaling{
+			PollIntervalSec: 30,
+			ScaleStepMax:    3,
+			MaxRunnersTotal: 20,
+		},
+		Spawn: Spawn{
+			AgentRegistrationEndpoint: "http://localhost:8081/register-hybrid",
+		},
+		Rollout: Rollout{BatchSize: 1, MaxAgents: 1},
+		TLS: TLS{
+			PKIDir:       "./pki",
+			CertTTLHours: 168,
+			Hosts:        []string{"localhost", "127.0.0.1"},
+		},
+		Tracing: DefaultTracing(),
+		Logging: DefaultLogging(),
+		Audit:   Audit{Path: "./audit/audit.jsonl"},
+		Webhook: Webhook{
+			DeliveriesDir:        "./deliveries",
+			RetentionHours:       72,
+			ReconcileIntervalSec: 300,
+		},
+		Queue: Queue{
+			Capacity:      1000,
+			Dir:           "./intake",
+			HighWatermark: 0.8,
+			RetryAfterSec: 30,
+		},
+	}
+}
+
+// LoadTower memuat default → file → env lalu memvalidasi hasilnya
+func LoadTower(path string) (Tower, error) {
+	cfg := DefaultTower()
+	if err := Load(path, "tower", &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+func (c Tower) Validate() error {
+	var errs Errors
+	errs.Check(c.Listen != "", "tower.listen must not be empty")
+	errs.Check(c.Mode == "webhook" || c.Mode == "polling",
+		"tower.mode (MODE) must be \"webhook\" or \"polling\", got %q", c.Mode)
+	errs.Check(c.Scaling.PollIntervalSec > 0, "tower.scaling.poll_interval_sec must be > 0, got %d", c.Scaling.PollIntervalSec)
+	errs.Check(c.Scaling.ScaleStepMax > 0, "tower.scaling.scale_step_max must be > 0, got %d", c.Scaling.ScaleStepMax)
+	errs.Check(c.Scaling.MaxRunnersTotal >= 0, "tower.scaling.max_runners_total must be >= 0, got %d", c.Scaling.MaxRunnersTotal)
+	errs.Check(c.Rollout.BatchSize > 0, "tower.rollout.batch_size must be > 0, got %d", c.Rollout.BatchSize)
+	errs.Check(c.Rollout.MaxAgents > 0, "tower.rollout.max_agents must be > 0, got %d", c.Rollout.MaxAgents)
+
+	switch c.Spawn.Method {
+	case "", "local":
+		errs.Check(c.Spawn.AgentRegistrationEndpoint != "", "tower.spawn.agent_registration_endpoint must not be empty")
+	case "gcp_mig":
+		errs.Check(c.Spawn.GCPProject != "", "tower.spawn.gcp_project is required when spawn.method is gcp_mig")
+		errs.Check(c.Spawn.GCPMigName != "", "tower.spawn.gcp_mig_name is required when spawn.method is gcp_mig")
+	default:
+		errs.Addf("tower.spawn.method must be \"local\" or \"gcp_mig\", got %q", c.Spawn.Method)
+	}
+
+	if c.TLS.Enabled {
+		errs.Check(c.TLS.PKIDir != "", "tower.tls.pki_dir must not be empty when tls is enabled")
+		errs.Check(c.TLS.CertTTLHours > 0, "tower.tls.cert_ttl_hours must be > 0, got %d", c.TLS.CertTTLHours)
+		errs.Check(len(c.TLS.Hosts) > 0, "tower.tls.hosts must list at least one host")
+	}
+	errs.Check(c.Webhook.RetentionHours > 0, "tower.webhook.retention_hours must be > 0, got %d", c.Webhook.RetentionHours)
+	errs.Check(c.Webhook.HookID >= 0, "tower.webhook.hook_id (GITHUB_WEBHOOK_ID) must be >= 0, got %d", c.Webhook.HookID)
+	errs.Check(c.Webhook.ReconcileIntervalSec > 0, "tower.webhook.reconcile_interval_sec must be > 0, got %d", c.Webhook.ReconcileIntervalSec)
+	errs.Check(c.Queue.Capacity > 0, "tower.queue.capacity must be > 0, got %d", c.Queue.Capacity)
+	errs.Check(c.Queue.HighWatermark > 0 && c.Queue.HighWatermark <= 1, "tower.queue.high_watermark must be in (0, 1], got %g", c.Queue.HighWatermark)
+	errs.Check(c.Queue.RetryAfterSec > 0, "tower.queue.retry_after_sec must be > 0, got %d", c.Queue.RetryAfterSec)
+	c.Tracing.Check(&errs, "tower.tracing")
+	c.Logging.Check(&errs, "tower.logging")
+	return errs.Err()
+}
@@ -0,0 +1,26 @@
+package config
+
+import "net/url"
+
+// Tracing mengatur export trace OpenTelemetry (OTLP/HTTP) dan dipakai towerd
+// maupun agentd. Endpoint kosong berarti tracing mati.
+type Tracing struct {
+	// base URL collector, misal http://localhost:4318 (path /v1/traces ditambahkan otomatis)
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TCR_TRACE_SAMPLE_RATIO"`
+}
+
+func DefaultTracing() Tracing {
+	return Tracing{SampleRatio: 1}
+}
+
+// Check memvalidasi section tracing; section adalah prefix nama field di pesan error
+func (t Tracing) Check(errs *Errors, section string) {
+	if t.Endpoint != "" {
+		u, err := url.Parse(t.Endpoint)
+		errs.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"%s.endpoint (OTEL_EXPORTER_OTLP_ENDPOINT) must be an http(s) URL, got %q", section, t.Endpoint)
+	}
+	errs.Check(t.SampleRatio >= 0 && t.SampleRatio <= 1,
+		"%s.sample_ratio (TCR_TRACE_SAMPLE_RATIO) must be between 0 and 1, got %g", section, t.SampleRatio)
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Watch memanggil reload setiap kali file config berubah (cek mtime tiap
+// interval). reload bertanggung jawab memuat ulang, memvalidasi dan hanya
+// menerapkan setting yang aman diganti saat jalan.
+func Watch(path string, interval time.Duration, reload func()) {
+	if path == "" {
+		return
+	}
+	last := modTime(path)
+	go func() {
+		for {
+			time.Sleep(interval)
+			mt := modTime(path)
+			if mt.IsZero() || mt.Equal(last) {
+				continue
+			}
+			last = mt
+			slog.Default().With("component", "config").Info("config changed, reloading", "path", path)
+			reload()
+		}
+	}()
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -1,14 +1,16 @@
 package controller
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/ridwandwisiswanto/tcr/internal/core"
+	"github.com/ridwandwisiswanto/tcr/internal/events"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
 )
 
 var lastDispatchedID string
@@ -48,19 +50,32 @@ func StartDispatcher() {
 					continue
 				}
 				job.Status = "dispatched"
+				job.RunnerID = runner.ID
+				job.DispatchedAt = time.Now()
+				incJobStatus(*job)
+				dispatched := *job
 				jobQueueMu.Unlock()
 
-				payload, _ := json.Marshal(job)
-				url := fmt.Sprintf("http://%s:%s/job", runner.Address, runner.Port)
-				resp, err := http.Post(url, "application/json", bytes.NewBuffer(payload))
+				err := sendJob(dispatched, runner)
+				observeDispatch(dispatched, err)
 				if err != nil {
-					log.Printf("❌ Failed to dispatch job %s to runner %s: %v", job.JobName, runner.ID, err)
+					// job kembali menunggu dan tetap di intake (durable)
+					jobQueueMu.Lock()
+					if job.Status == "dispatched" && job.RunnerID == runner.ID {
+						job.Status = "queued"
+						job.RunnerID = ""
+						job.DispatchedAt = time.Time{}
+						incJobStatus(*job)
+					}
+					jobQueueMu.Unlock()
+					jobLog(dispatched).Error("dispatch failed, job queued again", "runner_id", runner.ID, "err", err)
 					continue
 				}
-				resp.Body.Close()
+				releaseIntake(dispatched.ID)
 
 				lastDispatchedID = job.ID
-				log.Printf("🚀 Dispatched job '%s' (ID: %s) to runner '%s'", job.JobName, job.ID, runner.ID)
+				events.Publish(events.JobStatus, job.ID, map[string]string{"status": "dispatched", "runner": runner.ID})
+				jobLog(dispatched).Info("job dispatched", "runner_id", runner.ID, "wait", dispatched.Wait(dispatched.DispatchedAt))
 				MarkRunnerBusy(runner.ID, true)
 			}
 		}
@@ -78,6 +93,10 @@ func TriggerNextJob() {
 				break
 			}
 		}
+		var next core.Job
+		if nextJob != nil {
+			next = *nextJob
+		}
 		jobQueueMu.Unlock()
 
 		if nextJob == nil {
@@ -89,21 +108,60 @@ func TriggerNextJob() {
 			return
 		}
 
-		payload, _ := json.Marshal(nextJob)
-		url := fmt.Sprintf("http://%s:%s/job", runner.Address, runner.Port)
-		resp, err := http.Post(url, "application/json", bytes.NewBuffer(payload))
-		if err != nil {
-			log.Printf("❌ Failed to trigger next job %s: %v", nextJob.JobName, err)
+		if err := sendJob(next, runner); err != nil {
+			observeDispatch(next, err)
+			jobLog(next).Error("dispatch failed", "runner_id", runner.ID, "err", err)
 			return
 		}
-		resp.Body.Close()
 
 		jobQueueMu.Lock()
 		nextJob.Status = "dispatched"
+		nextJob.RunnerID = runner.ID
+		nextJob.DispatchedAt = time.Now()
+		incJobStatus(*nextJob)
+		dispatched := *nextJob
 		jobQueueMu.Unlock()
+		releaseIntake(dispatched.ID)
+		observeDispatch(dispatched, nil)
 
 		lastDispatchedID = nextJob.ID
-		log.Printf("⚡ Triggered next job '%s' (ID: %s) to runner '%s'", nextJob.JobName, nextJob.ID, runner.ID)
+		events.Publish(events.JobStatus, nextJob.ID, map[string]string{"status": "dispatched", "runner": runner.ID})
+		jobLog(dispatched).Info("job dispatched", "runner_id", runner.ID, "wait", dispatched.Wait(dispatched.DispatchedAt), "trigger", "result")
 		MarkRunnerBusy(runner.ID, true)
 	}()
 }
+
+// sendJob mengirim job ke runner di dalam span job.dispatch. Waktu tunggu di
+// antrean dicatat sebagai span job.queued; trace context span dispatch ikut
+// di payload (trace_context) dan header traceparent supaya runner bisa
+// melanjutkan trace yang sama sampai callback /job/result.
+func sendJob(j core.Job, runner *Runner) error {
+	dispatchedAt := j.DispatchedAt
+	if dispatchedAt.IsZero() {
+		dispatchedAt = time.Now()
+	}
+	_, wait := startJobSpan(j, "job.queued", trace.WithTimestamp(dispatchedAt.Add(-j.Wait(dispatchedAt))))
+	wait.End(trace.WithTimestamp(dispatchedAt))
+
+	ctx, span := startJobSpan(j, "job.dispatch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("tcr.runner.id", runner.ID)))
+	defer span.End()
+
+	j.TraceContext = tracing.Carrier(ctx)
+	payload, _ := json.Marshal(j)
+	resp, err := PostJSONContext(ctx, RunnerURL(runner.Address, runner.Port, "/job"), payload)
+	if err != nil {
+		tracing.Fail(span, err)
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		// runner menolak: job belum diterima siapa pun, jangan dilepas dari intake
+		err := fmt.Errorf("runner %s responded %d", runner.ID, resp.StatusCode)
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		tracing.Fail(span, err)
+		return err
+	}
+	return nil
+}
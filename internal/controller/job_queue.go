@@ -2,37 +2,43 @@ package controller
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/ridwandwisiswanto/tcr/internal/core"
+	"github.com/ridwandwisiswanto/tcr/internal/events"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
 )
 
-// type Job struct {
-// 	ID        string    `json:"id"`
-// 	Action    string    `json:"action"`
-// 	RepoOwner string    `json:"repo_owner"`
-// 	RepoName  string    `json:"repo_name"`
-// 	JobName   string    `json:"job_name"`
-// 	Status    string    `json:"status"`
-// 	CreatedAt time.Time `json:"created_at"`
-// }
-
 var (
 	jobQueue   = []core.Job{}
 	jobQueueMu sync.Mutex
 )
 
-// AddJob menambahkan job baru ke queue
+// AddJob menambahkan job baru ke queue. Job dari webhook sudah membawa trace
+// context; job dari poller memulai trace-nya di sini.
 func AddJob(j core.Job) {
+	ctx, span := startJobSpan(j, "job.enqueue")
+	defer span.End()
+	if j.TraceContext == nil {
+		j.TraceContext = tracing.Carrier(ctx)
+	}
+	if j.TraceID == "" {
+		j.TraceID = tracing.TraceID(ctx)
+	}
+
 	jobQueueMu.Lock()
 	defer jobQueueMu.Unlock()
 
+	if j.QueuedAt.IsZero() {
+		j.QueuedAt = j.CreatedAt
+	}
 	jobQueue = append(jobQueue, j)
-	// jobQueueGauge.Set(float64(len(jobQueue))) // 🟢 metrics update
+	incJobStatus(j)
 
-	log.Printf("🧩 Job added to queue: %s (%s/%s)", j.JobName, j.RepoOwner, j.RepoName)
+	jobLog(j).Info("job queued", "trace_id", j.TraceID)
+	events.Publish(events.JobQueued, j.ID, j)
 }
 
 // GetJobs mengembalikan semua job yang ada di queue
@@ -53,13 +59,27 @@ func UpdateJobStatus(id string, status string) {
 
 	for i := range jobQueue {
 		if jobQueue[i].ID == id {
+			// job yang dibatalkan operator tidak ditimpa laporan runner
+			if jobQueue[i].Status == "cancelled" {
+				jobLog(jobQueue[i]).Info("ignoring status for cancelled job", "status", status)
+				return
+			}
 			jobQueue[i].Status = status
-			log.Printf("🟡 Job %s status updated to %s", id, status)
+			incJobStatus(jobQueue[i])
+			if status != "queued" {
+				releaseIntake(id)
+			}
+			if jobFinished(status) && jobQueue[i].FinishedAt.IsZero() {
+				jobQueue[i].FinishedAt = time.Now()
+				observeJobDuration(jobQueue[i])
+			}
+			jobLog(jobQueue[i]).Info("job status updated", "status", status)
+			events.Publish(events.JobStatus, id, map[string]string{"status": status})
 			return
 		}
 	}
 
-	log.Printf("⚠️ Job %s not found for status update", id)
+	logger.Warn("job not found for status update", "job_id", id, "status", status)
 }
 
 // RegisterHTTPRoutes menambahkan route HTTP /jobs
@@ -71,16 +91,60 @@ func RegisterHTTPRoutes() {
 		}
 		jobs := GetJobs()
 		w.Header().Set("Content-Type", "application/json")
+
+		// ?id=<job> untuk detail satu job
+		if id := r.URL.Query().Get("id"); id != "" {
+			for _, j := range jobs {
+				if j.ID == id {
+					json.NewEncoder(w).Encode(j)
+					return
+				}
+			}
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
 		json.NewEncoder(w).Encode(jobs)
 	})
 }
 
-// StartJobQueueListener — listener background untuk job masuk dari webhook
+// StartJobQueueListener — listener background untuk job masuk dari webhook.
+// Job queued baru di-Ack (releaseIntake) saat tidak lagi menunggu, jadi
+// backlog yang sebenarnya dihitung di kapasitas intake dan dimuat ulang dari
+// disk kalau towerd restart sebelum job di-dispatch.
 func StartJobQueueListener() {
+	intake := core.JobQueue
 	go func() {
-		for job := range core.JobQueue {
-			log.Printf("📦 Received new job: %s from repo %s/%s", job.JobName, job.RepoOwner, job.RepoName)
-			AddJob(job)
+		for {
+			job := intake.Take()
+			jobLog(job).Debug("job received from intake")
+			addIntakeJob(job)
 		}
 	}()
 }
+
+// addIntakeJob memasukkan job dari intake ke queue scheduler. Job hasil
+// requeue sudah ada di queue; salinannya di intake hanya menahan tempat
+// sampai job itu tidak lagi menunggu.
+func addIntakeJob(job core.Job) {
+	jobQueueMu.Lock()
+	if existing := findJobLocked(job.ID); existing != nil {
+		status := existing.Status
+		jobQueueMu.Unlock()
+		if status != "queued" {
+			releaseIntake(job.ID)
+		}
+		return
+	}
+	jobQueueMu.Unlock()
+
+	AddJob(job)
+	if job.Status != "queued" {
+		releaseIntake(job.ID)
+	}
+}
+
+// releaseIntake melepas job dari intake setelah di-dispatch, dibatalkan atau
+// statusnya berubah. No-op untuk job yang tidak lewat intake (poller).
+func releaseIntake(id string) {
+	core.JobQueue.Ack(id)
+}
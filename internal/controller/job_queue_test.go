@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+)
+
+// waitForJob menunggu listener memindahkan job dari intake ke queue scheduler
+func waitForJob(t *testing.T, id string) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, j := range GetJobs() {
+			if j.ID == id {
+				return
+			}
+		}
+	}
+	t.Fatalf("job %s never reached the scheduler queue", id)
+}
+
+func TestJobQueueListener_QueuedJobsCountAgainstIntake(t *testing.T) {
+	dir := t.TempDir()
+	intake, err := core.OpenIntake(dir, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := core.JobQueue
+	core.JobQueue = intake
+	defer func() { core.JobQueue = prev }()
+	StartJobQueueListener()
+
+	for _, id := range []string{"iq-1", "iq-2"} {
+		if err := core.AddJob(core.Job{ID: id, Status: "queued", CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("add %s: %v", id, err)
+		}
+		waitForJob(t, id)
+	}
+
+	// job masih menunggu dispatch: intake tetap penuh
+	if err := core.AddJob(core.Job{ID: "iq-3", Status: "queued"}); !errors.Is(err, core.ErrQueueFull) {
+		t.Fatalf("expected queued jobs to fill the intake, got %v", err)
+	}
+	if d := intake.Len(); d != 2 {
+		t.Fatalf("expected intake depth 2, got %d", d)
+	}
+
+	// job yang belum di-dispatch dimuat ulang setelah restart
+	if reopened, _ := core.OpenIntake(dir, 2, 1); reopened.Len() != 2 {
+		t.Fatalf("expected 2 jobs restored after restart, got %d", reopened.Len())
+	}
+
+	// job yang tidak lagi menunggu melepas tempatnya
+	if _, err := CancelJob("iq-1", "admin", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.AddJob(core.Job{ID: "iq-3", Status: "queued", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("expected room after cancel, got %v", err)
+	}
+	waitForJob(t, "iq-3")
+	if reopened, _ := core.OpenIntake(dir, 2, 1); reopened.Len() != 2 {
+		t.Fatalf("expected iq-2 and iq-3 on disk, got %d", reopened.Len())
+	}
+}
+
+func TestRequeueJob_GoesThroughIntake(t *testing.T) {
+	dir := t.TempDir()
+	intake, err := core.OpenIntake(dir, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := core.JobQueue
+	core.JobQueue = intake
+	defer func() { core.JobQueue = prev }()
+	StartJobQueueListener()
+
+	AddJob(core.Job{ID: "rq-1", Status: "failed", CreatedAt: time.Now()})
+	if err := core.AddJob(core.Job{ID: "rq-2", Status: "queued", CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, "rq-2")
+
+	// intake penuh: requeue ditolak dan job tetap selesai
+	if _, err := RequeueJob("rq-1", "admin", "test"); !errors.Is(err, core.ErrQueueFull) {
+		t.Fatalf("expected requeue to respect intake capacity, got %v", err)
+	}
+	for _, j := range GetJobs() {
+		if j.ID == "rq-1" && j.Status != "failed" {
+			t.Fatalf("rejected requeue changed the job: %+v", j)
+		}
+	}
+
+	if _, err := CancelJob("rq-2", "admin", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RequeueJob("rq-1", "admin", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if reopened, _ := core.OpenIntake(dir, 1, 1); reopened.Len() != 1 {
+		t.Fatalf("expected the requeued job on disk, got %d", reopened.Len())
+	}
+
+	// listener tidak menggandakan job yang sudah ada di queue
+	time.Sleep(100 * time.Millisecond)
+	n := 0
+	for _, j := range GetJobs() {
+		if j.ID == "rq-1" {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Fatalf("expected rq-1 once in the scheduler queue, got %d", n)
+	}
+}
@@ -0,0 +1,16 @@
+package controller
+
+import (
+	"log/slog"
+
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+	"github.com/ridwandwisiswanto/tcr/internal/logging"
+)
+
+var logger = logging.For("controller")
+
+// jobLog adalah logger dengan field standar sebuah job (job_id, repo, pool)
+func jobLog(j core.Job) *slog.Logger {
+	repo, pool := jobLabels(j)
+	return logger.With("job_id", j.ID, "job", j.JobName, "repo", repo, "pool", pool)
+}
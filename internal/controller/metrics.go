@@ -2,32 +2,45 @@ package controller
 
 import (
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/ridwandwisiswanto/tcr/internal/core"
 )
 
 var (
 	JobTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "tcr_jobs_total",
-			Help: "Total jobs by status",
+			Help: "Job status transitions by status, repository and pool",
 		},
-		[]string{"status"},
+		[]string{"status", "repo", "pool"},
 	)
 	JobsInQueue = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "tcr_jobs_in_queue",
-			Help: "Number of jobs currently in queue",
+			Help: "Number of jobs currently queued (not yet dispatched)",
 		},
 	)
-	JobDuration = prometheus.NewHistogram(
+	JobDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "tcr_job_duration_seconds",
-			Help:    "Job duration in seconds",
-			Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+			Help:    "Job duration in seconds, from dispatch to completion",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 14),
+		},
+		[]string{"repo", "pool"},
+	)
+	JobQueueWait = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "tcr_job_queue_wait_seconds",
+			Help:    "Time a job waited in the queue before being dispatched",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 14),
 		},
+		[]string{"repo", "pool"},
 	)
 	RunnersTotal = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -47,33 +60,153 @@ var (
 			Help: "Number of failed dispatch attempts",
 		},
 	)
+	DispatchAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tcr_dispatch_attempts_total",
+			Help: "Dispatch attempts to runners by result (success, error)",
+		},
+		[]string{"result", "repo", "pool"},
+	)
+	Agents = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tcr_agents",
+			Help: "Agents by state and pool (an agent with several labels counts in each pool)",
+		},
+		[]string{"state", "pool"},
+	)
+	AgentRunners = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tcr_agent_runners",
+			Help: "Runners reported by active agents, by pool",
+		},
+		[]string{"pool"},
+	)
+	ScaleDecisions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tcr_scale_decisions_total",
+			Help: "Scaling decisions by source (poller, manual) and direction (up, down, hold)",
+		},
+		[]string{"source", "direction"},
+	)
+	ScaleRunners = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tcr_scale_runners_total",
+			Help: "Runners requested by scaling decisions",
+		},
+		[]string{"source", "direction"},
+	)
+	ScaleErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tcr_scale_errors_total",
+			Help: "Failed scale up/down operations",
+		},
+		[]string{"source"},
+	)
 )
 
 func init() {
-	prometheus.MustRegister(JobTotal, JobsInQueue, JobDuration, RunnersTotal, RunnersIdle, DispatchErrors)
+	prometheus.MustRegister(JobTotal, JobsInQueue, JobDuration, JobQueueWait, RunnersTotal, RunnersIdle,
+		DispatchErrors, DispatchAttempts, Agents, AgentRunners, ScaleDecisions, ScaleRunners, ScaleErrors)
 }
 
-// ExposeMetrics registers /metrics endpoint on the default mux (or explicit one)
+// ExposeMetrics registers /metrics endpoint on the default mux (admin only
+// behind auth.Server.Protect).
 func ExposeMetrics() {
-	http.Handle("/metrics", promhttp.Handler())
-	// optionally run separate listener if you want different port for metrics
-	// go http.ListenAndServe(addr, nil)
+	http.Handle("/metrics", MetricsHandler())
+}
+
+// ServeMetrics menjalankan listener terpisah yang hanya melayani /metrics,
+// supaya Prometheus bisa scrape tanpa admin token
+func ServeMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", MetricsHandler())
+	go func() {
+		logger.Info("metrics listening", "addr", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Warn("metrics listener stopped", "err", err)
+		}
+	}()
+}
+
+// MetricsHandler melayani metrics Prometheus. Gauge yang dihitung dari state
+// (queue, runner, agent) diperbarui saat scrape.
+func MetricsHandler() http.Handler {
+	metrics := promhttp.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		updateJobsInQueue()
+		updateRunnerGauges()
+		updateAgentGauges()
+		metrics.ServeHTTP(w, r)
+	})
+}
+
+// GitHub menambahkan label OS/arsitektur default ke semua job self-hosted;
+// label lain (gpu, large, ...) yang menentukan pool
+var defaultRunnerLabels = map[string]bool{
+	"self-hosted": true, "linux": true, "windows": true, "macos": true,
+	"x64": true, "arm64": true, "arm": true,
+}
+
+// jobLabels mengembalikan label repo dan pool untuk metrics job
+func jobLabels(j core.Job) (repo, pool string) {
+	var custom []string
+	for _, l := range j.Labels {
+		if !defaultRunnerLabels[strings.ToLower(l)] {
+			custom = append(custom, l)
+		}
+	}
+	sort.Strings(custom)
+	pool = strings.Join(custom, ",")
+	if pool == "" {
+		pool = "default"
+	}
+	return j.RepoOwner + "/" + j.RepoName, pool
 }
 
 // Helper functions to update metrics — call from job queue / dispatcher / runners
-func incJobStatus(status string) {
-	JobTotal.WithLabelValues(status).Inc()
-	updateJobsInQueue()
+func incJobStatus(j core.Job) {
+	repo, pool := jobLabels(j)
+	JobTotal.WithLabelValues(j.Status, repo, pool).Inc()
 }
 
 func updateJobsInQueue() {
 	jobQueueMu.Lock()
-	JobsInQueue.Set(float64(len(jobQueue)))
+	queued := 0
+	for _, j := range jobQueue {
+		if j.Status == "queued" {
+			queued++
+		}
+	}
 	jobQueueMu.Unlock()
+	JobsInQueue.Set(float64(queued))
+}
+
+func observeJobDuration(j core.Job) {
+	if j.DispatchedAt.IsZero() || j.FinishedAt.IsZero() {
+		return
+	}
+	repo, pool := jobLabels(j)
+	JobDuration.WithLabelValues(repo, pool).Observe(j.FinishedAt.Sub(j.DispatchedAt).Seconds())
+}
+
+// observeDispatch mencatat hasil dispatch; wait time hanya dicatat kalau berhasil
+func observeDispatch(j core.Job, err error) {
+	repo, pool := jobLabels(j)
+	if err != nil {
+		DispatchErrors.Inc()
+		DispatchAttempts.WithLabelValues("error", repo, pool).Inc()
+		return
+	}
+	DispatchAttempts.WithLabelValues("success", repo, pool).Inc()
+	JobQueueWait.WithLabelValues(repo, pool).Observe(j.Wait(j.DispatchedAt).Seconds())
 }
 
-func observeJobDuration(d time.Duration) {
-	JobDuration.Observe(d.Seconds())
+// observeScale mencatat keputusan scaling; count 0 berarti hold
+func observeScale(source, direction string, count int) {
+	ScaleDecisions.WithLabelValues(source, direction).Inc()
+	if count > 0 {
+		ScaleRunners.WithLabelValues(source, direction).Add(float64(count))
+	}
 }
 
 func updateRunnerGauges() {
@@ -89,3 +222,24 @@ func updateRunnerGauges() {
 	RunnersTotal.Set(total)
 	RunnersIdle.Set(float64(idleCount))
 }
+
+func updateAgentGauges() {
+	Agents.Reset()
+	AgentRunners.Reset()
+	for _, a := range ListAgents() {
+		pools := a.Labels
+		if len(pools) == 0 {
+			pools = []string{"default"}
+		}
+		state := a.State
+		if state == "" {
+			state = "unknown"
+		}
+		for _, p := range pools {
+			Agents.WithLabelValues(state, p).Inc()
+			if a.IsActive {
+				AgentRunners.WithLabelValues(p).Add(float64(a.Runners))
+			}
+		}
+	}
+}
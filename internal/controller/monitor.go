@@ -1,7 +1,6 @@
 package controller
 
 import (
-	"log"
 	"time"
 )
 
@@ -30,7 +29,7 @@ func AutoResetStuckRunners() {
 					// Kalau runner busy tapi gak ada job aktif → reset jadi idle
 					if !active {
 						r.IsBusy = false
-						log.Printf("🧹 Auto-reset runner %s (no active jobs)", r.ID)
+						logger.Info("runner reset to idle, no active job", "runner_id", r.ID)
 					}
 				}
 			}
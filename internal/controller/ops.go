@@ -0,0 +1,505 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ridwandwisiswanto/tcr/internal/audit"
+	"github.com/ridwandwisiswanto/tcr/internal/auth"
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+	"github.com/ridwandwisiswanto/tcr/internal/events"
+	"github.com/ridwandwisiswanto/tcr/internal/github"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
+)
+
+// Capacity is a summary of the pool for operators (tcrctl pool capacity)
+type Capacity struct {
+	Agents          int `json:"agents"`
+	AgentRunners    int `json:"agent_runners"`
+	Runners         int `json:"runners"`
+	BusyRunners     int `json:"busy_runners"`
+	DrainingRunners int `json:"draining_runners"`
+	QueuedJobs      int `json:"queued_jobs"`
+	DispatchedJobs  int `json:"dispatched_jobs"`
+	MaxRunnersTotal int `json:"max_runners_total"`
+	ScaleStepMax    int `json:"scale_step_max"`
+}
+
+// GetCapacity counts active agents, live runners and jobs waiting in the queue
+func GetCapacity() Capacity {
+	cfg := currentSettings()
+	c := Capacity{
+		MaxRunnersTotal: cfg.Scaling.MaxRunnersTotal,
+		ScaleStepMax:    cfg.Scaling.ScaleStepMax,
+	}
+
+	mu.Lock()
+	for _, a := range agents {
+		if a.IsActive {
+			c.Agents++
+			c.AgentRunners += a.Runners
+		}
+	}
+	mu.Unlock()
+
+	runnersMu.Lock()
+	for _, r := range runners {
+		if time.Since(r.LastSeen) > 30*time.Second {
+			continue
+		}
+		c.Runners++
+		if r.IsBusy {
+			c.BusyRunners++
+		}
+		if r.Draining {
+			c.DrainingRunners++
+		}
+	}
+	runnersMu.Unlock()
+
+	for _, j := range GetJobs() {
+		switch j.Status {
+		case "queued":
+			c.QueuedJobs++
+		case "dispatched":
+			c.DispatchedJobs++
+		}
+	}
+	return c
+}
+
+// RegisterOpsRoutes exposes operator actions (admin only, used by tcrctl):
+//
+//	POST /runners/drain?id=<runner>   stop dispatching jobs to a runner
+//	POST /runners/remove?id=<runner>  forget a runner and remove it from GitHub
+//	POST /agents/drain?agent=<id>     ask agentd to drain and exit
+//	POST /agents/remove?agent=<id>    forget an agent
+//	GET  /capacity                    pool capacity summary
+//	POST /scale {"direction":"up|down","count":n}
+//	POST /jobs/cancel?id=<job>&reason=  cancel a job and free its runner
+//	POST /jobs/requeue?id=<job>&reason= put a finished/cancelled job back in the queue
+func RegisterOpsRoutes() {
+	http.HandleFunc("/runners/drain", postOnly(handleRunnerDrain))
+	http.HandleFunc("/runners/remove", postOnly(handleRunnerRemove))
+	http.HandleFunc("/agents/drain", postOnly(handleAgentDrain))
+	http.HandleFunc("/agents/remove", postOnly(handleAgentRemove))
+	http.HandleFunc("/capacity", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, GetCapacity())
+	})
+	http.HandleFunc("/scale", postOnly(handleScale))
+	http.HandleFunc("/jobs/cancel", postOnly(handleJobAction(CancelJob)))
+	http.HandleFunc("/jobs/requeue", postOnly(handleJobAction(RequeueJob)))
+}
+
+func postOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// Errors returned by operator actions, mapped to HTTP status by the handlers
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	ErrInvalid  = errors.New("invalid request")
+)
+
+// httpStatus maps an operator action error to an HTTP status code
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, core.ErrQueueFull):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// DrainRunner stops dispatching new jobs to a runner; by is recorded in the audit log
+func DrainRunner(id, by string) (_ Runner, err error) {
+	defer func() { audit.Record(by, audit.RunnerDrain, id, nil, err) }()
+
+	runnersMu.Lock()
+	defer runnersMu.Unlock()
+	rn, ok := runners[id]
+	if !ok {
+		return Runner{}, fmt.Errorf("%w: runner %s", ErrNotFound, id)
+	}
+	rn.Draining = true
+	logger.Info("runner draining", "runner_id", id)
+	events.Publish(events.RunnerDraining, id, nil)
+	return *rn, nil
+}
+
+// RemoveRunner forgets an idle runner and removes it from GitHub; by is
+// recorded in the audit log
+func RemoveRunner(id, by string) (err error) {
+	defer func() { audit.Record(by, audit.RunnerRemove, id, nil, err) }()
+
+	runnersMu.Lock()
+	rn, ok := runners[id]
+	if ok && rn.IsBusy {
+		runnersMu.Unlock()
+		return fmt.Errorf("%w: runner %s is busy, drain it first", ErrConflict, id)
+	}
+	delete(runners, id)
+	runnersMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: runner %s", ErrNotFound, id)
+	}
+
+	// runner hybrid terdaftar di GitHub dengan nama yang sama
+	if ghID, err := github.GetRunnerIDByName(id); err == nil {
+		err := github.RemoveRunnerByID(ghID)
+		audit.Record(by, audit.GitHubRunnerDelete, id, map[string]interface{}{"github_runner_id": ghID}, err)
+		if err != nil {
+			logger.Warn("runner removed from tower but not from GitHub", "runner_id", id, "err", err)
+		}
+	}
+	logger.Info("runner removed", "runner_id", id)
+	events.Publish(events.RunnerRemoved, id, nil)
+	return nil
+}
+
+// DrainAgent asks agentd (through the next heartbeat directive) to drain and exit
+func DrainAgent(id, by string) (_ Agent, err error) {
+	defer func() { audit.Record(by, audit.AgentDrain, id, nil, err) }()
+
+	mu.Lock()
+	defer mu.Unlock()
+	a, ok := agents[id]
+	if !ok {
+		return Agent{}, fmt.Errorf("%w: agent %s", ErrNotFound, id)
+	}
+	a.DrainRequested = true
+	logger.Info("agent drain requested", "agent_id", id)
+	return *a, nil
+}
+
+// RemoveAgent forgets an agent; a live agentd re-registers on its next heartbeat
+func RemoveAgent(id, by string) (err error) {
+	defer func() { audit.Record(by, audit.AgentRemove, id, nil, err) }()
+
+	mu.Lock()
+	_, ok := agents[id]
+	delete(agents, id)
+	mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: agent %s", ErrNotFound, id)
+	}
+	logger.Info("agent removed", "agent_id", id)
+	events.Publish(events.AgentRemoved, id, nil)
+	return nil
+}
+
+// Scale adds or removes count runners on operator request; the request and
+// the provider resize it triggers are both recorded in the audit log. Scaling
+// down only removes idle towerd runners and fails when there is none.
+func Scale(direction string, count int, by string) (err error) {
+	params := map[string]interface{}{"direction": direction, "count": count}
+	defer func() { audit.Record(by, audit.PoolScale, "", params, err) }()
+
+	if count <= 0 {
+		return fmt.Errorf("%w: count must be > 0", ErrInvalid)
+	}
+
+	switch direction {
+	case "up":
+		c := GetCapacity()
+		if room := c.MaxRunnersTotal - c.Runners - c.AgentRunners; count > room {
+			return fmt.Errorf("%w: scaling up by %d would exceed max_runners_total (%d free)", ErrConflict, count, room)
+		}
+		logger.Info("manual scale up", "count", count)
+		events.Publish(events.ScaleUp, "manual", map[string]int{"count": count})
+		observeScale("manual", "up", count)
+		go func() {
+			if err := scaleUp(by, count); err != nil {
+				ScaleErrors.WithLabelValues("manual").Inc()
+				logger.Error("manual scale up failed", "count", count, "err", err)
+			}
+		}()
+	case "down":
+		removed := scaleDown(by, count)
+		params["removed"] = removed
+		if removed == 0 {
+			return fmt.Errorf("%w: no idle runner to remove", ErrConflict)
+		}
+		logger.Info("manual scale down", "count", count, "removed", removed)
+		events.Publish(events.ScaleDown, "manual", map[string]int{"count": removed})
+		observeScale("manual", "down", removed)
+	default:
+		return fmt.Errorf("%w: direction must be up or down", ErrInvalid)
+	}
+	return nil
+}
+
+// jobFinished: status akhir job, baik dari runner, GitHub, maupun operator
+func jobFinished(status string) bool {
+	switch status {
+	case "done", "success", "failed", "completed", "cancelled":
+		return true
+	}
+	return false
+}
+
+// CancelJob marks a job cancelled, tells the runner it was dispatched to to
+// abort and cancels the GitHub workflow run (which also aborts jobs picked up
+// by agentd runners). The runner is only freed once it confirms the abort;
+// otherwise it stays busy until it reports the job result. by is recorded in
+// the job history and the audit log.
+func CancelJob(id, by, reason string) (_ core.Job, err error) {
+	params := map[string]interface{}{"reason": reason}
+	defer func() { audit.Record(by, audit.JobCancel, id, params, err) }()
+
+	jobQueueMu.Lock()
+	j := findJobLocked(id)
+	if j == nil {
+		jobQueueMu.Unlock()
+		return core.Job{}, fmt.Errorf("%w: job %s", ErrNotFound, id)
+	}
+	if jobFinished(j.Status) {
+		jobQueueMu.Unlock()
+		return core.Job{}, fmt.Errorf("%w: job %s is already %s", ErrConflict, id, j.Status)
+	}
+	previous := j.Status
+	params["previous"] = previous
+	j.Status = "cancelled"
+	j.FinishedAt = time.Now()
+	j.Actions = append(j.Actions, core.JobAction{Action: "cancel", By: by, Reason: reason, At: time.Now()})
+	incJobStatus(*j)
+	job := copyJob(*j)
+	jobQueueMu.Unlock()
+	releaseIntake(id)
+
+	ctx, span := startJobSpan(job, "job.cancel", trace.WithAttributes(
+		attribute.String("tcr.actor", by), attribute.String("tcr.reason", reason)))
+	defer span.End()
+
+	jobLog(job).Info("job cancelled", "by", by, "reason", reason, "previous", previous)
+	events.Publish(events.JobCancelled, id, map[string]string{"by": by, "reason": reason, "previous": previous})
+
+	if job.RunnerID != "" && previous != "queued" {
+		if abortOnRunner(ctx, job) {
+			MarkRunnerBusy(job.RunnerID, false)
+			go TriggerNextJob()
+		} else {
+			jobLog(job).Warn("runner kept busy until it reports the job result", "runner_id", job.RunnerID)
+		}
+	}
+	if job.RunID != 0 {
+		if err := github.CancelWorkflowRun(job.RepoOwner, job.RepoName, job.RunID); err != nil {
+			tracing.Fail(span, err)
+			jobLog(job).Warn("GitHub workflow run not cancelled", "run_id", job.RunID, "err", err)
+		} else {
+			jobLog(job).Info("GitHub workflow run cancel requested", "run_id", job.RunID)
+		}
+	}
+	return job, nil
+}
+
+// RequeueJob puts a finished or cancelled job back in the queue so the
+// dispatcher picks it up again; running jobs must be cancelled first.
+func RequeueJob(id, by, reason string) (_ core.Job, err error) {
+	params := map[string]interface{}{"reason": reason}
+	defer func() { audit.Record(by, audit.JobRequeue, id, params, err) }()
+
+	jobQueueMu.Lock()
+	j := findJobLocked(id)
+	if j == nil {
+		jobQueueMu.Unlock()
+		return core.Job{}, fmt.Errorf("%w: job %s", ErrNotFound, id)
+	}
+	if !jobFinished(j.Status) {
+		jobQueueMu.Unlock()
+		return core.Job{}, fmt.Errorf("%w: job %s is %s, cancel it first", ErrConflict, id, j.Status)
+	}
+	previous := j.Status
+	params["previous"] = previous
+	requeued := copyJob(*j)
+	requeued.Status = "queued"
+	requeued.RunnerID = ""
+	requeued.QueuedAt = time.Now()
+	requeued.DispatchedAt = time.Time{}
+	requeued.FinishedAt = time.Time{}
+	requeued.Actions = append(requeued.Actions, core.JobAction{Action: "requeue", By: by, Reason: reason, At: time.Now()})
+
+	// lewat intake seperti job baru: kena batas capacity dan selamat dari
+	// restart sampai di-dispatch lagi
+	if err := core.JobQueue.Put(requeued); err != nil {
+		jobQueueMu.Unlock()
+		return core.Job{}, fmt.Errorf("requeue job %s: %w", id, err)
+	}
+	*j = requeued
+	if lastDispatchedID == id {
+		lastDispatchedID = ""
+	}
+	incJobStatus(*j)
+	job := copyJob(*j)
+	jobQueueMu.Unlock()
+
+	_, span := startJobSpan(job, "job.requeue", trace.WithAttributes(
+		attribute.String("tcr.actor", by), attribute.String("tcr.reason", reason)))
+	span.End()
+
+	jobLog(job).Info("job requeued", "by", by, "reason", reason, "previous", previous)
+	events.Publish(events.JobRequeued, id, map[string]string{"by": by, "reason": reason, "previous": previous})
+	go TriggerNextJob()
+	return job, nil
+}
+
+func findJobLocked(id string) *core.Job {
+	for i := range jobQueue {
+		if jobQueue[i].ID == id {
+			return &jobQueue[i]
+		}
+	}
+	return nil
+}
+
+func copyJob(j core.Job) core.Job {
+	j.Actions = append([]core.JobAction(nil), j.Actions...)
+	return j
+}
+
+// abortOnRunner meminta runner menghentikan job lewat POST /job/cancel
+// {"id", "runner"}: ke runnerd kalau runner terdaftar lewat /heartbeat, atau
+// ke agentd yang melaporkan nama runner itu di heartbeat VM. true hanya
+// kalau runnerd/agentd mengonfirmasi (2xx); runner yang tidak bisa
+// dihubungi atau menolak tetap sibuk sampai melaporkan hasil job.
+func abortOnRunner(ctx context.Context, job core.Job) bool {
+	url := abortURL(job.RunnerID)
+	if url == "" {
+		jobLog(job).Warn("no runnerd or agentd known for runner, cannot abort job", "runner_id", job.RunnerID)
+		return false
+	}
+
+	payload, _ := json.Marshal(map[string]string{"id": job.ID, "runner": job.RunnerID})
+	resp, err := PostJSONContext(ctx, url, payload)
+	if err != nil {
+		jobLog(job).Warn("cannot reach runner to abort job", "runner_id", job.RunnerID, "err", err)
+		return false
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		jobLog(job).Warn("runner refused job abort", "runner_id", job.RunnerID, "status_code", resp.StatusCode)
+		return false
+	}
+	return true
+}
+
+// abortURL adalah endpoint /job/cancel untuk runner id, kosong kalau tidak dikenal
+func abortURL(id string) string {
+	runnersMu.Lock()
+	rn, ok := runners[id]
+	runnersMu.Unlock()
+	if ok {
+		return RunnerURL(rn.Address, rn.Port, "/job/cancel")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, a := range agents {
+		if a.APIPort == "" || !containsString(a.RunnerNames, id) {
+			continue
+		}
+		host, _, err := net.SplitHostPort(a.Address)
+		if err != nil {
+			host = a.Address
+		}
+		return RunnerURL(host, a.APIPort, "/job/cancel")
+	}
+	return ""
+}
+
+// Actor returns who is calling, for the job history and logs
+func Actor(r *http.Request) string {
+	if c, ok := auth.FromRequest(r); ok && c.Subject != "" {
+		return c.Subject
+	}
+	return "unknown"
+}
+
+func handleJobAction(action func(id, by, reason string) (core.Job, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		j, err := action(q.Get("id"), Actor(r), q.Get("reason"))
+		if err != nil {
+			http.Error(w, err.Error(), httpStatus(err))
+			return
+		}
+		writeJSON(w, j)
+	}
+}
+
+func handleRunnerDrain(w http.ResponseWriter, r *http.Request) {
+	rn, err := DrainRunner(r.URL.Query().Get("id"), Actor(r))
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	writeJSON(w, rn)
+}
+
+func handleRunnerRemove(w http.ResponseWriter, r *http.Request) {
+	if err := RemoveRunner(r.URL.Query().Get("id"), Actor(r)); err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleAgentDrain(w http.ResponseWriter, r *http.Request) {
+	a, err := DrainAgent(r.URL.Query().Get("agent"), Actor(r))
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	writeJSON(w, a)
+}
+
+func handleAgentRemove(w http.ResponseWriter, r *http.Request) {
+	if err := RemoveAgent(r.URL.Query().Get("agent"), Actor(r)); err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleScale(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Direction string `json:"direction"`
+		Count     int    `json:"count"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "expected {\"direction\":\"up|down\",\"count\":n}", http.StatusBadRequest)
+		return
+	}
+	if err := Scale(body.Direction, body.Count, Actor(r)); err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
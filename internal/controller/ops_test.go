@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+)
+
+func runnerBusy(id string) bool {
+	runnersMu.Lock()
+	defer runnersMu.Unlock()
+	return runners[id].IsBusy
+}
+
+func TestCancelJob_KeepsRunnerBusyUntilAbortConfirmed(t *testing.T) {
+	var accept atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/job/cancel" || !accept.Load() {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	// LastSeen kosong: runner tidak ikut dipilih dispatcher selama test
+	runnersMu.Lock()
+	runners["abort-r1"] = &Runner{ID: "abort-r1", Address: host, Port: port, IsBusy: true}
+	runnersMu.Unlock()
+	defer func() {
+		runnersMu.Lock()
+		delete(runners, "abort-r1")
+		runnersMu.Unlock()
+	}()
+
+	for _, id := range []string{"abort-1", "abort-2"} {
+		AddJob(core.Job{ID: id, Status: "running", RunnerID: "abort-r1"})
+	}
+
+	// runner menolak abort: tetap sibuk sampai melaporkan hasil
+	if _, err := CancelJob("abort-1", "admin", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if !runnerBusy("abort-r1") {
+		t.Fatalf("runner freed although it did not confirm the abort")
+	}
+	req := httptest.NewRequest("POST", "/job/result", bytes.NewBufferString(`{"id":"abort-1","status":"cancelled","runner_id":"abort-r1"}`))
+	ResultHandler(httptest.NewRecorder(), req)
+	if runnerBusy("abort-r1") {
+		t.Fatalf("runner still busy after reporting the job result")
+	}
+
+	// runner mengonfirmasi abort: langsung dibebaskan
+	MarkRunnerBusy("abort-r1", true)
+	accept.Store(true)
+	if _, err := CancelJob("abort-2", "admin", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if runnerBusy("abort-r1") {
+		t.Fatalf("runner still busy after confirming the abort")
+	}
+}
+
+func TestCancelJob_AbortsAgentRunnerThroughAgentd(t *testing.T) {
+	var got atomic.Value
+	agentd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got.Store(r.URL.Path + " " + string(body))
+	}))
+	defer agentd.Close()
+	host, port, _ := net.SplitHostPort(agentd.Listener.Addr().String())
+
+	mu.Lock()
+	agents["abort-vm"] = &Agent{ID: "abort-vm", Address: net.JoinHostPort(host, "40000"), APIPort: port,
+		RunnerNames: []string{"abort-vm-agent-01"}}
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		delete(agents, "abort-vm")
+		mu.Unlock()
+	}()
+
+	AddJob(core.Job{ID: "abort-3", Status: "running", RunnerID: "abort-vm-agent-01"})
+	if _, err := CancelJob("abort-3", "admin", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := got.Load().(string); s != `/job/cancel {"id":"abort-3","runner":"abort-vm-agent-01"}` {
+		t.Fatalf("unexpected abort request %q", s)
+	}
+}
+
+func TestScaleDown_OnlyDrainsIdleRunners(t *testing.T) {
+	runnersMu.Lock()
+	runners["sd-busy"] = &Runner{ID: "sd-busy", IsBusy: true}
+	runners["sd-idle-1"] = &Runner{ID: "sd-idle-1"}
+	runners["sd-idle-2"] = &Runner{ID: "sd-idle-2"}
+	runnersMu.Unlock()
+	defer func() {
+		runnersMu.Lock()
+		for _, id := range []string{"sd-busy", "sd-idle-1", "sd-idle-2"} {
+			delete(runners, id)
+		}
+		runnersMu.Unlock()
+	}()
+
+	ids := drainIdleRunners(1)
+	if len(ids) != 1 || ids[0] == "sd-busy" {
+		t.Fatalf("expected one idle runner, got %v", ids)
+	}
+	runnersMu.Lock()
+	busyDraining, picked := runners["sd-busy"].Draining, runners[ids[0]].Draining
+	delete(runners, "sd-idle-1")
+	delete(runners, "sd-idle-2")
+	runnersMu.Unlock()
+	if busyDraining || !picked {
+		t.Fatalf("busy runner draining=%v, picked runner draining=%v", busyDraining, picked)
+	}
+
+	// hanya runner sibuk tersisa: scale down gagal, bukan no-op yang sukses
+	if err := Scale("down", 1, "admin"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict without idle runners, got %v", err)
+	}
+	if !runnerBusy("sd-busy") {
+		t.Fatalf("busy runner touched by scale down")
+	}
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/auth"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
+)
+
+// outbound calls from towerd to runners and agents; switched to mTLS by
+// ConfigureOutboundTLS when towerd runs with its internal CA.
+var (
+	outboundClient = &http.Client{Timeout: 10 * time.Second}
+	outboundScheme = "http"
+	outboundSecret string
+)
+
+// ConfigureOutboundAuth signs every towerd → runner/agent call with the
+// bootstrap secret (auth.SignTower), so agentd/runnerd can reject callers
+// that are not towerd when mTLS is off.
+func ConfigureOutboundAuth(bootstrap string) {
+	outboundSecret = bootstrap
+}
+
+// signOutbound adds the tower signature to a runner/agent request
+func signOutbound(req *http.Request) {
+	auth.SignTower(req, outboundSecret)
+}
+
+// ConfigureOutboundTLS makes every towerd → runner/agent call use HTTPS with
+// the given client config (tower certificate + internal CA pool).
+func ConfigureOutboundTLS(cfg *tls.Config) {
+	outboundClient = &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: cfg},
+	}
+	outboundScheme = "https"
+}
+
+// OutboundTransport is the transport for long-lived proxied streams (no timeout)
+func OutboundTransport() http.RoundTripper {
+	if outboundClient.Transport != nil {
+		return outboundClient.Transport
+	}
+	return http.DefaultTransport
+}
+
+// RunnerURL builds the URL of a runner/agent endpoint with the right scheme
+func RunnerURL(host, port, path string) string {
+	return fmt.Sprintf("%s://%s:%s%s", outboundScheme, host, port, path)
+}
+
+// PostJSON posts body to a runner/agent endpoint using the outbound client
+func PostJSON(url string, body []byte) (*http.Response, error) {
+	return PostJSONContext(context.Background(), url, body)
+}
+
+// PostJSONContext is PostJSON with the trace context of ctx propagated in
+// the traceparent header
+func PostJSONContext(ctx context.Context, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, req.Header)
+	signOutbound(req)
+	return outboundClient.Do(req)
+}
@@ -4,64 +4,73 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
 	"os/exec"
-	"strconv"
+	"sync"
 	"time"
 
+	"github.com/ridwandwisiswanto/tcr/internal/audit"
+	"github.com/ridwandwisiswanto/tcr/internal/config"
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+	"github.com/ridwandwisiswanto/tcr/internal/events"
 	"github.com/ridwandwisiswanto/tcr/internal/github"
+	"github.com/ridwandwisiswanto/tcr/internal/logging"
 )
 
 var (
-	pollIntervalSec  int
-	maxScaleStep     int
-	globalMaxRunners int
-	spawnMethod      string
-	agentRegisterURL string
-	gcloudProject    string
-	gcpMigName       string
-	mode             string
+	settingsMu sync.RWMutex
+	settings   = config.DefaultTower()
 )
 
-func atoiEnv(k string, def int) int {
-	if v := os.Getenv(k); v != "" {
-		if i, err := strconv.Atoi(v); err == nil {
-			return i
-		}
-	}
-	return def
+// Configure memasang config towerd hasil config.LoadTower; dipanggil sekali
+// di main sebelum route dan poller dijalankan.
+func Configure(cfg config.Tower) {
+	settingsMu.Lock()
+	settings = cfg
+	settingsMu.Unlock()
 }
 
-func getEnv(k, def string) string {
-	if v := os.Getenv(k); v != "" {
-		return v
+// Reload menerapkan setting yang aman diganti saat towerd jalan
+// (scaling limits, rollout, dan level log); sisanya tetap pakai nilai saat start.
+func Reload(cfg config.Tower) {
+	settingsMu.Lock()
+	settings.Scaling = cfg.Scaling
+	settings.Rollout = cfg.Rollout
+	settings.Logging.Level, settings.Logging.Levels = cfg.Logging.Level, cfg.Logging.Levels
+	settingsMu.Unlock()
+	if err := logging.Reload(cfg.Logging); err != nil {
+		logger.Warn("log levels not reloaded", "err", err)
 	}
-	return def
+
+	rolloutMu.Lock()
+	rollout.BatchSize = cfg.Rollout.BatchSize
+	rollout.MaxAgents = cfg.Rollout.MaxAgents
+	rolloutMu.Unlock()
+
+	core.JobQueue.SetLimits(cfg.Queue.Capacity, cfg.Queue.HighWatermark)
+
+	logger.Info("settings applied", "scaling", cfg.Scaling, "rollout", cfg.Rollout, "queue_capacity", cfg.Queue.Capacity)
+}
+
+func currentSettings() config.Tower {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return settings
 }
 
 func StartPoller() {
-	mode = os.Getenv("MODE")
-	pollIntervalSec = atoiEnv("POLL_INTERVAL_SECONDS", 30)
-	maxScaleStep = atoiEnv("SCALE_STEP_MAX", 3)
-	globalMaxRunners = atoiEnv("MAX_RUNNERS_TOTAL", 20)
-	spawnMethod = os.Getenv("SPAWN_METHOD")
-	agentRegisterURL = getEnv("AGENT_REGISTRATION_ENDPOINT", "http://localhost:8081/register-hybrid")
-	gcloudProject = os.Getenv("GCP_PROJECT")
-	gcpMigName = os.Getenv("GCP_MIG_NAME")
-	log.Printf("🧩 MODE env detected = '%s'", mode)
-
-	if mode != "polling" {
-		log.Printf("🔕 Poller disabled (MODE=%s)", mode)
+	cfg := currentSettings()
+	if cfg.Mode != "polling" {
+		logger.Info("poller disabled", "mode", cfg.Mode)
 		return
 	}
-	log.Printf("🔁 Starting polling engine (interval=%ds)", pollIntervalSec)
+	logger.Info("poller started", "interval_sec", cfg.Scaling.PollIntervalSec)
 	go pollLoop()
 }
 
 func pollLoop() {
-	ticker := time.NewTicker(time.Duration(pollIntervalSec) * time.Second)
+	interval := time.Duration(currentSettings().Scaling.PollIntervalSec) * time.Second
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	var backoff time.Duration
@@ -70,6 +79,11 @@ func pollLoop() {
 		case <-ticker.C:
 			backoff = 0 // reset on each scheduled tick
 			processOnce()
+			// interval bisa berubah lewat hot reload
+			if next := time.Duration(currentSettings().Scaling.PollIntervalSec) * time.Second; next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
 		default:
 			if backoff > 0 {
 				time.Sleep(backoff)
@@ -85,19 +99,23 @@ func pollLoop() {
 }
 
 func processOnce() {
+	cfg := currentSettings()
+	maxScaleStep := cfg.Scaling.ScaleStepMax
+	globalMaxRunners := cfg.Scaling.MaxRunnersTotal
+
 	queued, err := github.CountQueuedRuns()
 	if err != nil {
-		log.Printf("⚠️ poll error (queued): %v", err)
+		logger.Warn("poll failed", "stage", "queued_runs", "err", err)
 		return
 	}
 
 	total, idle, err := github.GetRunners()
 	if err != nil {
-		log.Printf("⚠️ poll error (runners): %v", err)
+		logger.Warn("poll failed", "stage", "runners", "err", err)
 		return
 	}
 
-	log.Printf("📡 Poll: queued=%d | total_runners=%d | idle=%d", queued, total, idle)
+	logger.Debug("poll", "queued", queued, "runners", total, "idle", idle)
 
 	// compute how many more runners to spawn
 	need := queued - idle
@@ -112,16 +130,24 @@ func processOnce() {
 				toRemove = maxScaleStep
 			}
 			if toRemove > 0 {
-				go scaleDown(toRemove)
+				go func() {
+					if n := scaleDown("poller", toRemove); n > 0 {
+						events.Publish(events.ScaleDown, "poller", map[string]int{"count": n, "queued": queued, "idle": idle})
+						observeScale("poller", "down", n)
+					}
+				}()
+				return
 			}
 		}
+		observeScale("poller", "hold", 0)
 		return
 	}
 
 	// respect global max runners
 	remainingCapacity := globalMaxRunners - total
 	if remainingCapacity <= 0 {
-		log.Printf("⚠️ cannot scale up: reached global max %d", globalMaxRunners)
+		logger.Warn("cannot scale up, max_runners_total reached", "max_runners_total", globalMaxRunners, "queued", queued)
+		observeScale("poller", "hold", 0)
 		return
 	}
 	if need > remainingCapacity {
@@ -131,16 +157,28 @@ func processOnce() {
 		need = maxScaleStep
 	}
 
-	log.Printf("🧩 Scaling up: need=%d", need)
-	if spawnMethod == "gcp_mig" {
-		if err := scaleUpViaGCP(need); err != nil {
-			log.Printf("❌ scaleUpViaGCP failed: %v", err)
-		}
-	} else {
-		if err := spawnLocalRunners(need); err != nil {
-			log.Printf("❌ spawnLocalRunners failed: %v", err)
-		}
+	logger.Info("scaling up", "count", need, "queued", queued, "idle", idle)
+	events.Publish(events.ScaleUp, "poller", map[string]int{"count": need, "queued": queued, "idle": idle})
+	observeScale("poller", "up", need)
+	if err := scaleUp("poller", need); err != nil {
+		ScaleErrors.WithLabelValues("poller").Inc()
+		logger.Error("scale up failed", "count", need, "err", err)
+	}
+}
+
+// scaleUp menambah n runner lewat spawn method yang dikonfigurasi; setiap
+// panggilan ke provider dicatat di audit log atas nama by (poller atau operator)
+func scaleUp(by string, n int) (err error) {
+	spawn := currentSettings().Spawn
+	params := map[string]interface{}{"count": n, "method": "local"}
+	defer func() { audit.Record(by, audit.ProviderResize, spawn.GCPMigName, params, err) }()
+
+	if spawn.Method == "gcp_mig" {
+		params["method"], params["project"] = spawn.Method, spawn.GCPProject
+		return scaleUpViaGCP(n)
 	}
+	params["endpoint"] = spawn.AgentRegistrationEndpoint
+	return spawnLocalRunners(n)
 }
 
 // spawnLocalRunners — instruct existing agent manager / launcher to create new runner instances
@@ -150,7 +188,7 @@ func spawnLocalRunners(n int) error {
 	for i := 0; i < n; i++ {
 		payload := map[string]string{"action": "spawn"}
 		b, _ := json.Marshal(payload)
-		resp, err := httpPost(agentRegisterURL, "application/json", b)
+		resp, err := httpPost(currentSettings().Spawn.AgentRegistrationEndpoint, "application/json", b)
 		if err != nil {
 			return err
 		}
@@ -163,21 +201,21 @@ func spawnLocalRunners(n int) error {
 func httpPost(url, contentType string, body []byte) (*http.Response, error) {
 	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", contentType)
-	client := &http.Client{Timeout: 10 * time.Second}
-	return client.Do(req)
+	return outboundClient.Do(req)
 }
 
 // scaleUpViaGCP uses gcloud CLI as a quick placeholder (recommended: replace with GCP Compute API)
 func scaleUpViaGCP(n int) error {
-	if gcloudProject == "" || gcpMigName == "" {
+	spawn := currentSettings().Spawn
+	if spawn.GCPProject == "" || spawn.GCPMigName == "" {
 		return fmt.Errorf("gcloud project or MIG not set")
 	}
 	// Example: gcloud compute instance-groups managed resize NAME --size=NEW_SIZE --project=PROJECT
 	// This is a placeholder: you must compute new size (current+ n) and call CLI
 	cmd := exec.Command("gcloud",
 		"compute", "instance-groups", "managed", "resize",
-		gcpMigName,
-		"--project", gcloudProject,
+		spawn.GCPMigName,
+		"--project", spawn.GCPProject,
 		"--zone", "asia-southeast1-a",
 		"--size", fmt.Sprintf("%d", n),
 	)
@@ -188,9 +226,42 @@ func scaleUpViaGCP(n int) error {
 	return nil
 }
 
-func scaleDown(n int) {
-	// simple placeholder: remove idle runners via GitHub API (we expect agentd to detect VM idle and shutdown)
-	// Option: call GitHub to list idle runners and delete first n via API
-	log.Printf("🧹 scaleDown requested: %d", n)
-	// TODO: implement deletion logic with caution and safety (drain, unregister)
+// scaleDown melepas sampai n runner towerd yang idle: runner di-drain dulu
+// (tidak menerima job baru) lalu dihapus dari towerd dan GitHub. Runner milik
+// agentd tidak disentuh; agentd mengecilkan pool-nya sendiri. Mengembalikan
+// jumlah runner yang benar-benar dihapus.
+func scaleDown(by string, n int) int {
+	removed := 0
+	for _, id := range drainIdleRunners(n) {
+		if err := RemoveRunner(id, by); err != nil {
+			logger.Warn("scale down: cannot remove runner", "runner_id", id, "err", err)
+			continue
+		}
+		removed++
+	}
+	logger.Info("scaled down", "requested", n, "removed", removed)
+	return removed
+}
+
+// drainIdleRunners menandai sampai n runner idle sebagai draining dalam satu
+// lock, sehingga dispatcher tidak sempat mengirim job ke runner yang akan dihapus
+func drainIdleRunners(n int) []string {
+	runnersMu.Lock()
+	defer runnersMu.Unlock()
+
+	var ids []string
+	for id, rn := range runners {
+		if len(ids) == n {
+			break
+		}
+		if rn.IsBusy {
+			continue
+		}
+		if !rn.Draining {
+			rn.Draining = true
+			events.Publish(events.RunnerDraining, id, nil)
+		}
+		ids = append(ids, id)
+	}
+	return ids
 }
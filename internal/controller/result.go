@@ -2,9 +2,14 @@ package controller
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ridwandwisiswanto/tcr/internal/auth"
+	"github.com/ridwandwisiswanto/tcr/internal/core"
 )
 
 type JobResult struct {
@@ -27,12 +32,40 @@ func ResultHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// span result masuk ke trace job; request runner (yang mungkin membawa
+	// traceparent sendiri) ditautkan sebagai link
+	jobQueueMu.Lock()
+	var job core.Job
+	j := findJobLocked(res.ID)
+	if j != nil {
+		job = *j
+	}
+	jobQueueMu.Unlock()
+
+	// hasil hanya boleh dilaporkan runner yang menerima job itu (atau admin)
+	if c, ok := auth.FromRequest(r); ok && c.Role != auth.RoleAdmin {
+		if c.Role != auth.RoleRunner || j == nil || job.RunnerID != c.Subject || res.RunnerID != c.Subject {
+			logger.Warn("job result rejected", "job_id", res.ID, "runner_id", res.RunnerID, "subject", c.Subject, "role", c.Role)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+	job.ID = res.ID
+	_, span := startJobSpan(job, "job.result",
+		trace.WithLinks(trace.LinkFromContext(r.Context())),
+		trace.WithAttributes(
+			attribute.String("tcr.job.status", res.Status),
+			attribute.String("tcr.runner.id", res.RunnerID),
+		))
+	defer span.End()
+
 	UpdateJobStatus(res.ID, res.Status)
 
-	if res.Status == "success" || res.Status == "failed" {
+	// "cancelled" = runner selesai membatalkan job (lihat CancelJob)
+	if res.Status == "success" || res.Status == "failed" || res.Status == "cancelled" {
 		MarkRunnerBusy(res.RunnerID, false)
 		UpdateJobStatus(res.ID, "done")
-		log.Printf("✅ Job %s fully completed and marked done", res.ID)
+		jobLog(job).Info("job completed", "status", res.Status, "runner_id", res.RunnerID)
 
 		go TriggerNextJob() // langsung trigger job berikutnya
 	}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/ridwandwisiswanto/tcr/internal/audit"
+	"github.com/ridwandwisiswanto/tcr/internal/github"
+)
+
+// Rollout holds the desired runner version towerd wants every agent to run,
+// and how aggressively agents may be upgraded to it.
+type Rollout struct {
+	DesiredVersion  string `json:"desired_version"`
+	PreviousVersion string `json:"previous_version,omitempty"`
+	BatchSize       int    `json:"batch_size"`
+	MaxAgents       int    `json:"max_agents"`
+	Status          string `json:"status"` // idle | rolling | rolled_back
+}
+
+var (
+	rollout   = Rollout{Status: "idle"}
+	rolloutMu sync.Mutex
+)
+
+// SetDesiredRunnerVersion starts a rolling upgrade (or downgrade) to version;
+// by is recorded in the audit log.
+func SetDesiredRunnerVersion(version, by string) {
+	rolloutMu.Lock()
+	defer rolloutMu.Unlock()
+
+	if version == "" || version == rollout.DesiredVersion {
+		return
+	}
+	audit.Record(by, audit.RolloutVersion, version, map[string]interface{}{"from": rollout.DesiredVersion}, nil)
+	rollout.PreviousVersion = rollout.DesiredVersion
+	rollout.DesiredVersion = version
+	rollout.Status = "rolling"
+	logger.Info("desired runner version changed", "from", rollout.PreviousVersion, "to", version)
+}
+
+// GetRollout returns a copy of the current rollout state.
+func GetRollout() Rollout {
+	rolloutMu.Lock()
+	defer rolloutMu.Unlock()
+	return rollout
+}
+
+// directiveFor decides what an agent should do after a heartbeat: which
+// version it should run and whether it may start upgrading now.
+// Caller must hold mu (agents map).
+func directiveFor(a *Agent, cached []string, failed string) map[string]interface{} {
+	rolloutMu.Lock()
+	defer rolloutMu.Unlock()
+
+	// an agent failed to bring new runners online → roll the fleet back
+	if failed != "" && failed == rollout.DesiredVersion && rollout.PreviousVersion != "" {
+		logger.Warn("agent failed runner upgrade, rolling back", "agent_id", a.ID, "failed_version", failed, "version", rollout.PreviousVersion)
+		rollout.DesiredVersion, rollout.PreviousVersion = rollout.PreviousVersion, failed
+		rollout.Status = "rolled_back"
+	}
+
+	upgrade := false
+	if a.RunnerVersion != "" && a.RunnerVersion != rollout.DesiredVersion && containsString(cached, rollout.DesiredVersion) {
+		upgrading := 0
+		for _, other := range agents {
+			if other.State == "upgrading" {
+				upgrading++
+			}
+		}
+		if a.State == "upgrading" || upgrading < rollout.MaxAgents {
+			upgrade = true
+			// reserve the slot right away so two agents don't start at once
+			a.State = "upgrading"
+		}
+	}
+
+	if rollout.Status == "rolling" && allAgentsAt(rollout.DesiredVersion) {
+		rollout.Status = "idle"
+		logger.Info("runner rollout complete", "version", rollout.DesiredVersion)
+	}
+
+	// dirty runners get replaced with clean instances, but not mid-upgrade
+	replace := []string{}
+	if !upgrade && a.State == "running" {
+		replace = append(replace, a.DirtyRunners...)
+	}
+
+	return map[string]interface{}{
+		"desired_version": rollout.DesiredVersion,
+		"upgrade":         upgrade,
+		"batch_size":      rollout.BatchSize,
+		"replace_runners": replace,
+		"drain":           a.DrainRequested,
+	}
+}
+
+func allAgentsAt(version string) bool {
+	for _, a := range agents {
+		if a.IsActive && a.RunnerVersion != version {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterRolloutRoutes loads the rollout settings from config and exposes
+// /runner-version (GET state, POST {"version": "x.y.z"})
+func RegisterRolloutRoutes() {
+	rolloutMu.Lock()
+	rollout.DesiredVersion = github.RunnerVersion()
+	rollout.BatchSize = currentSettings().Rollout.BatchSize
+	rollout.MaxAgents = currentSettings().Rollout.MaxAgents
+	rolloutMu.Unlock()
+
+	http.HandleFunc("/runner-version", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var body struct {
+				Version string `json:"version"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Version == "" {
+				http.Error(w, "missing version", http.StatusBadRequest)
+				return
+			}
+			SetDesiredRunnerVersion(body.Version, Actor(r))
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GetRollout())
+	})
+}
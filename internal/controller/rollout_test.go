@@ -0,0 +1,57 @@
+package controller
+
+import "testing"
+
+func TestDirectiveFor_ReservesUpgradeSlotsAndRollsBack(t *testing.T) {
+	rolloutMu.Lock()
+	prev := rollout
+	rollout = Rollout{DesiredVersion: "1.0.0", BatchSize: 1, MaxAgents: 1, Status: "idle"}
+	rolloutMu.Unlock()
+	defer func() {
+		rolloutMu.Lock()
+		rollout = prev
+		rolloutMu.Unlock()
+	}()
+
+	mu.Lock()
+	defer mu.Unlock()
+	a1 := &Agent{ID: "ro-1", IsActive: true, State: "running", RunnerVersion: "1.0.0"}
+	a2 := &Agent{ID: "ro-2", IsActive: true, State: "running", RunnerVersion: "1.0.0"}
+	agents[a1.ID], agents[a2.ID] = a1, a2
+	defer func() {
+		delete(agents, a1.ID)
+		delete(agents, a2.ID)
+	}()
+
+	SetDesiredRunnerVersion("2.0.0", "admin")
+	cached := []string{"1.0.0", "2.0.0"}
+
+	// max_agents 1: agent pertama mendapat slot, yang kedua menunggu
+	if d := directiveFor(a1, cached, ""); d["upgrade"] != true || a1.State != "upgrading" {
+		t.Fatalf("first agent not allowed to upgrade: %v state=%s", d, a1.State)
+	}
+	if d := directiveFor(a2, cached, ""); d["upgrade"] != false {
+		t.Fatalf("second agent upgraded while the slot is taken: %v", d)
+	}
+	// heartbeat berikutnya dari pemegang slot tetap boleh lanjut
+	if d := directiveFor(a1, cached, ""); d["upgrade"] != true {
+		t.Fatalf("slot holder lost its slot: %v", d)
+	}
+	// versi belum di-cache: agent hanya diminta prefetch
+	a1.State = "running"
+	if d := directiveFor(a2, []string{"1.0.0"}, ""); d["upgrade"] != false || d["desired_version"] != "2.0.0" {
+		t.Fatalf("agent without the cached version told to upgrade: %v", d)
+	}
+
+	// upgrade gagal di satu agent: seluruh fleet kembali ke versi lama
+	d := directiveFor(a1, cached, "2.0.0")
+	if d["desired_version"] != "1.0.0" || d["upgrade"] != false {
+		t.Fatalf("expected rollback directive, got %v", d)
+	}
+	if r := rollout; r.Status != "rolled_back" || r.PreviousVersion != "2.0.0" {
+		t.Fatalf("unexpected rollout after failure: %+v", r)
+	}
+	if d := directiveFor(a2, cached, ""); d["desired_version"] != "1.0.0" || d["upgrade"] != false {
+		t.Fatalf("other agents not rolled back: %v", d)
+	}
+}
@@ -2,11 +2,13 @@ package controller
 
 import (
 	"encoding/json"
-	"log"
 	"net"
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/auth"
+	"github.com/ridwandwisiswanto/tcr/internal/events"
 )
 
 type Runner struct {
@@ -15,6 +17,9 @@ type Runner struct {
 	Port     string    `json:"port"`
 	LastSeen time.Time `json:"last_seen"`
 	IsBusy   bool      `json:"is_busy"`
+
+	// runner yang di-drain tidak menerima job baru (lihat /runners/drain)
+	Draining bool `json:"draining"`
 }
 
 var (
@@ -34,10 +39,17 @@ func HeartbeatHandler(w http.ResponseWriter, r *http.Request) {
 		port = "8081" // default runner port
 	}
 
+	// hanya runner atas namanya sendiri (atau admin); agent tidak boleh
+	// mendaftarkan runner lalu menerima job yang di-dispatch
+	if c, ok := auth.FromRequest(r); ok && c.Role != auth.RoleAdmin && (c.Role != auth.RoleRunner || c.Subject != id) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
 	// ambil hanya IP dari RemoteAddr (tanpa port ephemeral)
 	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		log.Printf("⚠️ Cannot parse host from RemoteAddr: %v", err)
+		logger.Warn("cannot parse runner host", "remote_addr", r.RemoteAddr, "err", err)
 		host = "localhost"
 	}
 
@@ -54,7 +66,8 @@ func HeartbeatHandler(w http.ResponseWriter, r *http.Request) {
 			Port:     port,
 			LastSeen: time.Now(),
 		}
-		log.Printf("🟢 Runner registered: %s (%s:%s)", id, host, port)
+		logger.Info("runner registered", "runner_id", id, "address", host, "port", port)
+		events.Publish(events.RunnerRegistered, id, runners[id])
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -66,7 +79,7 @@ func GetIdleRunner() *Runner {
 	defer runnersMu.Unlock()
 
 	for _, r := range runners {
-		if !r.IsBusy && time.Since(r.LastSeen) < 30*time.Second {
+		if !r.IsBusy && !r.Draining && time.Since(r.LastSeen) < 30*time.Second {
 			return r
 		}
 	}
@@ -82,9 +95,11 @@ func MarkRunnerBusy(id string, busy bool) {
 	if r, ok := runners[id]; ok {
 		r.IsBusy = busy
 		if !busy {
-			log.Printf("🟢 Runner %s is now idle", id)
+			logger.Debug("runner idle", "runner_id", id)
+			events.Publish(events.RunnerIdle, id, nil)
 		} else {
-			log.Printf("🔴 Runner %s marked busy", id)
+			logger.Debug("runner busy", "runner_id", id)
+			events.Publish(events.RunnerBusy, id, nil)
 		}
 	}
 }
@@ -99,3 +114,15 @@ func RegisterRunnerRoutes() {
 		json.NewEncoder(w).Encode(runners)
 	})
 }
+
+// ListRunners returns a copy of every runner registered with towerd
+func ListRunners() []Runner {
+	runnersMu.Lock()
+	defer runnersMu.Unlock()
+
+	out := make([]Runner, 0, len(runners))
+	for _, r := range runners {
+		out = append(out, *r)
+	}
+	return out
+}
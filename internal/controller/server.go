@@ -2,17 +2,55 @@ package controller
 
 import (
 	"encoding/json"
-	"log"
+	"net"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 	"sync"
 	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/auth"
+	"github.com/ridwandwisiswanto/tcr/internal/events"
+	"github.com/ridwandwisiswanto/tcr/internal/logging"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
 )
 
 type Agent struct {
-	ID       string
-	Address  string
-	LastSeen time.Time
-	IsActive bool
+	ID       string    `json:"id"`
+	Address  string    `json:"address"`
+	LastSeen time.Time `json:"last_seen"`
+	IsActive bool      `json:"is_active"`
+	Runners  int       `json:"runners"`
+	State    string    `json:"state"`
+
+	// nama runner GitHub di VM ini; dipakai untuk membatalkan job di agentd
+	RunnerNames []string `json:"runner_names"`
+
+	RunnerVersion  string   `json:"runner_version"`
+	CachedVersions []string `json:"cached_versions"`
+
+	DiskUsedPercent float64  `json:"disk_used_percent"`
+	DiskPressure    bool     `json:"disk_pressure"`
+	DirtyRunners    []string `json:"dirty_runners"`
+
+	// cgroup usage per runner name, as reported by agentd
+	RunnerUsage map[string]RunnerUsage `json:"runner_usage"`
+
+	// port of agentd's own API (runner logs)
+	APIPort string `json:"api_port"`
+
+	// set by an operator (/agents/drain); sent to agentd in the next directive
+	DrainRequested bool `json:"drain_requested"`
+
+	// runner labels of this VM (RUNNER_LABELS), used to group agents into pools
+	Labels []string `json:"labels"`
+}
+
+type RunnerUsage struct {
+	MemoryMB int64 `json:"memory_mb"`
+	CPUSec   int64 `json:"cpu_sec"`
+	Pids     int64 `json:"pids"`
+	OOMKills int64 `json:"oom_kills"`
 }
 
 var (
@@ -26,8 +64,8 @@ func StartServer(addr string) {
 
 	go monitorAgents()
 
-	log.Printf("Towerd listening on %s\n", addr)
-	log.Fatal(http.ListenAndServe(addr, nil))
+	logger.Info("towerd listening", "addr", addr)
+	logging.Fatal(logger, "towerd stopped", "err", http.ListenAndServe(addr, nil))
 }
 
 func handleHeartbeat(w http.ResponseWriter, r *http.Request) {
@@ -46,12 +84,138 @@ func handleHeartbeat(w http.ResponseWriter, r *http.Request) {
 		a.IsActive = true
 	} else {
 		agents[id] = &Agent{ID: id, Address: ip, LastSeen: time.Now(), IsActive: true}
-		log.Printf("🆕 Registered new agent: %s (%s)", id, ip)
+		logger.Info("agent registered", "agent_id", id, "address", ip)
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleVMHeartbeat menerima heartbeat (dan state akhir saat drain) dari agentd
+func handleVMHeartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var hb struct {
+		Instance       string   `json:"instance"`
+		Runners        int      `json:"runners"`
+		RunnerNames    []string `json:"runner_names"`
+		State          string   `json:"state"`
+		RunnerVersion  string   `json:"runner_version"`
+		CachedVersions []string `json:"cached_versions"`
+		UpgradeFailed  string   `json:"upgrade_failed"`
+		Disk           struct {
+			UsedPercent  float64  `json:"used_percent"`
+			Pressure     bool     `json:"pressure"`
+			DirtyRunners []string `json:"dirty_runners"`
+		} `json:"disk"`
+		Cgroups map[string]RunnerUsage `json:"cgroups"`
+		APIPort string                 `json:"api_port"`
+		Labels  []string               `json:"labels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&hb); err != nil || hb.Instance == "" {
+		http.Error(w, "invalid heartbeat", http.StatusBadRequest)
+		return
+	}
+
+	// hanya agent (atas namanya sendiri) atau admin; runner tidak boleh
+	// mengirim heartbeat VM, termasuk upgrade_failed yang memicu rollback
+	if c, ok := auth.FromRequest(r); ok && c.Role != auth.RoleAdmin && (c.Role != auth.RoleAgent || c.Subject != hb.Instance) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	a, ok := agents[hb.Instance]
+	if !ok {
+		a = &Agent{ID: hb.Instance, Address: r.RemoteAddr}
+		agents[hb.Instance] = a
+		logger.Info("agent registered", "agent_id", hb.Instance, "address", r.RemoteAddr)
+		events.Publish(events.AgentRegistered, hb.Instance, map[string]string{"address": r.RemoteAddr})
+	}
+	if a.State != hb.State && hb.State != "" {
+		logger.Info("agent state changed", "agent_id", a.ID, "from", a.State, "to", hb.State)
+		events.Publish(events.AgentState, a.ID, map[string]string{"from": a.State, "to": hb.State})
+	}
+	a.LastSeen = time.Now()
+	a.Runners = hb.Runners
+	a.RunnerNames = hb.RunnerNames
+	a.State = hb.State
+	a.IsActive = hb.State != "stopped"
+	a.RunnerVersion = hb.RunnerVersion
+	a.CachedVersions = hb.CachedVersions
+	a.DiskUsedPercent = hb.Disk.UsedPercent
+	a.DiskPressure = hb.Disk.Pressure
+	a.DirtyRunners = hb.Disk.DirtyRunners
+	for name, u := range hb.Cgroups {
+		if prev, ok := a.RunnerUsage[name]; ok && u.OOMKills > prev.OOMKills {
+			logger.Warn("runner OOM killed", "agent_id", a.ID, "runner_id", name, "oom_kills", u.OOMKills)
+		}
+	}
+	a.RunnerUsage = hb.Cgroups
+	a.APIPort = hb.APIPort
+	a.Labels = hb.Labels
+	// agentd menaruh workspace terbesar di DirtyRunners selama pressure;
+	// directiveFor meminta runner itu diganti dengan instance bersih
+	if hb.Disk.Pressure {
+		logger.Warn("agent under disk pressure, replacing largest workspaces", "agent_id", a.ID,
+			"disk_used_percent", hb.Disk.UsedPercent, "runners", hb.Disk.DirtyRunners)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(directiveFor(a, hb.CachedVersions, hb.UpgradeFailed))
+}
+
+// RegisterAgentRoutes untuk endpoint /vm/heartbeat dan /agents (dipakai agentd)
+func RegisterAgentRoutes() {
+	http.HandleFunc("/vm/heartbeat", handleVMHeartbeat)
+	http.HandleFunc("/agents", handleAgents)
+	http.HandleFunc("/agents/logs", handleAgentLogs)
+}
+
+// handleAgentLogs mem-proxy /logs dari agentd:
+// GET /agents/logs?agent=<id>&runner=<name>&tail=200&follow=true
+func handleAgentLogs(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("agent")
+
+	mu.Lock()
+	a, ok := agents[id]
+	var target string
+	if ok && a.APIPort != "" {
+		host, _, err := net.SplitHostPort(a.Address)
+		if err != nil {
+			host = a.Address
+		}
+		target = RunnerURL(host, a.APIPort, "")
+	}
+	mu.Unlock()
+
+	if target == "" {
+		http.Error(w, "unknown agent", http.StatusNotFound)
+		return
+	}
+
+	u, _ := url.Parse(target)
+	proxy := httputil.NewSingleHostReverseProxy(u)
+	proxy.FlushInterval = -1 // stream follow=true langsung ke client
+	proxy.Transport = OutboundTransport()
+	// credential operator (admin token, cookie dashboard) tidak ikut ke
+	// agentd; agentd memverifikasi tanda tangan towerd sebagai gantinya
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Header.Del("Authorization")
+		req.Header.Del("Cookie")
+		signOutbound(req)
+	}
+	r.URL.Path = "/logs"
+	tracing.Inject(r.Context(), r.Header) // agent melanjutkan span request towerd
+	proxy.ServeHTTP(w, r)
+}
+
 func handleAgents(w http.ResponseWriter, r *http.Request) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -66,7 +230,7 @@ func monitorAgents() {
 		mu.Lock()
 		for id, a := range agents {
 			if a.IsActive && now.Sub(a.LastSeen) > time.Minute {
-				log.Printf("⚠️ Agent %s idle > 1m, sending shutdown", id)
+				logger.Info("agent idle for over a minute, sending shutdown", "agent_id", id)
 				go sendShutdown(a)
 				a.IsActive = false
 			}
@@ -76,12 +240,24 @@ func monitorAgents() {
 }
 
 func sendShutdown(a *Agent) {
-	url := "http://" + a.Address + "/shutdown"
-	resp, err := http.Post(url, "application/json", nil)
+	url := outboundScheme + "://" + a.Address + "/shutdown"
+	resp, err := PostJSON(url, nil)
 	if err != nil {
-		log.Printf("❌ Failed to shutdown %s: %v", a.ID, err)
+		logger.Error("agent shutdown failed", "agent_id", a.ID, "err", err)
 		return
 	}
 	defer resp.Body.Close()
-	log.Printf("✅ Sent shutdown to %s", a.ID)
+	logger.Info("agent shutdown sent", "agent_id", a.ID)
+}
+
+// ListAgents returns a copy of every agent known to towerd
+func ListAgents() []Agent {
+	mu.Lock()
+	defer mu.Unlock()
+
+	out := make([]Agent, 0, len(agents))
+	for _, a := range agents {
+		out = append(out, *a)
+	}
+	return out
 }
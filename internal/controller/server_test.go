@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/auth"
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+)
+
+func TestHandleAgentLogs_DoesNotForwardOperatorCredentials(t *testing.T) {
+	var got http.Header
+	agentAPI := httptest.NewServer(auth.RequireTower(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}), "boot"))
+	defer agentAPI.Close()
+	host, port, _ := net.SplitHostPort(agentAPI.Listener.Addr().String())
+
+	ConfigureOutboundAuth("boot")
+	defer ConfigureOutboundAuth("")
+	mu.Lock()
+	agents["logs-vm"] = &Agent{ID: "logs-vm", Address: net.JoinHostPort(host, "40000"), APIPort: port}
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		delete(agents, "logs-vm")
+		mu.Unlock()
+	}()
+
+	req := httptest.NewRequest("GET", "/agents/logs?agent=logs-vm&runner=r1", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	req.Header.Set("Cookie", "session=x")
+	rec := httptest.NewRecorder()
+	handleAgentLogs(rec, req)
+
+	if rec.Code != http.StatusOK || got == nil {
+		t.Fatalf("expected signed request to reach agentd, got %d", rec.Code)
+	}
+	if got.Get("Authorization") != "" || got.Get("Cookie") != "" {
+		t.Fatalf("operator credentials forwarded to agentd: %v", got)
+	}
+}
+
+func TestClientEndpoints_OnlyAcceptTheirOwnIdentity(t *testing.T) {
+	srv := &auth.Server{Issuer: auth.NewIssuer("k"), AdminToken: "adm", TTL: time.Hour}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/vm/heartbeat", handleVMHeartbeat)
+	mux.HandleFunc("/heartbeat", HeartbeatHandler)
+	mux.HandleFunc("/job/result", ResultHandler)
+	h := srv.Protect(mux, auth.Policy{Clients: []string{"/vm/heartbeat", "/heartbeat", "/job/result"}})
+
+	agentTok, _ := srv.Issuer.Issue("id-vm", auth.RoleAgent, time.Hour)
+	runnerTok, _ := srv.Issuer.Issue("id-r1", auth.RoleRunner, time.Hour)
+	otherTok, _ := srv.Issuer.Issue("id-r2", auth.RoleRunner, time.Hour)
+	call := func(token, method, path, body string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	defer func() {
+		mu.Lock()
+		delete(agents, "id-vm")
+		mu.Unlock()
+		runnersMu.Lock()
+		delete(runners, "id-r1")
+		runnersMu.Unlock()
+	}()
+
+	AddJob(core.Job{ID: "id-job", Status: "running", RunnerID: "id-r1"})
+	cases := []struct {
+		name, token, method, path, body string
+		want                            int
+	}{
+		{"runner posing as agent", runnerTok, "POST", "/vm/heartbeat", `{"instance":"id-vm","upgrade_failed":"2.0.0"}`, http.StatusForbidden},
+		{"agent for another VM", agentTok, "POST", "/vm/heartbeat", `{"instance":"other-vm"}`, http.StatusForbidden},
+		{"agent heartbeat", agentTok, "POST", "/vm/heartbeat", `{"instance":"id-vm"}`, http.StatusOK},
+		{"agent posing as runner", agentTok, "GET", "/heartbeat?id=id-r1", "", http.StatusForbidden},
+		{"runner heartbeat", runnerTok, "GET", "/heartbeat?id=id-r1", "", http.StatusOK},
+		{"agent reporting a result", agentTok, "POST", "/job/result", `{"id":"id-job","status":"success","runner_id":"id-r1"}`, http.StatusForbidden},
+		{"result from another runner", otherTok, "POST", "/job/result", `{"id":"id-job","status":"success","runner_id":"id-r1"}`, http.StatusForbidden},
+		{"result for an unknown job", runnerTok, "POST", "/job/result", `{"id":"missing","status":"success","runner_id":"id-r1"}`, http.StatusForbidden},
+		{"result from the assigned runner", runnerTok, "POST", "/job/result", `{"id":"id-job","status":"running","runner_id":"id-r1"}`, http.StatusOK},
+		{"admin", "adm", "POST", "/job/result", `{"id":"id-job","status":"running","runner_id":"id-r1"}`, http.StatusOK},
+	}
+	for _, c := range cases {
+		if got := call(c.token, c.method, c.path, c.body); got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
+)
+
+// startJobSpan memulai span satu tahap job (enqueue, dispatch, result, ...)
+// di bawah trace job tersebut; job tanpa trace context memulai trace baru
+func startJobSpan(j core.Job, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ctx := tracing.FromCarrier(context.Background(), j.TraceContext)
+	repo, pool := jobLabels(j)
+	opts = append(opts, trace.WithAttributes(
+		attribute.String("tcr.job.id", j.ID),
+		attribute.String("tcr.job.name", j.JobName),
+		attribute.String("tcr.repo", repo),
+		attribute.String("tcr.pool", pool),
+	))
+	if j.RunID != 0 {
+		opts = append(opts, trace.WithAttributes(attribute.Int64("github.run_id", j.RunID)))
+	}
+	return tracing.Tracer().Start(ctx, name, opts...)
+}
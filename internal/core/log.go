@@ -0,0 +1,5 @@
+package core
+
+import "github.com/ridwandwisiswanto/tcr/internal/logging"
+
+var logger = logging.For("core")
@@ -0,0 +1,36 @@
+package core
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	intakeEnqueued = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tcr_job_intake_enqueued_total",
+		Help: "Jobs accepted into the intake buffer",
+	})
+	intakeRejected = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tcr_job_intake_rejected_total",
+		Help: "Jobs rejected because the intake buffer was full",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(intakeEnqueued, intakeRejected,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "tcr_job_intake_depth",
+			Help: "Webhook jobs accepted but not yet dispatched or cancelled",
+		}, func() float64 { return float64(JobQueue.Len()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "tcr_job_intake_capacity",
+			Help: "Configured intake buffer capacity",
+		}, func() float64 { return float64(JobQueue.Stats().Capacity) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "tcr_job_intake_overloaded",
+			Help: "1 while the intake depth is above the high watermark (alert on this)",
+		}, func() float64 {
+			if JobQueue.Stats().Overloaded {
+				return 1
+			}
+			return 0
+		}),
+	)
+}
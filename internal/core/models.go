@@ -2,12 +2,63 @@ package core
 
 import "time"
 
+// Job adalah workflow job GitHub yang diantrekan towerd. Tag JSON di sini
+// adalah kontrak payload ke runner (/job) dan endpoint lama /jobs.
 type Job struct {
-	ID        string
-	Action    string
-	RepoOwner string
-	RepoName  string
-	JobName   string
-	Status    string
-	CreatedAt time.Time
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`
+	RepoOwner string    `json:"repo_owner"`
+	RepoName  string    `json:"repo_name"`
+	JobName   string    `json:"job_name"`
+	Status    string    `json:"status"`
+	Labels    []string  `json:"labels"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// ID workflow run / job di GitHub (dari webhook workflow_job), dipakai
+	// untuk cancel-run
+	RunID       int64 `json:"run_id,omitempty"`
+	GitHubJobID int64 `json:"github_job_id,omitempty"`
+
+	// runner towerd tempat job terakhir di-dispatch
+	RunnerID string `json:"runner_id,omitempty"`
+
+	// waktu masuk antrean (diulang saat requeue), di-dispatch, dan selesai;
+	// dipakai untuk menghitung queue wait time
+	QueuedAt     time.Time `json:"queued_at"`
+	DispatchedAt time.Time `json:"dispatched_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+
+	// riwayat aksi operator (cancel, requeue)
+	Actions []JobAction `json:"actions,omitempty"`
+
+	// trace OpenTelemetry job: TraceID untuk mencari trace di collector,
+	// TraceContext (traceparent/tracestate) jadi parent span tiap tahap dan
+	// ikut di payload dispatch ke runner
+	TraceID      string            `json:"trace_id,omitempty"`
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+}
+
+// JobAction mencatat siapa yang membatalkan / mengantrekan ulang job
+type JobAction struct {
+	Action string    `json:"action"`
+	By     string    `json:"by"`
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Wait adalah lama job menunggu di antrean sampai di-dispatch (atau sampai
+// now kalau belum di-dispatch)
+func (j Job) Wait(now time.Time) time.Duration {
+	start := j.QueuedAt
+	if start.IsZero() {
+		start = j.CreatedAt
+	}
+	end := j.DispatchedAt
+	if end.IsZero() {
+		end = now
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
 }
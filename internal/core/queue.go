@@ -1,16 +1,278 @@
 package core
 
-import "log"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
-// Channel untuk komunikasi antar package tanpa import langsung
-var JobQueue = make(chan Job, 100)
+	"github.com/ridwandwisiswanto/tcr/internal/events"
+)
 
-// AddJob = enqueue job ke global channel
-func AddJob(j Job) {
-	select {
-	case JobQueue <- j:
-		log.Printf("📥 Enqueued job: %s (%s/%s)", j.JobName, j.RepoOwner, j.RepoName)
+// ErrQueueFull: intake sudah berisi Capacity job. Job tidak dibuang diam-diam;
+// pemanggil harus menolak (webhook membalas 503 + Retry-After).
+var ErrQueueFull = errors.New("job queue full")
+
+// IntakeStats adalah kondisi intake untuk API dan metrics
+type IntakeStats struct {
+	Depth         int     `json:"depth"` // job yang belum di-dispatch (belum di-Ack)
+	Capacity      int     `json:"capacity"`
+	HighWatermark float64 `json:"high_watermark"`
+	Overloaded    bool    `json:"overloaded"`
+	Durable       bool    `json:"durable"`
+	Rejected      uint64  `json:"rejected"`
+}
+
+// Intake adalah buffer FIFO job dari webhook ke scheduler controller
+// (komunikasi antar package tanpa import langsung). Dengan Dir, tiap job
+// ditulis ke file sebelum Put kembali dan baru dihapus setelah Ack (job
+// di-dispatch atau dibatalkan), sehingga job yang belum di-dispatch selamat
+// dari restart dan backlog scheduler ikut dihitung di Capacity.
+//
+// Kalau depth melewati HighWatermark × Capacity, event queue.pressure
+// dipublikasikan; queue.recovered setelah turun ke separuhnya.
+type Intake struct {
+	Dir string
+
+	mu         sync.Mutex
+	ready      *sync.Cond
+	capacity   int
+	high       float64
+	pending    []intakeItem
+	inflight   map[string][]string // job ID → file, bisa lebih dari satu kalau ID kembar
+	seq        uint64
+	overloaded bool
+	rejected   uint64
+}
+
+type intakeItem struct {
+	job  Job
+	file string
+}
+
+// JobQueue diisi webhook dan dikosongkan controller.StartJobQueueListener;
+// towerd menggantinya dengan intake berbasis file dari config
+var JobQueue = NewIntake(100, 0.8)
+
+// NewIntake membuat intake di memori saja
+func NewIntake(capacity int, highWatermark float64) *Intake {
+	q := &Intake{capacity: capacity, high: highWatermark, inflight: map[string][]string{}}
+	q.ready = sync.NewCond(&q.mu)
+	return q
+}
+
+// OpenIntake memuat job yang belum di-Ack dari dir, urut seperti saat masuk.
+// Job lama tetap dimuat walaupun melebihi capacity.
+func OpenIntake(dir string, capacity int, highWatermark float64) (*Intake, error) {
+	q := NewIntake(capacity, highWatermark)
+	q.Dir = dir
+	if dir == "" {
+		return q, nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("intake: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(f), ".json"), 10, 64)
+		if err != nil {
+			continue
+		}
+		var j Job
+		b, err := os.ReadFile(f)
+		if err == nil {
+			err = json.Unmarshal(b, &j)
+		}
+		if err != nil {
+			logger.Warn("skipping unreadable intake job", "path", f, "err", err)
+			continue
+		}
+		q.pending = append(q.pending, intakeItem{job: j, file: f})
+		if seq > q.seq {
+			q.seq = seq
+		}
+	}
+	if len(q.pending) > 0 {
+		logger.Info("intake jobs restored", "count", len(q.pending), "dir", dir)
+	}
+	return q, nil
+}
+
+// Put menambahkan job ke intake. ErrQueueFull kalau penuh; error lain kalau
+// job tidak bisa ditulis ke Dir.
+func (q *Intake) Put(j Job) error {
+	q.mu.Lock()
+	if q.depth() >= q.capacity {
+		q.rejected++
+		q.mu.Unlock()
+		intakeRejected.Inc()
+		logger.Warn("queue full, rejecting job", "job_id", j.ID, "job", j.JobName, "repo", j.RepoOwner+"/"+j.RepoName, "capacity", q.capacity)
+		return ErrQueueFull
+	}
+
+	item := intakeItem{job: j}
+	if q.Dir != "" {
+		q.seq++
+		item.file = filepath.Join(q.Dir, fmt.Sprintf("%020d.json", q.seq))
+		if err := writeJob(item.file, j); err != nil {
+			q.mu.Unlock()
+			logger.Error("cannot persist intake job", "job_id", j.ID, "err", err)
+			return err
+		}
+	}
+	q.pending = append(q.pending, item)
+	q.ready.Signal()
+	changed, stats := q.checkPressure()
+	q.mu.Unlock()
+
+	intakeEnqueued.Inc()
+	logger.Debug("job enqueued", "job_id", j.ID, "job", j.JobName, "repo", j.RepoOwner+"/"+j.RepoName)
+	if changed {
+		publishPressure(stats)
+	}
+	return nil
+}
+
+// Take menunggu dan mengambil job terlama. Job tetap dihitung di depth (dan
+// tetap ada di Dir) sampai pemanggil Ack, yaitu saat job tidak lagi menunggu.
+func (q *Intake) Take() Job {
+	q.mu.Lock()
+	for len(q.pending) == 0 {
+		q.ready.Wait()
+	}
+	item := q.pending[0]
+	q.pending = q.pending[1:]
+	q.inflight[item.job.ID] = append(q.inflight[item.job.ID], item.file)
+	q.mu.Unlock()
+	return item.job
+}
+
+// Ack menandai job hasil Take tidak lagi menunggu dan menghapus file-nya
+func (q *Intake) Ack(id string) {
+	q.mu.Lock()
+	files := q.inflight[id]
+	if len(files) == 0 {
+		q.mu.Unlock()
+		return
+	}
+	file := files[0]
+	if len(files) == 1 {
+		delete(q.inflight, id)
+	} else {
+		q.inflight[id] = files[1:]
+	}
+	changed, stats := q.checkPressure()
+	q.mu.Unlock()
+
+	if file != "" {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			logger.Warn("cannot remove intake job", "job_id", id, "path", file, "err", err)
+		}
+	}
+	if changed {
+		publishPressure(stats)
+	}
+}
+
+// SetLimits mengganti capacity dan high watermark (hot-reload). Job yang
+// sudah ada tidak dibuang walaupun melebihi capacity baru.
+func (q *Intake) SetLimits(capacity int, highWatermark float64) {
+	q.mu.Lock()
+	q.capacity, q.high = capacity, highWatermark
+	changed, stats := q.checkPressure()
+	q.mu.Unlock()
+	if changed {
+		publishPressure(stats)
+	}
+}
+
+// Len adalah jumlah job yang belum di-Ack (backlog sebenarnya)
+func (q *Intake) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.depth()
+}
+
+func (q *Intake) Stats() IntakeStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.stats()
+}
+
+func (q *Intake) stats() IntakeStats {
+	return IntakeStats{
+		Depth:         q.depth(),
+		Capacity:      q.capacity,
+		HighWatermark: q.high,
+		Overloaded:    q.overloaded,
+		Durable:       q.Dir != "",
+		Rejected:      q.rejected,
+	}
+}
+
+func (q *Intake) depth() int {
+	n := len(q.pending)
+	for _, files := range q.inflight {
+		n += len(files)
+	}
+	return n
+}
+
+// checkPressure memperbarui status overloaded dengan hysteresis; caller
+// memegang mu. changed true kalau status berubah.
+func (q *Intake) checkPressure() (changed bool, stats IntakeStats) {
+	mark := q.high * float64(q.capacity)
+	depth := float64(q.depth())
+	switch {
+	case !q.overloaded && depth >= mark:
+		q.overloaded = true
+	case q.overloaded && depth <= mark/2:
+		q.overloaded = false
 	default:
-		log.Printf("⚠️ Queue full, dropping job: %s", j.JobName)
+		return false, IntakeStats{}
+	}
+	return true, q.stats()
+}
+
+func publishPressure(s IntakeStats) {
+	if s.Overloaded {
+		logger.Warn("job queue above high watermark", "depth", s.Depth, "capacity", s.Capacity, "high_watermark", s.HighWatermark)
+		events.Publish(events.QueuePressure, "intake", s)
+		return
 	}
+	logger.Info("job queue recovered", "depth", s.Depth, "capacity", s.Capacity)
+	events.Publish(events.QueueRecovered, "intake", s)
+}
+
+// writeJob menulis job secara atomik lewat rename
+func writeJob(path string, j Job) error {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// AddJob = enqueue job ke JobQueue. Error (ErrQueueFull atau gagal tulis)
+// berarti job tidak masuk; pemanggil (webhook) menolak delivery-nya supaya
+// bisa dikirim ulang / di-replay.
+func AddJob(j Job) error {
+	return JobQueue.Put(j)
 }
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ridwandwisiswanto/tcr/internal/events"
+)
+
+func TestIntake_RejectsWhenFullAndSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenIntake(dir, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := q.Put(Job{ID: id}); err != nil {
+			t.Fatalf("put %s: %v", id, err)
+		}
+	}
+	if err := q.Put(Job{ID: "c"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if s := q.Stats(); s.Depth != 2 || s.Rejected != 1 || !s.Durable {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	// "a" diambil tapi belum di-Ack: setelah restart harus muncul lagi
+	if j := q.Take(); j.ID != "a" {
+		t.Fatalf("expected FIFO order, got %s", j.ID)
+	}
+	q2, err := OpenIntake(dir, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j := q2.Take(); j.ID != "a" {
+		t.Fatalf("expected un-acked job a after restart, got %s", j.ID)
+	}
+	q2.Ack("a")
+	if j := q2.Take(); j.ID != "b" {
+		t.Fatalf("expected b, got %s", j.ID)
+	}
+	q2.Ack("b")
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 0 {
+		t.Fatalf("acked jobs still on disk: %v", files)
+	}
+
+	// nomor urut berlanjut, file lama tidak tertimpa
+	if err := q2.Put(Job{ID: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	q3, _ := OpenIntake(dir, 2, 1)
+	if j := q3.Take(); j.ID != "d" || q3.Len() != 1 {
+		t.Fatalf("unexpected job %s (depth %d)", j.ID, q3.Len())
+	}
+}
+
+func TestIntake_PressureEvents(t *testing.T) {
+	sub := events.Subscribe(events.ParseFilter("queue.*", ""), 4)
+	defer sub.Close()
+
+	q := NewIntake(8, 0.5)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		q.Put(Job{ID: id})
+	}
+	if !q.Stats().Overloaded {
+		t.Fatalf("expected overloaded at the high watermark")
+	}
+	if e := <-sub.C; e.Type != events.QueuePressure {
+		t.Fatalf("expected %s, got %s", events.QueuePressure, e.Type)
+	}
+
+	// hysteresis: baru pulih di separuh watermark
+	q.Ack(q.Take().ID)
+	if !q.Stats().Overloaded {
+		t.Fatalf("recovered too early")
+	}
+	q.Ack(q.Take().ID)
+	if q.Stats().Overloaded {
+		t.Fatalf("expected recovery when drained")
+	}
+	if e := <-sub.C; e.Type != events.QueueRecovered {
+		t.Fatalf("expected %s, got %s", events.QueueRecovered, e.Type)
+	}
+
+	// capacity baru berlaku tanpa membuang job
+	q.SetLimits(1, 1)
+	if err := q.Put(Job{ID: "5"}); !errors.Is(err, ErrQueueFull) || q.Len() != 2 {
+		t.Fatalf("expected full after shrinking capacity, got %v (depth %d)", err, q.Len())
+	}
+}
@@ -0,0 +1,31 @@
+// Package dashboard menyajikan web dashboard towerd: HTML/JS statis (tanpa
+// build step) yang di-embed ke binary. Semua data diambil dari /api/v1 dengan
+// admin token yang dimasukkan operator di browser.
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// Prefix adalah path tempat dashboard dipasang di towerd
+const Prefix = "/ui/"
+
+//go:embed web
+var files embed.FS
+
+// Handler menyajikan asset dashboard di bawah Prefix
+func Handler() http.Handler {
+	web, err := fs.Sub(files, "web")
+	if err != nil {
+		panic(err) // hanya terjadi kalau direktori embed berubah nama
+	}
+	fileServer := http.StripPrefix(Prefix, http.FileServer(http.FS(web)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// asset kecil dan ikut versi binary; selalu revalidasi supaya upgrade towerd langsung terlihat
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		fileServer.ServeHTTP(w, r)
+	})
+}
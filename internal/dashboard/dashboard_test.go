@@ -0,0 +1,38 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerServesEmbeddedAssets(t *testing.T) {
+	h := Handler()
+	cases := []struct {
+		path, contentType, contains string
+	}{
+		{"/ui/", "text/html", "TCR Dashboard"},
+		{"/ui/app.js", "javascript", "/api/v1/events/stream"},
+		{"/ui/style.css", "text/css", ".cards"},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", c.path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d", c.path, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.Contains(ct, c.contentType) {
+			t.Errorf("GET %s: content type %q, want %s", c.path, ct, c.contentType)
+		}
+		if !strings.Contains(rec.Body.String(), c.contains) {
+			t.Errorf("GET %s: body does not contain %q", c.path, c.contains)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/ui/missing.js", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for missing asset, got %d", rec.Code)
+	}
+}
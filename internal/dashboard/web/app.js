@@ -0,0 +1,261 @@
+// TCR dashboard: snapshot awal dari /api/v1, lalu update didorong towerd
+// lewat /api/v1/events/stream (SSE). Stream dibaca dengan fetch supaya bisa
+// mengirim header Authorization (EventSource tidak bisa).
+
+const API = "/api/v1";
+const TOKEN_KEY = "tcr.token";
+const ACTIVE = new Set(["queued", "dispatched", "running", "in_progress"]);
+const MAX_JOBS = 200;
+const MAX_SCALING = 50;
+
+const state = {
+  jobs: new Map(),
+  runners: new Map(),
+  agents: new Map(),
+  pools: [],
+  queue: null,
+  capacity: null,
+  scaling: [],
+};
+
+const $ = id => document.getElementById(id);
+let token = localStorage.getItem(TOKEN_KEY) || "";
+let stream = null;
+
+class Unauthorized extends Error {}
+
+async function api(path) {
+  const res = await fetch(API + path, {headers: {Authorization: `Bearer ${token}`}});
+  if (res.status === 401 || res.status === 403) throw new Unauthorized();
+  if (res.status === 404) return null;
+  const body = await res.json();
+  if (!res.ok) throw new Error(body.error ? body.error.message : res.statusText);
+  return body;
+}
+
+const byId = items => new Map(items.map(x => [x.id, x]));
+
+async function loadAll() {
+  const [jobs, runners, agents, pools, queue, capacity, scaling] = await Promise.all([
+    api("/jobs?limit=500&sort=-created_at"),
+    api("/runners?limit=500"),
+    api("/agents?limit=500"),
+    api("/pools?limit=500"),
+    api("/queue"),
+    api("/pools/capacity"),
+    api(`/events?type=scale.*&sort=-time&limit=${MAX_SCALING}`),
+  ]);
+  state.jobs = byId(jobs.items);
+  state.runners = byId(runners.items);
+  state.agents = byId(agents.items);
+  state.pools = pools.items;
+  state.queue = queue;
+  state.capacity = capacity;
+  state.scaling = scaling.items;
+  render();
+}
+
+// ---- refresh per resource (dipanggil dari event) ----
+
+async function refreshItem(kind, map, id) {
+  const item = await api(`/${kind}/${encodeURIComponent(id)}`);
+  if (item) map.set(item.id, item); else map.delete(id);
+}
+
+async function refreshSummary() {
+  [state.queue, state.capacity] = await Promise.all([api("/queue"), api("/pools/capacity")]);
+}
+
+async function refreshAgents() {
+  const [agents, pools] = await Promise.all([api("/agents?limit=500"), api("/pools?limit=500")]);
+  state.agents = byId(agents.items);
+  state.pools = pools.items;
+}
+
+async function onEvent(ev) {
+  const [group] = ev.type.split(".");
+  switch (group) {
+    case "job":
+      await Promise.all([refreshItem("jobs", state.jobs, ev.subject), refreshSummary()]);
+      break;
+    case "runner":
+      await Promise.all([refreshItem("runners", state.runners, ev.subject), refreshSummary()]);
+      break;
+    case "agent":
+      await Promise.all([refreshAgents(), refreshSummary()]);
+      break;
+    case "scale":
+      state.scaling = [ev, ...state.scaling].slice(0, MAX_SCALING);
+      break;
+    default:
+      return;
+  }
+  render();
+}
+
+// ---- SSE lewat fetch ----
+
+async function connect() {
+  let lastId = "";
+  while (token) {
+    stream = new AbortController();
+    try {
+      const headers = {Authorization: `Bearer ${token}`};
+      if (lastId) headers["Last-Event-ID"] = lastId;
+      const res = await fetch(`${API}/events/stream?type=job.*,runner.*,agent.*,scale.*`, {headers, signal: stream.signal});
+      if (res.status === 401 || res.status === 403) throw new Unauthorized();
+      setLive(true);
+
+      const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buf = "";
+      for (;;) {
+        const {value, done} = await reader.read();
+        if (done) break;
+        buf += value;
+        let end;
+        while ((end = buf.indexOf("\n\n")) >= 0) {
+          const frame = buf.slice(0, end);
+          buf = buf.slice(end + 2);
+          const data = [];
+          for (const line of frame.split("\n")) {
+            if (line.startsWith("id: ")) lastId = line.slice(4);
+            else if (line.startsWith("data: ")) data.push(line.slice(6));
+          }
+          if (data.length) onEvent(JSON.parse(data.join("\n"))).catch(showError);
+        }
+      }
+    } catch (e) {
+      if (e instanceof Unauthorized) return signOut("Token rejected by towerd");
+      if (e.name === "AbortError") return;
+    }
+    setLive(false);
+    await new Promise(r => setTimeout(r, 3000));
+  }
+}
+
+// ---- render ----
+
+function esc(v) {
+  return String(v ?? "").replace(/[&<>"']/g, c => ({"&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;", "'": "&#39;"}[c]));
+}
+
+function duration(sec) {
+  if (sec == null) return "-";
+  sec = Math.round(sec);
+  if (sec < 60) return `${sec}s`;
+  if (sec < 3600) return `${Math.floor(sec / 60)}m${sec % 60}s`;
+  return `${Math.floor(sec / 3600)}h${Math.floor(sec % 3600 / 60)}m`;
+}
+
+function ago(t) {
+  if (!t) return "-";
+  return duration((Date.now() - new Date(t)) / 1000) + " ago";
+}
+
+function time(t) {
+  return t ? new Date(t).toLocaleString() : "-";
+}
+
+function status(s) {
+  return `<span class="status-${esc(s)}">${esc(s)}</span>`;
+}
+
+function rows(id, items, row, empty) {
+  $(id).innerHTML = items.length ? items.map(row).join("") : `<tr><td colspan="99" class="muted">${empty}</td></tr>`;
+}
+
+// wait job yang masih antre terus bertambah, jadi dihitung di browser
+function jobWait(j) {
+  if (j.status === "queued") return (Date.now() - new Date(j.queued_at)) / 1000;
+  return j.wait_seconds;
+}
+
+function render() {
+  const q = state.queue, c = state.capacity;
+  if (q) {
+    $("q-queued").textContent = q.queued;
+    $("q-dispatched").textContent = q.dispatched;
+    $("q-oldest").textContent = q.queued ? duration(q.oldest_queued_seconds) : "-";
+    $("q-wait").textContent = q.wait_samples ? `${duration(q.wait_p50_seconds)} / ${duration(q.wait_p95_seconds)}` : "-";
+  }
+  if (c) {
+    $("c-runners").textContent = `${c.busy_runners} / ${c.runners}`;
+    $("c-agents").textContent = `${c.agents} (${c.agent_runners} runners)`;
+    $("c-max").textContent = c.max_runners_total;
+  }
+
+  rows("pools", state.pools, p => `<tr>
+    <td>${esc(p.name)}</td><td>${p.agents}</td><td>${p.active_agents}</td><td>${p.runners}</td></tr>`, "No agents registered");
+
+  const jobs = [...state.jobs.values()].sort((a, b) =>
+    (ACTIVE.has(b.status) - ACTIVE.has(a.status)) || b.created_at.localeCompare(a.created_at)).slice(0, MAX_JOBS);
+  rows("jobs", jobs, j => `<tr>
+    <td>${esc(j.id)}</td><td>${esc(j.repo)}</td><td>${esc(j.name)}</td><td>${esc(j.labels.join(", "))}</td>
+    <td>${status(j.status)}</td><td>${esc(j.runner_id || "-")}</td><td>${duration(jobWait(j))}</td><td>${time(j.created_at)}</td></tr>`, "No jobs");
+
+  rows("runners", [...state.runners.values()].sort((a, b) => a.id.localeCompare(b.id)), r => `<tr>
+    <td>${esc(r.id)}</td><td>${esc(r.address)}:${esc(r.port)}</td><td>${status(r.status)}</td><td>${ago(r.last_seen)}</td></tr>`, "No runners");
+
+  rows("agents", [...state.agents.values()].sort((a, b) => a.id.localeCompare(b.id)), a => `<tr>
+    <td>${esc(a.id)}</td><td>${status(a.drain_requested && a.state === "running" ? "draining" : a.state)}</td><td>${a.runners}</td>
+    <td>${esc(a.labels.join(", "))}</td><td>${esc(a.runner_version)}</td><td>${Math.round(a.disk_used_percent)}%</td><td>${ago(a.last_seen)}</td></tr>`, "No agents");
+
+  rows("scaling", state.scaling, e => {
+    const d = e.data || {};
+    return `<tr><td>${time(e.time)}</td><td>${esc(e.type.replace("scale.", ""))}</td><td>${d.count ?? "-"}</td>
+      <td>${esc(e.subject)}</td><td>${d.queued ?? "-"}</td><td>${d.idle ?? "-"}</td></tr>`;
+  }, "No scaling actions yet");
+}
+
+// ---- login ----
+
+function setLive(on) {
+  $("live").textContent = on ? "● live" : "○ reconnecting...";
+  $("live").className = on ? "live" : "muted";
+}
+
+function showError(e) {
+  $("error").textContent = e.message || String(e);
+  $("error").hidden = false;
+}
+
+function signOut(message) {
+  token = "";
+  localStorage.removeItem(TOKEN_KEY);
+  if (stream) stream.abort();
+  $("main").hidden = true;
+  $("logout").hidden = true;
+  $("login").hidden = false;
+  $("live").textContent = "○ offline";
+  $("live").className = "muted";
+  if (message) showError(new Error(message));
+}
+
+async function start() {
+  $("error").hidden = true;
+  $("login").hidden = true;
+  $("logout").hidden = false;
+  $("main").hidden = false;
+  try {
+    await loadAll();
+  } catch (e) {
+    if (e instanceof Unauthorized) return signOut("Token rejected by towerd");
+    showError(e);
+  }
+  connect();
+}
+
+$("login").addEventListener("submit", e => {
+  e.preventDefault();
+  token = $("token").value.trim();
+  if (!token) return;
+  localStorage.setItem(TOKEN_KEY, token);
+  $("token").value = "";
+  start();
+});
+$("logout").addEventListener("click", () => signOut());
+
+// render ulang tiap detik supaya umur/wait time tetap jalan
+setInterval(() => { if (token) render(); }, 1000);
+
+if (token) start(); else signOut();
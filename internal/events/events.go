@@ -0,0 +1,185 @@
+// Package events adalah event bus internal towerd: perubahan state job,
+// runner, agent, scaling dan webhook dicatat di ring buffer (untuk dibaca
+// ulang lewat API) dan dikirim ke subscriber (stream SSE dashboard/tcrctl).
+package events
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tipe event yang dipublikasikan towerd
+const (
+	JobQueued        = "job.queued"
+	JobStatus        = "job.status"
+	JobCancelled     = "job.cancelled"
+	JobRequeued      = "job.requeued"
+	RunnerRegistered = "runner.registered"
+	RunnerBusy       = "runner.busy"
+	RunnerIdle       = "runner.idle"
+	RunnerDraining   = "runner.draining"
+	RunnerRemoved    = "runner.removed"
+	AgentRegistered  = "agent.registered"
+	AgentState       = "agent.state"
+	AgentRemoved     = "agent.removed"
+	ScaleUp          = "scale.up"
+	ScaleDown        = "scale.down"
+	WebhookReceived  = "webhook.received"
+	QueuePressure    = "queue.pressure"
+	QueueRecovered   = "queue.recovered"
+)
+
+type Event struct {
+	ID      int64       `json:"id"`
+	Type    string      `json:"type"`
+	Time    time.Time   `json:"time"`
+	Subject string      `json:"subject"` // id job/runner/agent yang berubah
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Capacity adalah jumlah event terakhir yang disimpan di memori
+const Capacity = 1000
+
+var (
+	mu     sync.Mutex
+	ring   = make([]Event, 0, Capacity)
+	nextID int64
+	subs   = map[*Subscription]struct{}{}
+)
+
+// Filter memilih event untuk subscriber / query. Types berisi tipe persis
+// ("job.status") atau prefix ("job", "job.*"); kosong berarti semua.
+type Filter struct {
+	Types    []string
+	Subjects []string
+}
+
+// ParseFilter membaca filter dari daftar dipisah koma, misal query ?type=job.*,runner.busy
+func ParseFilter(types, subjects string) Filter {
+	return Filter{Types: splitList(types), Subjects: splitList(subjects)}
+}
+
+func (f Filter) Match(ev Event) bool {
+	if len(f.Subjects) > 0 && !contains(f.Subjects, ev.Subject) {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		prefix := strings.TrimSuffix(strings.TrimSuffix(t, "*"), ".")
+		if ev.Type == prefix || strings.HasPrefix(ev.Type, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription menerima event baru lewat C. Subscriber yang terlalu lambat
+// (buffer penuh) diputus: C ditutup dan Overflowed() bernilai true, supaya
+// pemanggil bisa menyambung ulang dan mengejar lewat Since.
+type Subscription struct {
+	C <-chan Event
+
+	ch       chan Event
+	filter   Filter
+	overflow bool
+}
+
+// Subscribe mendaftarkan subscriber baru dengan buffer sebesar buffer event
+func Subscribe(f Filter, buffer int) *Subscription {
+	ch := make(chan Event, buffer)
+	s := &Subscription{C: ch, ch: ch, filter: f}
+
+	mu.Lock()
+	subs[s] = struct{}{}
+	mu.Unlock()
+	return s
+}
+
+// Close berhenti menerima event; aman dipanggil lebih dari sekali
+func (s *Subscription) Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := subs[s]; ok {
+		delete(subs, s)
+		close(s.ch)
+	}
+}
+
+// Overflowed bernilai true kalau subscription diputus karena buffer penuh
+func (s *Subscription) Overflowed() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return s.overflow
+}
+
+// Publish mencatat event baru dan mengirimnya ke subscriber yang cocok
+func Publish(typ, subject string, data interface{}) Event {
+	mu.Lock()
+	defer mu.Unlock()
+
+	nextID++
+	ev := Event{ID: nextID, Type: typ, Time: time.Now(), Subject: subject, Data: data}
+	if len(ring) == Capacity {
+		copy(ring, ring[1:])
+		ring = ring[:Capacity-1]
+	}
+	ring = append(ring, ev)
+
+	for s := range subs {
+		if !s.filter.Match(ev) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			// jangan blok Publish karena satu subscriber lambat
+			s.overflow = true
+			delete(subs, s)
+			close(s.ch)
+		}
+	}
+	return ev
+}
+
+// Since mengembalikan event tersimpan dengan ID > id yang cocok dengan f,
+// dipakai untuk replay saat client menyambung ulang (Last-Event-ID)
+func Since(id int64, f Filter) []Event {
+	mu.Lock()
+	defer mu.Unlock()
+	var out []Event
+	for _, ev := range ring {
+		if ev.ID > id && f.Match(ev) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// Recent mengembalikan salinan event yang masih tersimpan (terlama di depan)
+func Recent() []Event {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Event(nil), ring...)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package events
+
+import "testing"
+
+func TestFilterMatch(t *testing.T) {
+	ev := Event{Type: "job.status", Subject: "42"}
+	cases := []struct {
+		f    Filter
+		want bool
+	}{
+		{Filter{}, true},
+		{ParseFilter("job.status", ""), true},
+		{ParseFilter("job", ""), true},
+		{ParseFilter("job.*", ""), true},
+		{ParseFilter("runner.*, job.queued", ""), false},
+		{ParseFilter("jobs", ""), false},
+		{ParseFilter("", "42"), true},
+		{ParseFilter("job.*", "7"), false},
+	}
+	for _, c := range cases {
+		if got := c.f.Match(ev); got != c.want {
+			t.Errorf("%+v.Match(job.status/42) = %v, want %v", c.f, got, c.want)
+		}
+	}
+}
+
+func TestSubscribeAndReplay(t *testing.T) {
+	sub := Subscribe(ParseFilter("runner.*", ""), 4)
+	defer sub.Close()
+
+	first := Publish(JobQueued, "j1", nil)
+	Publish(RunnerBusy, "r1", nil)
+
+	ev := <-sub.C
+	if ev.Type != RunnerBusy || ev.Subject != "r1" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	select {
+	case ev := <-sub.C:
+		t.Fatalf("filtered event delivered: %+v", ev)
+	default:
+	}
+
+	replay := Since(first.ID-1, Filter{})
+	if len(replay) != 2 || replay[0].ID != first.ID {
+		t.Fatalf("unexpected replay %+v", replay)
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	sub := Subscribe(Filter{}, 1)
+	Publish(ScaleUp, "a", nil)
+	Publish(ScaleUp, "b", nil) // buffer penuh → subscriber diputus
+
+	<-sub.C
+	if _, ok := <-sub.C; ok {
+		t.Fatal("expected channel to be closed after overflow")
+	}
+	if !sub.Overflowed() {
+		t.Fatal("expected Overflowed() to be true")
+	}
+	sub.Close() // aman setelah diputus
+}
@@ -1,9 +1,21 @@
 package github
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 )
 
 type RunnerCredential struct {
@@ -14,19 +26,292 @@ type RunnerCredential struct {
 	Timestamp string `json:"timestamp"`
 }
 
-// SaveRunnerCredential menyimpan credential runner ke file lokal
-func SaveRunnerCredential(runner any, token string) {
-	file := ".tcr_runner_credential.json"
-	data := map[string]any{
-		"runner": runner,
-		"token":  token,
+// KeyProvider menyediakan key AES-256 untuk enkripsi credential
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// FileKey membaca key dari file (hex atau base64 dari tepat 32 byte). Dengan
+// Generate, file yang belum ada diisi key random (permission 0600); tanpa
+// Generate file yang hilang adalah error, supaya path yang salah ketik tidak
+// diam-diam menghasilkan key baru yang tidak bisa membuka credential lama.
+type FileKey struct {
+	Path     string
+	Generate bool
+}
+
+func (f FileKey) Key() ([]byte, error) {
+	data, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) && !f.Generate {
+		return nil, fmt.Errorf("credential key file %s not found", f.Path)
+	}
+	if os.IsNotExist(err) {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(f.Path, []byte(hex.EncodeToString(key)), 0600); err != nil {
+			return nil, err
+		}
+		logger.Info("generated credential key", "path", f.Path)
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeKey(data)
+}
+
+// CommandKey menjalankan plugin lokal (misal wrapper KMS) yang mencetak key ke stdout
+type CommandKey struct {
+	Command string
+}
+
+func (c CommandKey) Key() ([]byte, error) {
+	out, err := exec.Command("sh", "-c", c.Command).Output()
+	if err != nil {
+		return nil, fmt.Errorf("key plugin failed: %v", err)
+	}
+	return decodeKey(out)
+}
+
+// decodeKey menerima key AES-256 sebagai 64 karakter hex atau base64 dari 32
+// byte. Key dengan panjang lain ditolak, bukan di-derive, dan 32 karakter
+// teks biasa tidak dianggap key raw, supaya passphrase lemah tidak diam-diam
+// dipakai sebagai key.
+func decodeKey(data []byte) ([]byte, error) {
+	s := strings.TrimSpace(string(data))
+	if b, err := hex.DecodeString(s); err == nil && len(b) == 32 {
+		return b, nil
+	}
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil && len(b) == 32 {
+		return b, nil
 	}
+	return nil, errors.New("credential key must be 32 bytes encoded as hex or base64")
+}
+
+// CredentialStore menyimpan credential runner terenkripsi (AES-256-GCM),
+// satu file per nama runner di <dir>, permission 0600.
+type CredentialStore struct {
+	Dir  string
+	Keys KeyProvider
+}
 
-	bytes, _ := json.MarshalIndent(data, "", "  ")
-	if err := os.WriteFile(file, bytes, 0644); err != nil {
-		log.Printf("⚠️ Failed to save credential: %v", err)
+var validCredentialName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// DefaultCredentialStore dibangun dari env:
+// TCR_STATE_DIR (default ~/.tcr), TCR_CREDENTIAL_KEY_COMMAND atau TCR_CREDENTIAL_KEY_FILE.
+func DefaultCredentialStore() *CredentialStore {
+	stateDir := os.Getenv("TCR_STATE_DIR")
+	if stateDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		stateDir = filepath.Join(home, ".tcr")
+	}
+
+	// hanya key di lokasi default yang dibuat otomatis
+	var keys KeyProvider = FileKey{Path: filepath.Join(stateDir, "credential.key"), Generate: true}
+	if cmd := os.Getenv("TCR_CREDENTIAL_KEY_COMMAND"); cmd != "" {
+		keys = CommandKey{Command: cmd}
+	} else if path := os.Getenv("TCR_CREDENTIAL_KEY_FILE"); path != "" {
+		keys = FileKey{Path: path}
+	}
+
+	return &CredentialStore{Dir: filepath.Join(stateDir, "credentials"), Keys: keys}
+}
+
+func (s *CredentialStore) path(name string) (string, error) {
+	if !validCredentialName.MatchString(name) {
+		return "", fmt.Errorf("invalid runner name %q", name)
+	}
+	return filepath.Join(s.Dir, name+".enc"), nil
+}
+
+func (s *CredentialStore) aead() (cipher.AEAD, error) {
+	key, err := s.Keys.Key()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Save mengenkripsi dan menulis credential (atomic rename)
+func (s *CredentialStore) Save(c RunnerCredential) error {
+	path, err := s.path(c.Name)
+	if err != nil {
+		return err
+	}
+	gcm, err := s.aead()
+	if err != nil {
+		return err
+	}
+
+	plain, _ := json.Marshal(c)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	// nama runner jadi additional data supaya file tidak bisa ditukar
+	sealed := gcm.Seal(nonce, nonce, plain, []byte(c.Name))
+
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.Dir, ".cred-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load membaca dan mendekripsi credential runner
+func (s *CredentialStore) Load(name string) (RunnerCredential, error) {
+	var c RunnerCredential
+	path, err := s.path(name)
+	if err != nil {
+		return c, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	gcm, err := s.aead()
+	if err != nil {
+		return c, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return c, errors.New("credential file too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(name))
+	if err != nil {
+		return c, fmt.Errorf("decrypt credential %s: %v", name, err)
+	}
+	err = json.Unmarshal(plain, &c)
+	return c, err
+}
+
+// List mengembalikan nama runner yang credential-nya tersimpan
+func (s *CredentialStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".enc"); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete menghapus credential runner (tidak error kalau memang tidak ada)
+func (s *CredentialStore) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// legacyCredentialFile adalah file plaintext yang dulu ditulis
+// SaveRunnerCredential di working directory
+const legacyCredentialFile = ".tcr_runner_credential.json"
+
+// MigrateLegacy memindahkan credential plaintext lama di path ke store
+// terenkripsi lalu menghapus file-nya. File yang tidak bisa dibaca tetap
+// dihapus karena isinya token plaintext; file hanya dipertahankan kalau
+// credential-nya gagal disimpan.
+func (s *CredentialStore) MigrateLegacy(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var legacy struct {
+		Runner RunnerRegisterResponse `json:"runner"`
+		Token  string                 `json:"token"`
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil || legacy.Runner.Name == "" {
+		logger.Warn("discarding unreadable plaintext runner credential", "path", path)
+	} else {
+		cred := RunnerCredential{
+			ID:        legacy.Runner.ID,
+			Name:      legacy.Runner.Name,
+			Status:    legacy.Runner.Status,
+			Token:     legacy.Token,
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+		if err := s.Save(cred); err != nil {
+			return fmt.Errorf("migrate %s: %w", path, err)
+		}
+		logger.Info("migrated plaintext runner credential", "runner_id", cred.Name, "path", path, "dir", s.Dir)
+	}
+	return os.Remove(path)
+}
+
+// MigrateLegacyCredential memindahkan .tcr_runner_credential.json di working
+// directory (kalau ada) ke credential store terenkripsi
+func MigrateLegacyCredential() {
+	if err := DefaultCredentialStore().MigrateLegacy(legacyCredentialFile); err != nil {
+		logger.Error("cannot migrate plaintext runner credential", "path", legacyCredentialFile, "err", err)
+	}
+}
+
+// SaveRunnerCredential menyimpan credential runner ke credential store terenkripsi
+func SaveRunnerCredential(runner RunnerRegisterResponse, token string) {
+	cred := RunnerCredential{
+		ID:        runner.ID,
+		Name:      runner.Name,
+		Status:    runner.Status,
+		Token:     token,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	store := DefaultCredentialStore()
+	if err := store.Save(cred); err != nil {
+		logger.Error("cannot save runner credential", "runner_id", runner.Name, "err", err)
 		return
 	}
 
-	log.Printf("💾 Saved runner credential to %s", file)
+	logger.Info("saved encrypted runner credential", "runner_id", runner.Name, "dir", store.Dir)
+}
+
+// DeleteRunnerCredential menghapus credential runner yang sudah di-remove
+func DeleteRunnerCredential(name string) {
+	if err := DefaultCredentialStore().Delete(name); err != nil {
+		logger.Warn("cannot delete runner credential", "runner_id", name, "err", err)
+	}
 }
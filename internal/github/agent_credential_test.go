@@ -0,0 +1,130 @@
+package github
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCredentialStore_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := &CredentialStore{
+		Dir:  filepath.Join(dir, "credentials"),
+		Keys: FileKey{Path: filepath.Join(dir, "credential.key"), Generate: true},
+	}
+
+	cred := RunnerCredential{ID: 7, Name: "vm-agent-01", Status: "online", Token: "AABBCC-secret"}
+	if err := store.Save(cred); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	path := filepath.Join(store.Dir, "vm-agent-01.enc")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("credential file missing: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), "AABBCC-secret") {
+		t.Fatalf("token stored in plaintext")
+	}
+
+	got, err := store.Load("vm-agent-01")
+	if err != nil || got.Token != cred.Token || got.ID != 7 {
+		t.Fatalf("unexpected credential %+v (%v)", got, err)
+	}
+
+	names, _ := store.List()
+	if len(names) != 1 || names[0] != "vm-agent-01" {
+		t.Fatalf("unexpected list %v", names)
+	}
+
+	if err := store.Delete("vm-agent-01"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if names, _ := store.List(); len(names) != 0 {
+		t.Fatalf("expected empty store, got %v", names)
+	}
+}
+
+func TestCredentialStore_WrongKeyAndBadName(t *testing.T) {
+	dir := t.TempDir()
+	store := &CredentialStore{Dir: dir, Keys: FileKey{Path: filepath.Join(dir, "a.key"), Generate: true}}
+	if err := store.Save(RunnerCredential{Name: "r1", Token: "t"}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	other := &CredentialStore{Dir: dir, Keys: FileKey{Path: filepath.Join(dir, "b.key"), Generate: true}}
+	if _, err := other.Load("r1"); err == nil {
+		t.Fatalf("expected decrypt error with a different key")
+	}
+
+	if err := store.Save(RunnerCredential{Name: "../escape"}); err == nil {
+		t.Fatalf("expected error for invalid runner name")
+	}
+}
+
+func TestDecodeKey_RequiresExactly32Bytes(t *testing.T) {
+	raw := []byte("0123456789abcdef0123456789abcdef")
+	for _, in := range []string{hex.EncodeToString(raw) + "\n", base64.StdEncoding.EncodeToString(raw)} {
+		if key, err := decodeKey([]byte(in)); err != nil || string(key) != string(raw) {
+			t.Fatalf("decodeKey(%q) = %x, %v", in, key, err)
+		}
+	}
+	// 32 karakter teks biasa bukan key raw
+	for _, in := range []string{string(raw), "passphrase", hex.EncodeToString(raw[:8]), base64.StdEncoding.EncodeToString(raw[:16])} {
+		if _, err := decodeKey([]byte(in)); err == nil {
+			t.Fatalf("expected error for key %q", in)
+		}
+	}
+}
+
+func TestCredentialStore_MigrateLegacy(t *testing.T) {
+	dir := t.TempDir()
+	store := &CredentialStore{Dir: filepath.Join(dir, "credentials"), Keys: FileKey{Path: filepath.Join(dir, "credential.key"), Generate: true}}
+
+	legacy := filepath.Join(dir, ".tcr_runner_credential.json")
+	os.WriteFile(legacy, []byte(`{"runner":{"id":3,"name":"old-runner","status":"online"},"token":"AAA-plain"}`), 0644)
+	if err := store.MigrateLegacy(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Fatalf("plaintext credential not removed: %v", err)
+	}
+	if c, err := store.Load("old-runner"); err != nil || c.Token != "AAA-plain" || c.ID != 3 {
+		t.Fatalf("unexpected migrated credential %+v (%v)", c, err)
+	}
+
+	// tidak ada file lama: tidak ada yang dilakukan
+	if err := store.MigrateLegacy(legacy); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultCredentialStore_GeneratesOnlyTheDefaultKey(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TCR_STATE_DIR", dir)
+	t.Setenv("TCR_CREDENTIAL_KEY_COMMAND", "")
+
+	t.Setenv("TCR_CREDENTIAL_KEY_FILE", "")
+	if _, err := DefaultCredentialStore().Keys.Key(); err != nil {
+		t.Fatalf("default key not generated: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "credential.key")); err != nil {
+		t.Fatalf("default key file missing: %v", err)
+	}
+
+	missing := filepath.Join(dir, "typo.key")
+	t.Setenv("TCR_CREDENTIAL_KEY_FILE", missing)
+	if _, err := DefaultCredentialStore().Keys.Key(); err == nil {
+		t.Fatal("expected error for a missing explicit key file")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Fatalf("explicit key file was created: %v", err)
+	}
+}
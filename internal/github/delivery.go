@@ -0,0 +1,283 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status delivery webhook yang disimpan
+const (
+	DeliveryReceived  = "received"  // tersimpan, belum selesai diproses
+	DeliveryProcessed = "processed" // job masuk antrean
+	DeliveryIgnored   = "ignored"   // bukan event yang dipakai towerd
+	DeliveryDropped   = "dropped"   // antrean penuh, job ditolak; bisa di-replay
+	DeliveryFailed    = "failed"    // payload tidak bisa diproses
+)
+
+// Sumber delivery
+const (
+	SourceWebhook   = "webhook"   // dikirim GitHub ke /github/webhook
+	SourceReconcile = "reconcile" // diambil dari API webhook deliveries GitHub
+)
+
+// Delivery adalah satu webhook delivery mentah yang sudah diverifikasi,
+// disimpan apa adanya supaya bisa diperiksa dan diproses ulang
+type Delivery struct {
+	ID          string            `json:"id"` // X-GitHub-Delivery
+	Event       string            `json:"event"`
+	Action      string            `json:"action,omitempty"`
+	Repo        string            `json:"repo,omitempty"`
+	Source      string            `json:"source"`
+	Status      string            `json:"status"`
+	Error       string            `json:"error,omitempty"`
+	JobID       string            `json:"job_id,omitempty"` // job terakhir yang dibuat dari delivery ini
+	Attempts    int               `json:"attempts"`
+	ReceivedAt  time.Time         `json:"received_at"`
+	ProcessedAt time.Time         `json:"processed_at,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        json.RawMessage   `json:"body,omitempty"`
+}
+
+// Summary adalah delivery tanpa header dan body, untuk daftar
+func (d Delivery) Summary() Delivery {
+	d.Headers, d.Body = nil, nil
+	return d
+}
+
+// deliveryHeaders adalah header request yang ikut disimpan
+var deliveryHeaders = []string{
+	"X-GitHub-Delivery", "X-GitHub-Event", "X-GitHub-Hook-ID",
+	"X-GitHub-Hook-Installation-Target-ID", "X-GitHub-Hook-Installation-Target-Type",
+	"X-Hub-Signature-256", "User-Agent", "Content-Type",
+}
+
+func pickHeaders(h http.Header) map[string]string {
+	out := map[string]string{}
+	for _, k := range deliveryHeaders {
+		if v := h.Get(k); v != "" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// memDeliveries adalah jumlah delivery yang disimpan kalau store tanpa direktori
+const memDeliveries = 1000
+
+// DeliveryStore menyimpan delivery sebagai satu file JSON per delivery di
+// Dir. Index (tanpa header/body) ada di memori; body dibaca dari file saat
+// dibutuhkan. Tanpa Dir semua delivery disimpan di memori (maks memDeliveries).
+// Delivery yang lebih tua dari Retention dihapus.
+type DeliveryStore struct {
+	Dir       string
+	Retention time.Duration
+
+	mu        sync.Mutex
+	index     map[string]Delivery
+	claimed   map[string]bool
+	lastPrune time.Time
+}
+
+// Deliveries dipakai WebhookHandler, replay dan rekonsiliasi; towerd
+// menggantinya dengan store berbasis file dari config
+var Deliveries = &DeliveryStore{Retention: 72 * time.Hour}
+
+// OpenDeliveryStore memuat index delivery yang sudah ada di dir
+func OpenDeliveryStore(dir string, retention time.Duration) (*DeliveryStore, error) {
+	s := &DeliveryStore{Dir: dir, Retention: retention, index: map[string]Delivery{}}
+	if dir == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("deliveries: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		d, err := readDelivery(f)
+		if err != nil {
+			logger.Warn("skipping unreadable webhook delivery", "path", f, "err", err)
+			continue
+		}
+		s.index[d.ID] = d.Summary()
+	}
+	s.prune(time.Now())
+	return s, nil
+}
+
+func readDelivery(path string) (Delivery, error) {
+	var d Delivery
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal(b, &d); err != nil {
+		return d, err
+	}
+	if d.ID == "" {
+		return d, fmt.Errorf("missing delivery id")
+	}
+	return d, nil
+}
+
+// validDeliveryID: delivery ID dipakai sebagai nama file
+func validDeliveryID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *DeliveryStore) path(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+// Save menulis (atau menimpa) delivery; file ditulis atomik lewat rename
+func (s *DeliveryStore) Save(d Delivery) error {
+	if !validDeliveryID(d.ID) {
+		return fmt.Errorf("invalid delivery id %q", d.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index == nil {
+		s.index = map[string]Delivery{}
+	}
+
+	if s.Dir == "" {
+		if _, ok := s.index[d.ID]; !ok && len(s.index) >= memDeliveries {
+			s.evictOldest()
+		}
+		s.index[d.ID] = d
+	} else {
+		b, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		tmp := s.path(d.ID) + ".tmp"
+		if err := os.WriteFile(tmp, b, 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, s.path(d.ID)); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		s.index[d.ID] = d.Summary()
+	}
+
+	if now := time.Now(); now.Sub(s.lastPrune) > 10*time.Minute {
+		s.prune(now)
+	}
+	return nil
+}
+
+// Get mengembalikan delivery lengkap (dengan header dan body)
+func (s *DeliveryStore) Get(id string) (Delivery, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.index[id]
+	if !ok || s.Dir == "" {
+		return d, ok, nil
+	}
+	full, err := readDelivery(s.path(id))
+	if err != nil {
+		return d, true, err
+	}
+	return full, true, nil
+}
+
+// Claim menandai delivery sedang diproses di proses ini; false kalau sudah
+// diklaim (webhook, replay atau rekonsiliasi lain sedang memprosesnya).
+// Delivery berstatus received yang tidak diklaim berarti terputus (misal
+// towerd crash) dan aman diproses ulang. Pasangkan dengan Release.
+func (s *DeliveryStore) Claim(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.claimed[id] {
+		return false
+	}
+	if s.claimed == nil {
+		s.claimed = map[string]bool{}
+	}
+	s.claimed[id] = true
+	return true
+}
+
+func (s *DeliveryStore) Release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.claimed, id)
+}
+
+// Status mengembalikan status delivery yang tersimpan ("" kalau belum ada)
+func (s *DeliveryStore) Status(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index[id].Status
+}
+
+// List mengembalikan ringkasan semua delivery tersimpan, terlama dulu
+func (s *DeliveryStore) List() []Delivery {
+	s.mu.Lock()
+	out := make([]Delivery, 0, len(s.index))
+	for _, d := range s.index {
+		out = append(out, d.Summary())
+	}
+	s.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ReceivedAt.Equal(out[j].ReceivedAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].ReceivedAt.Before(out[j].ReceivedAt)
+	})
+	return out
+}
+
+// prune menghapus delivery yang lebih tua dari Retention; caller memegang mu
+func (s *DeliveryStore) prune(now time.Time) {
+	s.lastPrune = now
+	if s.Retention <= 0 {
+		return
+	}
+	for id, d := range s.index {
+		if now.Sub(d.ReceivedAt) <= s.Retention {
+			continue
+		}
+		if s.Dir != "" {
+			if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+				logger.Warn("cannot remove expired webhook delivery", "delivery", id, "err", err)
+				continue
+			}
+		}
+		delete(s.index, id)
+	}
+}
+
+func (s *DeliveryStore) evictOldest() {
+	var oldest string
+	for id, d := range s.index {
+		if oldest == "" || d.ReceivedAt.Before(s.index[oldest].ReceivedAt) {
+			oldest = id
+		}
+	}
+	delete(s.index, oldest)
+}
+
+// newDeliveryID dipakai kalau request tidak membawa X-GitHub-Delivery
+func newDeliveryID(now time.Time) string {
+	return "local-" + strings.ReplaceAll(now.UTC().Format("20060102T150405.000000000"), ".", "-")
+}
@@ -0,0 +1,244 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/core"
+)
+
+const testPayload = `{"action":"queued","workflow_job":{"id":11,"run_id":22,"name":"build","labels":["self-hosted"],"status":"queued"},"repository":{"name":"web","owner":{"login":"acme"}}}`
+
+// useDeliveries mengganti store, secret webhook dan antrean (kapasitas 2)
+// selama test
+func useDeliveries(t *testing.T, dir string) *DeliveryStore {
+	t.Helper()
+	s, err := OpenDeliveryStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevStore, prevFetch, prevQueue := Deliveries, Webhook.Fetch, core.JobQueue
+	Deliveries = s
+	Webhook.Fetch = func(string) string { return "hooksecret" }
+	core.JobQueue = core.NewIntake(2, 1)
+	t.Cleanup(func() {
+		Deliveries, Webhook.Fetch, core.JobQueue = prevStore, prevFetch, prevQueue
+	})
+	return s
+}
+
+// takeJob mengambil satu job dari antrean test
+func takeJob() core.Job {
+	j := core.JobQueue.Take()
+	core.JobQueue.Ack(j.ID)
+	return j
+}
+
+func postWebhook(id, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/github/webhook", bytes.NewReader([]byte(body)))
+	req.Header.Set("X-GitHub-Event", "workflow_job")
+	req.Header.Set("X-GitHub-Delivery", id)
+	req.Header.Set("X-Hub-Signature-256", makeSignature("hooksecret", []byte(body)))
+	rec := httptest.NewRecorder()
+	WebhookHandler(rec, req)
+	return rec
+}
+
+func TestDeliveryStore_SaveReopenPrune(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenDeliveryStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := Delivery{ID: "old-1", Event: "workflow_job", Status: DeliveryProcessed, ReceivedAt: time.Now().Add(-2 * time.Hour)}
+	fresh := Delivery{ID: "new-1", Event: "workflow_job", Status: DeliveryDropped, ReceivedAt: time.Now(),
+		Headers: map[string]string{"X-GitHub-Delivery": "new-1"}, Body: json.RawMessage(testPayload)}
+	for _, d := range []Delivery{old, fresh} {
+		if err := s.Save(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Save(Delivery{ID: "../escape"}); err == nil {
+		t.Fatalf("expected invalid id to be rejected")
+	}
+
+	s, err = OpenDeliveryStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := s.List(); len(list) != 1 || list[0].ID != "new-1" || list[0].Body != nil {
+		t.Fatalf("expected only the fresh summary after reopen, got %+v", list)
+	}
+	got, ok, err := s.Get("new-1")
+	if err != nil || !ok || string(got.Body) != testPayload || got.Headers["X-GitHub-Delivery"] != "new-1" {
+		t.Fatalf("unexpected delivery %+v ok=%v (%v)", got, ok, err)
+	}
+	if s.Status("old-1") != "" {
+		t.Fatalf("expired delivery should be pruned")
+	}
+}
+
+func TestWebhookHandler_StoresAndDeduplicates(t *testing.T) {
+	useDeliveries(t, t.TempDir())
+
+	if rec := postWebhook("d-1", testPayload); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	d, ok, _ := Deliveries.Get("d-1")
+	if !ok || d.Status != DeliveryProcessed || d.Repo != "acme/web" || d.JobID == "" || string(d.Body) != testPayload {
+		t.Fatalf("unexpected stored delivery %+v", d)
+	}
+	if j := takeJob(); j.ID != d.JobID || j.RunID != 22 {
+		t.Fatalf("unexpected job %+v", j)
+	}
+
+	// redelivery dengan ID yang sama tidak membuat job baru
+	if rec := postWebhook("d-1", testPayload); rec.Code != http.StatusOK || core.JobQueue.Len() != 0 {
+		t.Fatalf("duplicate delivery enqueued (code %d, queue %d)", rec.Code, core.JobQueue.Len())
+	}
+}
+
+func TestWebhookHandler_QueueFullThenReplay(t *testing.T) {
+	useDeliveries(t, "")
+	QueueRetryAfter = 45 * time.Second
+	defer func() { QueueRetryAfter = 30 * time.Second }()
+	for i := 0; i < 2; i++ {
+		core.AddJob(core.Job{ID: fmt.Sprint(i)})
+	}
+
+	rec := postWebhook("d-full", testPayload)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "45" {
+		t.Fatalf("expected 503 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if s := Deliveries.Status("d-full"); s != DeliveryDropped {
+		t.Fatalf("expected dropped, got %q", s)
+	}
+	if _, err := ReplayDelivery("d-full", "admin"); !errors.Is(err, ErrReplayFailed) {
+		t.Fatalf("expected replay to fail while queue is full, got %v", err)
+	}
+
+	takeJob()
+	takeJob()
+	d, err := ReplayDelivery("d-full", "admin")
+	if err != nil || d.Status != DeliveryProcessed || d.Attempts != 3 || d.Body != nil {
+		t.Fatalf("unexpected replay result %+v (%v)", d, err)
+	}
+	if j := takeJob(); j.ID != d.JobID {
+		t.Fatalf("replayed job %s, want %s", j.ID, d.JobID)
+	}
+	if _, err := ReplayDelivery("missing", "admin"); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestReconcile_RecoversMissedDeliveries(t *testing.T) {
+	useDeliveries(t, t.TempDir())
+	t.Setenv("GITHUB_OWNER", "acme")
+	t.Setenv("GITHUB_REPO", "web")
+	t.Setenv("GITHUB_TOKEN", "ghs_test")
+
+	now := time.Now().UTC()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/web/hooks/9/deliveries":
+			if r.URL.Query().Get("cursor") == "" {
+				w.Header().Set("Link", `<http://x/repos/acme/web/hooks/9/deliveries?per_page=100&cursor=p2>; rel="next"`)
+				json.NewEncoder(w).Encode([]hookDelivery{
+					{ID: 1, GUID: "seen", Event: "workflow_job", DeliveredAt: now},
+					{ID: 2, GUID: "lost", Event: "workflow_job", DeliveredAt: now.Add(-time.Minute)},
+					{ID: 3, GUID: "ping", Event: "ping", DeliveredAt: now.Add(-time.Minute)},
+					{ID: 6, GUID: "inflight", Event: "workflow_job", DeliveredAt: now.Add(-time.Minute)},
+				})
+				return
+			}
+			json.NewEncoder(w).Encode([]hookDelivery{
+				{ID: 4, GUID: "lost", Event: "workflow_job", DeliveredAt: now.Add(-2 * time.Minute), Redelivery: true},
+				{ID: 5, GUID: "too-old", Event: "workflow_job", DeliveredAt: now.Add(-48 * time.Hour)},
+			})
+		case "/repos/acme/web/hooks/9/deliveries/2":
+			fmt.Fprintf(w, `{"request":{"headers":{"X-GitHub-Delivery":"lost","X-GitHub-Event":"workflow_job"},"payload":%s}}`, testPayload)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	prevBase := apiBase
+	apiBase = srv.URL
+	defer func() { apiBase = prevBase }()
+
+	postWebhook("seen", testPayload)
+	takeJob()
+	// delivery yang masih diproses WebhookHandler (received + diklaim)
+	Deliveries.Save(Delivery{ID: "inflight", Event: "workflow_job", Status: DeliveryReceived,
+		ReceivedAt: now, Body: json.RawMessage(testPayload)})
+	Deliveries.Claim("inflight")
+
+	rc := &Reconciler{HookID: 9, Interval: time.Minute, Lookback: time.Hour}
+	res, err := rc.Run(time.Time{}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Checked != 3 || res.Missing != 1 || res.Recovered != 1 || res.Failed != 0 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	d, ok, _ := Deliveries.Get("lost")
+	if !ok || d.Source != SourceReconcile || d.Status != DeliveryProcessed || d.Headers["X-GitHub-Event"] != "workflow_job" {
+		t.Fatalf("unexpected recovered delivery %+v", d)
+	}
+	if core.JobQueue.Len() != 1 {
+		t.Fatalf("expected 1 recovered job, got %d", core.JobQueue.Len())
+	}
+
+	if s := Deliveries.Status("inflight"); s != DeliveryReceived {
+		t.Fatalf("claimed delivery was processed by reconcile: %s", s)
+	}
+	takeJob()
+
+	// tanpa klaim, delivery received berarti terputus dan dipulihkan
+	Deliveries.Release("inflight")
+	if res, _ := rc.Run(now.Add(-time.Hour), "admin"); res.Missing != 1 || res.Recovered != 1 {
+		t.Fatalf("expected interrupted delivery to be recovered, got %+v", res)
+	}
+	takeJob()
+	// putaran berikutnya tidak memproses ulang
+	if res, _ := rc.Run(time.Time{}, "admin"); res.Missing != 0 {
+		t.Fatalf("expected nothing missing on third run, got %+v", res)
+	}
+}
+
+func TestReconcile_InMemoryStoreStartsAtProcessStart(t *testing.T) {
+	useDeliveries(t, "")
+	t.Setenv("GITHUB_OWNER", "acme")
+	t.Setenv("GITHUB_REPO", "web")
+	t.Setenv("GITHUB_TOKEN", "ghs_test")
+
+	prevStart := processStart
+	processStart = time.Now().Add(-time.Minute)
+	defer func() { processStart = prevStart }()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// delivery dari sebelum restart: sudah diproses proses sebelumnya
+		json.NewEncoder(w).Encode([]hookDelivery{
+			{ID: 1, GUID: "before-restart", Event: "workflow_job", DeliveredAt: time.Now().Add(-time.Hour)},
+		})
+	}))
+	defer srv.Close()
+	prevBase := apiBase
+	apiBase = srv.URL
+	defer func() { apiBase = prevBase }()
+
+	rc := &Reconciler{HookID: 9, Interval: time.Minute, Lookback: 72 * time.Hour}
+	res, err := rc.Run(time.Time{}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Since.Equal(processStart) || res.Checked != 0 || core.JobQueue.Len() != 0 {
+		t.Fatalf("expected window clamped to process start, got %+v (queued %d)", res, core.JobQueue.Len())
+	}
+}
@@ -0,0 +1,5 @@
+package github
+
+import "github.com/ridwandwisiswanto/tcr/internal/logging"
+
+var logger = logging.For("github")
@@ -0,0 +1,125 @@
+package github
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	apiRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tcr_github_api_requests_total",
+			Help: "GitHub API calls by endpoint, method and HTTP status (\"error\" for transport errors)",
+		},
+		[]string{"endpoint", "method", "code"},
+	)
+	apiDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "tcr_github_api_request_duration_seconds",
+			Help:    "GitHub API call latency",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"endpoint", "method"},
+	)
+	rateLimitRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tcr_github_api_rate_limit_remaining",
+			Help: "X-RateLimit-Remaining from the last GitHub API response",
+		},
+		[]string{"resource"},
+	)
+	rateLimitReset = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tcr_github_api_rate_limit_reset_timestamp_seconds",
+			Help: "X-RateLimit-Reset (unix time) from the last GitHub API response",
+		},
+		[]string{"resource"},
+	)
+	webhooksReceived = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tcr_webhooks_received_total",
+			Help: "GitHub webhook deliveries by event, action, repository and result",
+		},
+		[]string{"event", "action", "repo", "result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(apiRequests, apiDuration, rateLimitRemaining, rateLimitReset, webhooksReceived)
+}
+
+// newClient membuat http.Client untuk GitHub API yang mencatat metrics tiap call
+func newClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second, Transport: instrumentedTransport{http.DefaultTransport}}
+}
+
+type instrumentedTransport struct {
+	base http.RoundTripper
+}
+
+func (t instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpointLabel(req.URL)
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	apiDuration.WithLabelValues(endpoint, req.Method).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		apiRequests.WithLabelValues(endpoint, req.Method, "error").Inc()
+		return resp, err
+	}
+	apiRequests.WithLabelValues(endpoint, req.Method, strconv.Itoa(resp.StatusCode)).Inc()
+
+	if remaining := resp.Header.Get("X-RateLimit-Remaining"); remaining != "" {
+		resource := resp.Header.Get("X-RateLimit-Resource")
+		if resource == "" {
+			resource = "core"
+		}
+		if v, err := strconv.ParseFloat(remaining, 64); err == nil {
+			rateLimitRemaining.WithLabelValues(resource).Set(v)
+		}
+		if v, err := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Reset"), 64); err == nil {
+			rateLimitReset.WithLabelValues(resource).Set(v)
+		}
+	}
+	return resp, nil
+}
+
+// endpointLabel menormalkan path supaya cardinality label tetap kecil:
+// /repos/acme/web/actions/runners/42 → /repos/{owner}/{repo}/actions/runners/{id}
+func endpointLabel(u *url.URL) string {
+	if u.Host != "api.github.com" && u.Host != "" {
+		return u.Host
+	}
+	segs := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, s := range segs {
+		switch {
+		case i > 0 && segs[0] == "repos" && i == 1:
+			segs[i] = "{owner}"
+		case i > 0 && segs[0] == "repos" && i == 2:
+			segs[i] = "{repo}"
+		case s != "" && strings.Trim(s, "0123456789") == "":
+			segs[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segs, "/")
+}
+
+// eventLabel: header X-GitHub-Event belum terverifikasi saat event diabaikan,
+// jadi nilai di luar daftar dikelompokkan ke "other" supaya cardinality aman
+func eventLabel(event string) string {
+	switch event {
+	case "workflow_job", "workflow_run", "ping", "check_run", "push", "pull_request":
+		return event
+	}
+	return "other"
+}
+
+// observeWebhook mencatat satu webhook delivery
+func observeWebhook(event, action, repo, result string) {
+	webhooksReceived.WithLabelValues(event, action, repo, result).Inc()
+}
@@ -0,0 +1,57 @@
+package github
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestEndpointLabel(t *testing.T) {
+	cases := map[string]string{
+		"https://api.github.com/repos/acme/web/actions/runners/42":                 "/repos/{owner}/{repo}/actions/runners/{id}",
+		"https://api.github.com/repos/acme/web/actions/runs?status=queued":         "/repos/{owner}/{repo}/actions/runs",
+		"https://api.github.com/repos/acme/web/actions/runners/registration-token": "/repos/{owner}/{repo}/actions/runners/registration-token",
+		"https://pipelines.actions.githubusercontent.com/_apis/agents":             "pipelines.actions.githubusercontent.com",
+	}
+	for raw, want := range cases {
+		u, _ := url.Parse(raw)
+		if got := endpointLabel(u); got != want {
+			t.Errorf("endpointLabel(%s) = %s, want %s", raw, got, want)
+		}
+	}
+}
+
+func TestInstrumentedTransportRecordsRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "4321")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	c := &http.Client{Transport: instrumentedTransport{http.DefaultTransport}}
+	resp, err := c.Post(srv.URL+"/repos/acme/web/actions/runs/7/cancel", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	rec := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	host := strings.TrimPrefix(srv.URL, "http://")
+	for _, want := range []string{
+		`tcr_github_api_requests_total{code="202",endpoint="` + host + `",method="POST"} 1`,
+		`tcr_github_api_rate_limit_remaining{resource="core"} 4321`,
+		`tcr_github_api_rate_limit_reset_timestamp_seconds{resource="core"} 1.7e+09`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output missing %s", want)
+		}
+	}
+}
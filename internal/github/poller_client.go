@@ -7,25 +7,22 @@ import (
 	"net/url"
 	"os"
 	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/secrets"
 )
 
 var (
-	githubToken string
-	githubOwner string
-	githubRepo  string
-	client      = &http.Client{Timeout: 10 * time.Second}
-)
+	client = newClient()
 
-func init() {
-	fmt.Println("🔍 ENV CHECK:", os.Getenv("GITHUB_OWNER"), os.Getenv("GITHUB_REPO"), os.Getenv("GITHUB_TOKEN"))
-}
+	// apiBase bisa diganti test untuk mengarah ke server palsu
+	apiBase = "https://api.github.com"
+)
 
+// apiURL membentuk URL API repo dari GITHUB_OWNER/GITHUB_REPO. Token dibaca
+// tiap request lewat secrets.Get (tanpa state global yang bisa balapan antara
+// poller, token broker dan rekonsiliasi).
 func apiURL(path string, q url.Values) string {
-	githubToken = os.Getenv("GITHUB_TOKEN")
-	githubOwner = os.Getenv("GITHUB_OWNER")
-	githubRepo = os.Getenv("GITHUB_REPO")
-	client = &http.Client{Timeout: 10 * time.Second}
-	base := fmt.Sprintf("https://api.github.com/repos/%s/%s", githubOwner, githubRepo)
+	base := fmt.Sprintf("%s/repos/%s/%s", apiBase, os.Getenv("GITHUB_OWNER"), os.Getenv("GITHUB_REPO"))
 	u := base + path
 	if q != nil {
 		u = u + "?" + q.Encode()
@@ -35,15 +32,13 @@ func apiURL(path string, q url.Values) string {
 
 // CountQueuedRuns returns number of workflow runs with status=queued
 func CountQueuedRuns() (int, error) {
-	// fmt.Printf("DEBUG GITHUB_OWNER=%s GITHUB_REPO=%s GITHUB_TOKEN_PREFIX=%s...\n",
-	// 	githubOwner, githubRepo, githubToken[:6])
 	q := url.Values{}
 	q.Set("status", "queued")
 	// per_page small to reduce payload; GitHub paginates — we'll only read first page count
 	q.Set("per_page", "100")
 
 	req, _ := http.NewRequest("GET", apiURL("/actions/runs", q), nil)
-	req.Header.Set("Authorization", "Bearer "+githubToken)
+	req.Header.Set("Authorization", "Bearer "+secrets.Get("GITHUB_TOKEN"))
 	req.Header.Set("Accept", "application/vnd.github+json")
 
 	resp, err := client.Do(req)
@@ -68,7 +63,7 @@ func CountQueuedRuns() (int, error) {
 // GetRunners returns all runners and count idle ones
 func GetRunners() (total int, idle int, err error) {
 	req, _ := http.NewRequest("GET", apiURL("/actions/runners", nil), nil)
-	req.Header.Set("Authorization", "Bearer "+githubToken)
+	req.Header.Set("Authorization", "Bearer "+secrets.Get("GITHUB_TOKEN"))
 	req.Header.Set("Accept", "application/vnd.github+json")
 
 	resp, err := client.Do(req)
@@ -105,7 +100,7 @@ func GetRunners() (total int, idle int, err error) {
 // GetRegistrationToken asks GitHub for registration token (for new runner)
 func GetRegistrationToken() (string, time.Time, error) {
 	req, _ := http.NewRequest("POST", apiURL("/actions/runners/registration-token", nil), nil)
-	req.Header.Set("Authorization", "Bearer "+githubToken)
+	req.Header.Set("Authorization", "Bearer "+secrets.Get("GITHUB_TOKEN"))
 	req.Header.Set("Accept", "application/vnd.github+json")
 
 	resp, err := client.Do(req)
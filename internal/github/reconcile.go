@@ -0,0 +1,265 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ridwandwisiswanto/tcr/internal/audit"
+	"github.com/ridwandwisiswanto/tcr/internal/secrets"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
+)
+
+// ReconcilerActor adalah actor audit untuk rekonsiliasi periodik
+const ReconcilerActor = "reconciler"
+
+// ErrReconcileDisabled: hook ID webhook tidak dikonfigurasi
+var ErrReconcileDisabled = errors.New("webhook hook_id is not configured (GITHUB_WEBHOOK_ID)")
+
+// ReconcileResult meringkas satu putaran rekonsiliasi
+type ReconcileResult struct {
+	Since     time.Time `json:"since"`
+	Checked   int       `json:"checked"`   // delivery workflow_job di GitHub dalam window
+	Missing   int       `json:"missing"`   // tidak ada / belum berhasil diproses di towerd
+	Recovered int       `json:"recovered"` // berhasil dijadikan job
+	Failed    int       `json:"failed"`
+}
+
+// Reconciler mencocokkan delivery di API webhook deliveries GitHub
+// (GET /repos/{owner}/{repo}/hooks/{hook_id}/deliveries) dengan Deliveries,
+// lalu memproses delivery yang terlewat: yang tidak pernah sampai diambil
+// payload-nya dari GitHub, yang tersimpan tapi dibuang/terputus diproses ulang.
+type Reconciler struct {
+	HookID   int
+	Interval time.Duration
+	Lookback time.Duration // window putaran pertama
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// Reconcile dipakai API dan loop periodik; towerd mengisinya dari config
+var Reconcile = &Reconciler{Interval: 5 * time.Minute, Lookback: 72 * time.Hour}
+
+// processStart: batas bawah window kalau Deliveries tidak durable. Delivery
+// sebelum proses ini jalan tidak ada di store memori, jadi semuanya akan
+// terlihat hilang dan diproses ulang menjadi job kembar.
+var processStart = time.Now()
+
+// Start menjalankan rekonsiliasi periodik di background (no-op tanpa HookID)
+func (rc *Reconciler) Start() {
+	if rc.HookID == 0 {
+		logger.Info("webhook reconciliation disabled, hook_id not set")
+		return
+	}
+	if Deliveries.Dir == "" {
+		logger.Warn("webhook deliveries are kept in memory, reconciliation starts at process start", "since", processStart)
+	}
+	go func() {
+		for {
+			if _, err := rc.Run(time.Time{}, ReconcilerActor); err != nil {
+				logger.Warn("webhook reconciliation failed", "hook_id", rc.HookID, "err", err)
+			}
+			time.Sleep(rc.Interval)
+		}
+	}()
+}
+
+// Run menjalankan satu putaran untuk delivery sejak since. since kosong:
+// sejak putaran sebelumnya (dikurangi Interval sebagai overlap), atau
+// Lookback untuk putaran pertama. Tanpa deliveries_dir window tidak pernah
+// mundur melewati start proses. Putaran manual dan putaran periodik yang
+// memulihkan delivery dicatat di audit log.
+func (rc *Reconciler) Run(since time.Time, by string) (res ReconcileResult, err error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	start := time.Now()
+	if since.IsZero() {
+		since = start.Add(-rc.Lookback)
+		if !rc.last.IsZero() {
+			since = rc.last.Add(-rc.Interval)
+		}
+	}
+	if Deliveries.Dir == "" && since.Before(processStart) {
+		logger.Debug("deliveries not durable, reconciling only since process start", "requested_since", since, "since", processStart)
+		since = processStart
+	}
+	res.Since = since
+	defer func() {
+		if by != ReconcilerActor || res.Recovered > 0 || err != nil {
+			audit.Record(by, audit.WebhookReconcile, strconv.Itoa(rc.HookID), map[string]interface{}{
+				"since": since, "checked": res.Checked, "missing": res.Missing,
+				"recovered": res.Recovered, "failed": res.Failed,
+			}, err)
+		}
+	}()
+
+	if rc.HookID == 0 {
+		return res, ErrReconcileDisabled
+	}
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "webhook.reconcile",
+		trace.WithAttributes(attribute.Int("github.hook_id", rc.HookID)))
+	defer span.End()
+
+	list, err := listHookDeliveries(rc.HookID, since)
+	if err != nil {
+		tracing.Fail(span, err)
+		return res, err
+	}
+
+	seen := map[string]bool{}
+	for _, hd := range list {
+		if hd.Event != "workflow_job" || seen[hd.GUID] {
+			continue
+		}
+		seen[hd.GUID] = true
+		res.Checked++
+
+		// klaim dulu supaya delivery yang masih di WebhookHandler/replay
+		// (status received) tidak ikut diproses dan menjadi job kembar
+		if !Deliveries.Claim(hd.GUID) {
+			continue
+		}
+		switch Deliveries.Status(hd.GUID) {
+		case DeliveryProcessed, DeliveryIgnored, DeliveryFailed:
+			Deliveries.Release(hd.GUID)
+			continue
+		}
+		res.Missing++
+		switch rc.recover(ctx, hd) {
+		case DeliveryProcessed:
+			res.Recovered++
+		case DeliveryIgnored:
+		default:
+			res.Failed++
+		}
+		Deliveries.Release(hd.GUID)
+	}
+
+	rc.last = start
+	if res.Missing > 0 {
+		logger.Info("webhook reconciliation", "hook_id", rc.HookID, "checked", res.Checked,
+			"missing", res.Missing, "recovered", res.Recovered, "failed", res.Failed)
+	}
+	return res, nil
+}
+
+// recover memproses satu delivery yang terlewat (sudah diklaim pemanggil):
+// body tersimpan dipakai ulang, kalau tidak ada diambil dari GitHub.
+// Mengembalikan status akhir delivery ("" kalau gagal diambil).
+func (rc *Reconciler) recover(ctx context.Context, hd hookDelivery) string {
+	d, ok, err := Deliveries.Get(hd.GUID)
+	if err == nil && (!ok || len(d.Body) == 0) {
+		d, err = fetchHookDelivery(rc.HookID, hd)
+	}
+	if err != nil {
+		logger.Warn("cannot recover webhook delivery", "delivery", hd.GUID, "err", err)
+		return ""
+	}
+	d.Source = SourceReconcile
+
+	ctx, span := tracing.Tracer().Start(ctx, "webhook.recover", trace.WithAttributes(
+		attribute.String("github.delivery", d.ID)))
+	defer span.End()
+	return processDelivery(ctx, d).Status
+}
+
+// hookDelivery adalah satu item di API webhook deliveries GitHub
+type hookDelivery struct {
+	ID          int64     `json:"id"`
+	GUID        string    `json:"guid"`
+	DeliveredAt time.Time `json:"delivered_at"`
+	Redelivery  bool      `json:"redelivery"`
+	StatusCode  int       `json:"status_code"`
+	Event       string    `json:"event"`
+	Action      string    `json:"action"`
+}
+
+var nextCursor = regexp.MustCompile(`[?&]cursor=([^&>]+)[^>]*>;\s*rel="next"`)
+
+// listHookDeliveries mengambil delivery sejak since, terbaru dulu, mengikuti
+// cursor pagination sampai melewati since
+func listHookDeliveries(hookID int, since time.Time) ([]hookDelivery, error) {
+	var out []hookDelivery
+	q := url.Values{"per_page": {"100"}}
+	for {
+		var page []hookDelivery
+		link, err := getGitHubJSON(apiURL(fmt.Sprintf("/hooks/%d/deliveries", hookID), q), &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, hd := range page {
+			if hd.DeliveredAt.Before(since) {
+				return out, nil
+			}
+			out = append(out, hd)
+		}
+		m := nextCursor.FindStringSubmatch(link)
+		if len(page) == 0 || m == nil {
+			return out, nil
+		}
+		cursor, _ := url.QueryUnescape(m[1])
+		q.Set("cursor", cursor)
+	}
+}
+
+// fetchHookDelivery mengambil header dan payload request asli dari GitHub
+func fetchHookDelivery(hookID int, hd hookDelivery) (Delivery, error) {
+	var detail struct {
+		Request struct {
+			Headers map[string]string `json:"headers"`
+			Payload json.RawMessage   `json:"payload"`
+		} `json:"request"`
+	}
+	if _, err := getGitHubJSON(apiURL(fmt.Sprintf("/hooks/%d/deliveries/%d", hookID, hd.ID), nil), &detail); err != nil {
+		return Delivery{}, err
+	}
+	if len(detail.Request.Payload) == 0 || string(detail.Request.Payload) == "null" {
+		return Delivery{}, fmt.Errorf("delivery %s has no payload", hd.GUID)
+	}
+	h := http.Header{}
+	for k, v := range detail.Request.Headers {
+		h.Set(k, v)
+	}
+	return Delivery{
+		ID:         hd.GUID,
+		Event:      hd.Event,
+		ReceivedAt: hd.DeliveredAt.UTC(),
+		Headers:    pickHeaders(h),
+		Body:       detail.Request.Payload,
+	}, nil
+}
+
+// getGitHubJSON memanggil GET ke API GitHub dengan GITHUB_TOKEN dan
+// mengembalikan header Link untuk pagination
+func getGitHubJSON(u string, out interface{}) (string, error) {
+	token := secrets.Get("GITHUB_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("missing GITHUB_TOKEN")
+	}
+	req, _ := http.NewRequest("GET", u, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call GitHub API: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub API %s responded %d", endpointLabel(req.URL), resp.StatusCode)
+	}
+	return resp.Header.Get("Link"), json.NewDecoder(resp.Body).Decode(out)
+}
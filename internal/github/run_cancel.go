@@ -0,0 +1,36 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ridwandwisiswanto/tcr/internal/secrets"
+)
+
+// CancelWorkflowRun meminta GitHub membatalkan workflow run; runner yang
+// sedang menjalankan job dari run tersebut akan di-abort oleh GitHub.
+func CancelWorkflowRun(owner, repo string, runID int64) error {
+	token := secrets.Get("GITHUB_TOKEN")
+	if token == "" {
+		return fmt.Errorf("missing GITHUB_TOKEN")
+	}
+
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/runs/%d/cancel", owner, repo, runID)
+	req, _ := http.NewRequest("POST", url, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call GitHub API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return nil
+	case http.StatusConflict:
+		return fmt.Errorf("workflow run %d already completed", runID)
+	}
+	return fmt.Errorf("GitHub API responded %d", resp.StatusCode)
+}
@@ -0,0 +1,79 @@
+package github
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// nama proses yang menjalankan satu job di runner GitHub; Runner.Listener
+// (proses yang menerima job) tidak disentuh
+const runnerWorkerComm = "Runner.Worker"
+
+const procDir = "/proc"
+
+// AbortRunnerJob menghentikan job yang sedang berjalan di runner GitHub dengan
+// process group pgid: Runner.Worker dikirimi SIGTERM (runner membatalkan job
+// dan melaporkannya ke GitHub), lalu SIGKILL kalau belum keluar setelah
+// timeout. Mengembalikan jumlah worker yang dihentikan; 0 berarti runner
+// sedang tidak menjalankan job.
+func AbortRunnerJob(pgid int, timeout time.Duration) (int, error) {
+	pids, err := workersInGroup(pgid)
+	if err != nil || len(pids) == 0 {
+		return 0, err
+	}
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, syscall.SIGTERM); err != nil && err != syscall.ESRCH {
+			return 0, err
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if left, _ := workersInGroup(pgid); len(left) == 0 {
+			return len(pids), nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	logger.Warn("runner worker did not stop in time, killing", "pgid", pgid, "timeout", timeout)
+	for _, pid := range pids {
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+	}
+	return len(pids), nil
+}
+
+// workersInGroup mencari Runner.Worker yang masih hidup di process group pgid
+func workersInGroup(pgid int) ([]int, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(procDir, e.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		// format: pid (comm) state ppid pgrp ...; comm bisa berisi spasi
+		stat := string(b)
+		open, close := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+		if open < 0 || close < open {
+			continue
+		}
+		fields := strings.Fields(stat[close+1:])
+		if len(fields) < 3 || fields[0] == "Z" || stat[open+1:close] != runnerWorkerComm {
+			continue
+		}
+		if pgrp, err := strconv.Atoi(fields[2]); err == nil && pgrp == pgid {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
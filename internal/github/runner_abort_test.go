@@ -0,0 +1,48 @@
+package github
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestAbortRunnerJob_StopsOnlyWorkersInGroup(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if _, serr := os.Stat(procDir); err != nil || serr != nil {
+		t.Skip("needs sleep and /proc")
+	}
+	b, _ := os.ReadFile(sleep)
+	worker := filepath.Join(t.TempDir(), runnerWorkerComm)
+	if err := os.WriteFile(worker, b, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	start := func(path string) *exec.Cmd {
+		cmd := exec.Command(path, "30")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		go cmd.Wait()
+		t.Cleanup(func() { cmd.Process.Kill() })
+		return cmd
+	}
+	job := start(worker)
+	listener := start(sleep) // bukan Runner.Worker: tidak boleh disentuh
+
+	if n, err := AbortRunnerJob(listener.Process.Pid, time.Second); err != nil || n != 0 {
+		t.Fatalf("expected no worker in the listener group, got %d (%v)", n, err)
+	}
+	if n, err := AbortRunnerJob(job.Process.Pid, 5*time.Second); err != nil || n != 1 {
+		t.Fatalf("expected 1 aborted worker, got %d (%v)", n, err)
+	}
+	if left, _ := workersInGroup(job.Process.Pid); len(left) != 0 {
+		t.Fatalf("worker still running: %v", left)
+	}
+	if err := syscall.Kill(listener.Process.Pid, 0); err != nil {
+		t.Fatalf("non-worker process was stopped: %v", err)
+	}
+}
@@ -2,10 +2,15 @@ package github
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/runnerlog"
 )
 
 type RegistrationPayload struct {
@@ -25,7 +30,7 @@ func HybridRegister(token, url, runnerName string) error {
 		return fmt.Errorf("config.sh not found in %s — download GitHub runner binary first", runnerDir)
 	}
 
-	log.Printf("🧩 Starting hybrid registration for runner %s ...", runnerName)
+	logger.Info("registering hybrid runner", "runner_id", runnerName)
 
 	cmd := exec.Command(
 		"./config.sh",
@@ -43,7 +48,7 @@ func HybridRegister(token, url, runnerName string) error {
 		return fmt.Errorf("config.sh failed: %v", err)
 	}
 
-	log.Println("✅ Runner successfully registered to GitHub Actions.")
+	logger.Info("runner registered to GitHub", "runner_id", runnerName)
 	return nil
 }
 
@@ -59,13 +64,67 @@ func HybridRun() error {
 		return fmt.Errorf("run.sh not found in %s", runnerDir)
 	}
 
+	logw, err := runnerlog.New(HybridLogPath(), HybridRunnerName(), int64(atoiEnv("RUNNER_LOG_MAX_MB", 10))<<20, atoiEnv("RUNNER_LOG_BACKUPS", 3))
+	if err != nil {
+		return fmt.Errorf("open runner log: %v", err)
+	}
+	defer logw.Close()
+
 	cmd := exec.Command("./run.sh")
 	cmd.Dir = runnerDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = logw
+	cmd.Stderr = logw
+	// process group sendiri supaya HybridAbortJob bisa menemukan Runner.Worker
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
-	log.Println("🏃 Starting GitHub Actions runner...")
-	return cmd.Run()
+	logger.Info("starting GitHub Actions runner", "runner_id", HybridRunnerName(), "log", logw.Path())
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	hybridPgid.Store(int64(cmd.Process.Pid))
+	defer hybridPgid.Store(0)
+	return cmd.Wait()
+}
+
+// hybridPgid adalah process group run.sh yang sedang berjalan (0 kalau belum)
+var hybridPgid atomic.Int64
+
+// HybridAbortJob menghentikan job yang sedang dijalankan runner hybrid.
+// 0 berarti runner tidak sedang menjalankan job (atau belum dijalankan).
+func HybridAbortJob(timeout time.Duration) (int, error) {
+	pgid := int(hybridPgid.Load())
+	if pgid == 0 {
+		return 0, nil
+	}
+	return AbortRunnerJob(pgid, timeout)
+}
+
+// HybridLogPath lokasi file log runner hybrid (RUNNER_LOG_DIR/<RUNNER_NAME>.log)
+func HybridLogPath() string {
+	dir := os.Getenv("RUNNER_LOG_DIR")
+	if dir == "" {
+		runnerDir := os.Getenv("RUNNER_DIR")
+		if runnerDir == "" {
+			runnerDir = "./actions-runner"
+		}
+		dir = filepath.Join(runnerDir, "_logs")
+	}
+	return filepath.Join(dir, HybridRunnerName()+".log")
+}
+
+// HybridRunnerName adalah nama runner hybrid di GitHub (RUNNER_NAME, default runner-001)
+func HybridRunnerName() string {
+	if name := os.Getenv("RUNNER_NAME"); name != "" {
+		return name
+	}
+	return "runner-001"
+}
+
+func atoiEnv(k string, def int) int {
+	if i, err := strconv.Atoi(os.Getenv(k)); err == nil {
+		return i
+	}
+	return def
 }
 
 // HybridUnregister membersihkan runner saat shutdown
@@ -80,7 +139,8 @@ func HybridUnregister() {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	log.Println("🧹 Unregistering runner from GitHub...")
+	logger.Info("unregistering runner from GitHub", "runner_id", HybridRunnerName())
 	cmd.Run()
-	log.Println("✅ Runner unregistered.")
+	DeleteRunnerCredential(HybridRunnerName())
+	logger.Info("runner unregistered", "runner_id", HybridRunnerName())
 }
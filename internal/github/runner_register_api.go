@@ -5,12 +5,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"os"
 	"runtime"
 	"time"
 )
 
+// DefaultRunnerVersion dipakai kalau GH_RUNNER_VERSION tidak di-set
+const DefaultRunnerVersion = "2.317.0"
+
+// RunnerVersion mengembalikan versi runner dari env GH_RUNNER_VERSION
+func RunnerVersion() string {
+	if v := os.Getenv("GH_RUNNER_VERSION"); v != "" {
+		return v
+	}
+	return DefaultRunnerVersion
+}
+
 type RunnerRegisterRequest struct {
 	Name          string `json:"name"`
 	OSDescription string `json:"osDescription"`
@@ -38,7 +49,7 @@ func RegisterRunnerDirect(owner, repo, token, runnerName string) error {
 	registerReq := RunnerRegisterRequest{
 		Name:          runnerName,
 		OSDescription: fmt.Sprintf("%s %s", runtime.GOOS, runtime.GOARCH),
-		Version:       "2.317.0",
+		Version:       RunnerVersion(),
 		Ephemeral:     false,
 	}
 
@@ -51,7 +62,6 @@ func RegisterRunnerDirect(owner, repo, token, runnerName string) error {
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("http request failed: %v", err)
@@ -66,7 +76,7 @@ func RegisterRunnerDirect(owner, repo, token, runnerName string) error {
 	var result RunnerRegisterResponse
 	json.NewDecoder(resp.Body).Decode(&result)
 
-	log.Printf("✅ Runner %s registered to GitHub (ID: %d, Status: %s)", result.Name, result.ID, result.Status)
+	logger.Info("runner registered to GitHub", "runner_id", result.Name, "github_runner_id", result.ID, "status", result.Status)
 
 	// optional: simpan credential sementara
 	SaveRunnerCredential(result, token)
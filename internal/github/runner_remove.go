@@ -1,52 +1,60 @@
 package github
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
-)
-
-// RemoveRunnerByID — hapus runner dari repo menggunakan REST API
-func GetRunnerIDByName(name string) (int, error) {
-	token := os.Getenv("GITHUB_TOKEN")
-	owner := os.Getenv("GITHUB_OWNER")
-	repo := os.Getenv("GITHUB_REPO")
-
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/runners", owner, repo)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/vnd.github+json")
+	"strconv"
+	"strings"
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return 0, fmt.Errorf("failed to query runners: %v", err)
-	}
-	defer resp.Body.Close()
+	"github.com/ridwandwisiswanto/tcr/internal/secrets"
+)
 
-	var data struct {
-		Runners []struct {
-			ID   int    `json:"id"`
-			Name string `json:"name"`
-		} `json:"runners"`
-	}
+// repoRunner adalah satu entry di GET /actions/runners
+type repoRunner struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Busy   bool   `json:"busy"`
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return 0, fmt.Errorf("decode: %v", err)
-	}
+// runnersPerPage adalah batas per_page GitHub untuk daftar runner
+const runnersPerPage = 100
 
-	for _, r := range data.Runners {
-		if r.Name == name {
-			return r.ID, nil
+// findRunner mencari runner berdasarkan nama di semua halaman daftar runner
+// repo; repo dengan lebih dari 100 runner tidak muat di halaman pertama
+func findRunner(name string) (repoRunner, error) {
+	q := url.Values{"per_page": {strconv.Itoa(runnersPerPage)}}
+	for page := 1; ; page++ {
+		q.Set("page", strconv.Itoa(page))
+		var data struct {
+			Runners []repoRunner `json:"runners"`
+		}
+		link, err := getGitHubJSON(apiURL("/actions/runners", q), &data)
+		if err != nil {
+			return repoRunner{}, fmt.Errorf("failed to query runners: %w", err)
+		}
+		for _, r := range data.Runners {
+			if r.Name == name {
+				return r, nil
+			}
+		}
+		if len(data.Runners) < runnersPerPage || !strings.Contains(link, `rel="next"`) {
+			return repoRunner{}, fmt.Errorf("runner %s not found", name)
 		}
 	}
+}
 
-	return 0, fmt.Errorf("runner %s not found", name)
+// GetRunnerIDByName — cari ID runner GitHub berdasarkan nama
+func GetRunnerIDByName(name string) (int, error) {
+	r, err := findRunner(name)
+	return r.ID, err
 }
 
 // RemoveRunnerByID — menghapus runner dari GitHub repository menggunakan REST API
 func RemoveRunnerByID(runnerID int) error {
-	token := os.Getenv("GITHUB_TOKEN")
+	token := secrets.Get("GITHUB_TOKEN")
 	owner := os.Getenv("GITHUB_OWNER")
 	repo := os.Getenv("GITHUB_REPO")
 
@@ -60,7 +68,7 @@ func RemoveRunnerByID(runnerID int) error {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Accept", "application/vnd.github+json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to call GitHub API: %v", err)
 	}
@@ -72,3 +80,15 @@ func RemoveRunnerByID(runnerID int) error {
 
 	return nil
 }
+
+// IsRunnerBusy — cek apakah runner dengan nama tertentu sedang menjalankan job
+func IsRunnerBusy(name string) (bool, error) {
+	_, busy, err := GetRunnerStatus(name)
+	return busy, err
+}
+
+// GetRunnerStatus — ambil status (online/offline) dan busy flag runner berdasarkan nama
+func GetRunnerStatus(name string) (string, bool, error) {
+	r, err := findRunner(name)
+	return r.Status, r.Busy, err
+}
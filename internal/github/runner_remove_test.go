@@ -0,0 +1,43 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsRunnerBusy_FollowsPagination(t *testing.T) {
+	t.Setenv("GITHUB_OWNER", "acme")
+	t.Setenv("GITHUB_REPO", "web")
+	t.Setenv("GITHUB_TOKEN", "ghs_test")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var page []repoRunner
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Header().Set("Link", `<http://x/repos/acme/web/actions/runners?per_page=100&page=2>; rel="next"`)
+			for i := 0; i < runnersPerPage; i++ {
+				page = append(page, repoRunner{ID: i, Name: fmt.Sprintf("vm-agent-%03d", i), Status: "online"})
+			}
+		case "2":
+			page = []repoRunner{{ID: 500, Name: "late-runner", Status: "online", Busy: true}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total_count": runnersPerPage + 1, "runners": page})
+	}))
+	defer srv.Close()
+	prevBase := apiBase
+	apiBase = srv.URL
+	defer func() { apiBase = prevBase }()
+
+	if busy, err := IsRunnerBusy("late-runner"); err != nil || !busy {
+		t.Fatalf("runner on the second page: busy=%v err=%v", busy, err)
+	}
+	if id, err := GetRunnerIDByName("late-runner"); err != nil || id != 500 {
+		t.Fatalf("expected id 500, got %d %v", id, err)
+	}
+	if _, err := IsRunnerBusy("missing"); err == nil {
+		t.Fatal("expected not found for unknown runner")
+	}
+}
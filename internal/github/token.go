@@ -3,10 +3,11 @@ package github
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/secrets"
 )
 
 type RunnerTokenResponse struct {
@@ -14,39 +15,50 @@ type RunnerTokenResponse struct {
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// GetRunnerRegistrationToken memanggil GitHub API untuk mendapatkan token runner
+// GetRunnerRegistrationToken mengembalikan token registrasi runner (lewat cache broker)
 func GetRunnerRegistrationToken() (string, error) {
-	githubToken := os.Getenv("GITHUB_TOKEN")
+	t, err := Tokens.Get(TokenRegistration)
+	return t.Token, err
+}
+
+// GetRunnerRemovalToken mengembalikan token untuk `config.sh remove` (lewat cache broker)
+func GetRunnerRemovalToken() (string, error) {
+	t, err := Tokens.Get(TokenRemoval)
+	return t.Token, err
+}
+
+// requestRunnerToken memanggil GitHub API untuk mendapatkan token runner
+// (kind: registration atau remove)
+func requestRunnerToken(kind string) (string, time.Time, error) {
+	githubToken := secrets.Get("GITHUB_TOKEN")
 	githubOwner := os.Getenv("GITHUB_OWNER")
 	githubRepo := os.Getenv("GITHUB_REPO")
 
 	if githubToken == "" || githubOwner == "" || githubRepo == "" {
-		return "", fmt.Errorf("missing env vars: GITHUB_TOKEN, GITHUB_OWNER, or GITHUB_REPO")
+		return "", time.Time{}, fmt.Errorf("missing env vars: GITHUB_TOKEN, GITHUB_OWNER, or GITHUB_REPO")
 	}
 
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/runners/registration-token",
-		githubOwner, githubRepo)
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/runners/%s-token",
+		githubOwner, githubRepo, kind)
 
 	req, _ := http.NewRequest("POST", url, nil)
 	req.Header.Set("Authorization", "Bearer "+githubToken)
 	req.Header.Set("Accept", "application/vnd.github+json")
 
-	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("GitHub API call failed: %v", err)
+		return "", time.Time{}, fmt.Errorf("GitHub API call failed: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 300 {
-		return "", fmt.Errorf("GitHub API responded %d", resp.StatusCode)
+		return "", time.Time{}, fmt.Errorf("GitHub API responded %d", resp.StatusCode)
 	}
 
 	var result RunnerTokenResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %v", err)
+		return "", time.Time{}, fmt.Errorf("failed to decode response: %v", err)
 	}
 
-	log.Printf("🔑 Received GitHub runner registration token (expires at %s)", result.ExpiresAt)
-	return result.Token, nil
+	return result.Token, result.ExpiresAt, nil
 }
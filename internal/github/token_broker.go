@@ -0,0 +1,82 @@
+package github
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	TokenRegistration = "registration"
+	TokenRemoval      = "remove"
+)
+
+// Tokens adalah broker token runner yang dipakai towerd (lihat TokenBroker)
+var Tokens = NewTokenBroker(requestRunnerToken, 5*time.Minute)
+
+// TokenBroker meng-cache token registration/removal dari GitHub sampai
+// refreshBefore sebelum expires_at, dan menggabungkan request paralel
+// untuk jenis token yang sama jadi satu panggilan ke GitHub.
+type TokenBroker struct {
+	mu            sync.Mutex
+	fetch         func(kind string) (string, time.Time, error)
+	refreshBefore time.Duration
+	cache         map[string]RunnerTokenResponse
+	inflight      map[string]*tokenCall
+}
+
+type tokenCall struct {
+	done chan struct{}
+	resp RunnerTokenResponse
+	err  error
+}
+
+func NewTokenBroker(fetch func(kind string) (string, time.Time, error), refreshBefore time.Duration) *TokenBroker {
+	return &TokenBroker{
+		fetch:         fetch,
+		refreshBefore: refreshBefore,
+		cache:         map[string]RunnerTokenResponse{},
+		inflight:      map[string]*tokenCall{},
+	}
+}
+
+// Get mengembalikan token yang masih valid dari cache, atau mengambil yang baru
+func (b *TokenBroker) Get(kind string) (RunnerTokenResponse, error) {
+	b.mu.Lock()
+	if t, ok := b.cache[kind]; ok && time.Until(t.ExpiresAt) > b.refreshBefore {
+		b.mu.Unlock()
+		return t, nil
+	}
+
+	// sudah ada request yang jalan → tunggu hasilnya saja
+	if c, ok := b.inflight[kind]; ok {
+		b.mu.Unlock()
+		<-c.done
+		return c.resp, c.err
+	}
+
+	c := &tokenCall{done: make(chan struct{})}
+	b.inflight[kind] = c
+	b.mu.Unlock()
+
+	token, expiresAt, err := b.fetch(kind)
+	c.resp = RunnerTokenResponse{Token: token, ExpiresAt: expiresAt}
+	c.err = err
+
+	b.mu.Lock()
+	delete(b.inflight, kind)
+	if err == nil {
+		b.cache[kind] = c.resp
+		logger.Info("cached GitHub token", "kind", kind, "expires_at", expiresAt)
+	}
+	b.mu.Unlock()
+	close(c.done)
+
+	return c.resp, c.err
+}
+
+// Invalidate membuang token dari cache (misal setelah GitHub menolak token)
+func (b *TokenBroker) Invalidate(kind string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.cache, kind)
+}
@@ -0,0 +1,70 @@
+package github
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBroker_CachesUntilRefreshWindow(t *testing.T) {
+	var calls int32
+	expiresAt := time.Now().Add(time.Hour)
+	b := NewTokenBroker(func(kind string) (string, time.Time, error) {
+		atomic.AddInt32(&calls, 1)
+		return kind + "-token", expiresAt, nil
+	}, 5*time.Minute)
+
+	for i := 0; i < 3; i++ {
+		tok, err := b.Get(TokenRegistration)
+		if err != nil || tok.Token != "registration-token" {
+			t.Fatalf("unexpected token %q (%v)", tok.Token, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 GitHub call, got %d", calls)
+	}
+
+	// removal token di-cache terpisah
+	if tok, _ := b.Get(TokenRemoval); tok.Token != "remove-token" {
+		t.Fatalf("unexpected removal token %q", tok.Token)
+	}
+
+	// token yang hampir expired harus diambil ulang
+	expiresAt = time.Now().Add(time.Minute)
+	b.Invalidate(TokenRegistration)
+	b.Get(TokenRegistration)
+	b.Get(TokenRegistration)
+	if calls != 4 {
+		t.Fatalf("expected refresh inside window, got %d calls", calls)
+	}
+}
+
+func TestTokenBroker_CoalescesConcurrentRequests(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	b := NewTokenBroker(func(kind string) (string, time.Time, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "tok", time.Now().Add(time.Hour), nil
+	}, 5*time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tok, err := b.Get(TokenRegistration); err != nil || tok.Token != "tok" {
+				t.Errorf("unexpected token %q (%v)", tok.Token, err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("expected 1 GitHub call, got %d", calls)
+	}
+}
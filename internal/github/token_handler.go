@@ -1,19 +1,64 @@
 package github
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+
+	"github.com/ridwandwisiswanto/tcr/internal/audit"
+	"github.com/ridwandwisiswanto/tcr/internal/secrets"
 )
 
-// Handler untuk /github/token (dipanggil agent)
+// AuthorizeAgent memutuskan apakah request boleh menerima token runner.
+// Default: shared secret TOWER_AGENT_TOKEN di header Authorization: Bearer;
+// towerd menggantinya dengan cek credential hasil enrollment (auth.Protect).
+var AuthorizeAgent = func(r *http.Request) bool {
+	secret := secrets.Get("TOWER_AGENT_TOKEN")
+	if secret == "" {
+		return false
+	}
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(got), []byte(secret)) == 1
+}
+
+// RequestActor menentukan siapa yang meminta token, untuk audit log;
+// towerd menggantinya dengan subject credential hasil enrollment
+var RequestActor = func(r *http.Request) string {
+	return r.RemoteAddr
+}
+
+// Handler untuk /github/token (dipanggil agent). ?kind=remove untuk removal token.
+// Setiap token yang dibagikan (atau ditolak) dicatat di audit log tanpa nilai tokennya.
 func TokenHandler(w http.ResponseWriter, r *http.Request) {
-	token, err := GetRunnerRegistrationToken()
+	kind := r.URL.Query().Get("kind")
+	if kind == "" {
+		kind = TokenRegistration
+	}
+	params := map[string]interface{}{"remote_addr": r.RemoteAddr}
+
+	if !AuthorizeAgent(r) {
+		logger.Warn("token request rejected", "remote_addr", r.RemoteAddr)
+		audit.Record(RequestActor(r), audit.TokenIssue, kind, params, errors.New("unauthorized"))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if kind != TokenRegistration && kind != TokenRemoval {
+		http.Error(w, "unknown token kind", http.StatusBadRequest)
+		return
+	}
+
+	t, err := Tokens.Get(kind)
+	if err == nil {
+		params["expires_at"] = t.ExpiresAt
+	}
+	audit.Record(RequestActor(r), audit.TokenIssue, kind, params, err)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	resp := map[string]string{"token": token}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	json.NewEncoder(w).Encode(t)
 }
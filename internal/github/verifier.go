@@ -10,7 +10,9 @@ import (
 	"net/http"
 )
 
-func VerifySignature(r *http.Request, secret string) error {
+// VerifySignature memvalidasi X-Hub-Signature-256 terhadap salah satu secret.
+// Lebih dari satu secret dipakai selama masa grace rotasi (lihat WebhookSecrets).
+func VerifySignature(r *http.Request, secrets ...string) error {
 	signature := r.Header.Get("X-Hub-Signature-256")
 	if signature == "" {
 		return errors.New("missing signature header")
@@ -20,14 +22,18 @@ func VerifySignature(r *http.Request, secret string) error {
 	if err != nil {
 		return err
 	}
+	r.Body = io.NopCloser(bytes.NewReader(payload))
 
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(payload)
-	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
-	if !hmac.Equal([]byte(expected), []byte(signature)) {
-		return errors.New("invalid signature")
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(payload)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			return nil
+		}
 	}
-
-	r.Body = io.NopCloser(bytes.NewReader(payload))
-	return nil
+	return errors.New("invalid signature")
 }
@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"net/http"
 	"testing"
+	"time"
 )
 
 // helper untuk buat signature valid
@@ -63,3 +64,27 @@ func TestVerifySignature_MissingHeader(t *testing.T) {
 		t.Fatalf("expected error for missing header, got nil")
 	}
 }
+
+func TestVerifySignature_RotationGrace(t *testing.T) {
+	value := "old-secret"
+	s := &WebhookSecrets{Key: "K", Grace: time.Hour, Fetch: func(string) string { return value }}
+	s.Active()
+
+	value = "new-secret"
+	payload := []byte(`{"action":"queued"}`)
+	for _, secret := range []string{"old-secret", "new-secret"} {
+		req, _ := http.NewRequest("POST", "/", bytes.NewReader(payload))
+		req.Header.Set("X-Hub-Signature-256", makeSignature(secret, payload))
+		if err := VerifySignature(req, s.Active()...); err != nil {
+			t.Fatalf("expected %s to be accepted during grace window: %v", secret, err)
+		}
+	}
+
+	// setelah grace habis hanya secret baru yang valid
+	s.previousUntil = time.Now().Add(-time.Second)
+	req, _ := http.NewRequest("POST", "/", bytes.NewReader(payload))
+	req.Header.Set("X-Hub-Signature-256", makeSignature("old-secret", payload))
+	if err := VerifySignature(req, s.Active()...); err == nil {
+		t.Fatalf("expected old secret to be rejected after grace window")
+	}
+}
@@ -1,65 +1,245 @@
 package github
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ridwandwisiswanto/tcr/internal/audit"
 	"github.com/ridwandwisiswanto/tcr/internal/core"
+	"github.com/ridwandwisiswanto/tcr/internal/events"
+	"github.com/ridwandwisiswanto/tcr/internal/tracing"
+)
+
+// Error ReplayDelivery: ID tidak tersimpan, atau delivery tetap tidak menjadi
+// job (antrean penuh / payload rusak)
+var (
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrReplayFailed     = errors.New("replay failed")
 )
 
+// QueueRetryAfter dikirim sebagai Retry-After saat antrean job penuh;
+// towerd mengisinya dari config
+var QueueRetryAfter = 30 * time.Second
+
+// WebhookHandler memverifikasi delivery, menyimpannya mentah di Deliveries,
+// lalu memprosesnya. Delivery yang job-nya ditolak karena antrean penuh
+// dibalas 503 + Retry-After supaya terlihat gagal di GitHub dan bisa
+// di-replay/rekonsiliasi.
 func WebhookHandler(w http.ResponseWriter, r *http.Request) {
-	// Ambil secret dari ENV (sudah di-load di main.go lewat godotenv)
-	secret := os.Getenv("GITHUB_WEBHOOK_SECRET")
-	if secret == "" {
+	// span pertama trace job; trace context-nya disimpan di job sebagai parent
+	// tahap queue, dispatch dan result
+	ctx, span := tracing.Tracer().Start(r.Context(), "webhook.receive", trace.WithAttributes(
+		attribute.String("github.event", eventLabel(r.Header.Get("X-GitHub-Event"))),
+		attribute.String("github.delivery", r.Header.Get("X-GitHub-Delivery")),
+	))
+	defer span.End()
+
+	// Ambil secret dari secret provider (file/vault/env), termasuk secret lama selama grace rotasi
+	active := Webhook.Active()
+	event := r.Header.Get("X-GitHub-Event")
+	if len(active) == 0 {
+		observeWebhook(eventLabel(event), "", "", "misconfigured")
 		http.Error(w, "server misconfigured: missing GITHUB_WEBHOOK_SECRET", http.StatusInternalServerError)
 		return
 	}
 
-	event := r.Header.Get("X-GitHub-Event")
 	if event != "workflow_job" {
+		observeWebhook(eventLabel(event), "", "", "ignored")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	if err := VerifySignature(r, secret); err != nil {
-		log.Printf("❌ Invalid signature: %v", err)
+	if err := VerifySignature(r, active...); err != nil {
+		logger.Warn("webhook signature invalid", "delivery", r.Header.Get("X-GitHub-Delivery"), "err", err)
+		tracing.Fail(span, err)
+		observeWebhook(event, "", "", "invalid_signature")
 		http.Error(w, "invalid signature", http.StatusUnauthorized)
 		return
 	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		tracing.Fail(span, err)
+		http.Error(w, "cannot read body", http.StatusBadRequest)
+		return
+	}
 
-	var payload WorkflowJobPayload
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+	id := r.Header.Get("X-GitHub-Delivery")
+	if !validDeliveryID(id) {
+		id = newDeliveryID(time.Now())
+	}
+	// GitHub mengirim ulang (redeliver) dengan ID yang sama: yang sudah
+	// diproses, atau sedang diproses (rekonsiliasi/replay), tidak dijadikan job lagi
+	if !Deliveries.Claim(id) {
+		logger.Info("webhook delivery already in progress", "delivery", id)
+		observeWebhook(event, "", "", "duplicate")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	defer Deliveries.Release(id)
+	if Deliveries.Status(id) == DeliveryProcessed {
+		logger.Info("duplicate webhook delivery ignored", "delivery", id)
+		observeWebhook(event, "", "", "duplicate")
+		w.WriteHeader(http.StatusOK)
 		return
 	}
+	events.Publish(events.WebhookReceived, id, map[string]string{"event": event})
+
+	d := Delivery{
+		ID:         id,
+		Event:      event,
+		Source:     SourceWebhook,
+		Status:     DeliveryReceived,
+		ReceivedAt: time.Now().UTC(),
+		Headers:    pickHeaders(r.Header),
+		Body:       body,
+	}
+	// simpan dulu sebelum diproses, supaya crash di tengah tidak menghilangkan delivery
+	if err := Deliveries.Save(d); err != nil {
+		logger.Error("cannot store webhook delivery", "delivery", id, "err", err)
+	}
+
+	d = processDelivery(ctx, d)
+	switch d.Status {
+	case DeliveryFailed:
+		tracing.Fail(span, fmt.Errorf("%s", d.Error))
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+	case DeliveryDropped:
+		w.Header().Set("Retry-After", strconv.Itoa(int(QueueRetryAfter.Seconds())))
+		http.Error(w, "job queue full, delivery stored for replay", http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// processDelivery mengubah delivery workflow_job menjadi job di antrean,
+// mencatat hasilnya di delivery dan menyimpannya. ctx membawa span yang
+// menjadi parent trace job.
+func processDelivery(ctx context.Context, d Delivery) Delivery {
+	span := trace.SpanFromContext(ctx)
+	d.Attempts++
+	d.ProcessedAt = time.Now().UTC()
+	d.Error, d.JobID = "", ""
+
+	defer func() {
+		if err := Deliveries.Save(d); err != nil {
+			logger.Error("cannot store webhook delivery", "delivery", d.ID, "err", err)
+		}
+	}()
+
+	if d.Event != "workflow_job" {
+		d.Status = DeliveryIgnored
+		return d
+	}
+
+	var payload WorkflowJobPayload
+	if err := json.Unmarshal(d.Body, &payload); err != nil {
+		observeWebhook(d.Event, "", "", "bad_payload")
+		d.Status, d.Error = DeliveryFailed, err.Error()
+		return d
+	}
+	repo := payload.Repository.Owner.Login + "/" + payload.Repository.Name
+	d.Action, d.Repo = payload.Action, repo
 
-	core.AddJob(core.Job{
-		ID:        time.Now().Format("20060102150405"),
+	span.SetAttributes(
+		attribute.String("github.action", payload.Action),
+		attribute.String("tcr.repo", repo),
+		attribute.Int64("github.run_id", payload.WorkflowJob.RunID),
+	)
+	job := core.Job{
+		ID:        deliveryJobID(d),
 		Action:    payload.Action,
 		RepoOwner: payload.Repository.Owner.Login,
 		RepoName:  payload.Repository.Name,
 		JobName:   payload.WorkflowJob.Name,
+		Labels:    payload.WorkflowJob.Labels,
 		Status:    payload.WorkflowJob.Status,
 		CreatedAt: time.Now(),
-	})
 
-	log.Printf("📦 Job queued: %s | repo: %s/%s | status: %s",
-		payload.WorkflowJob.Name,
-		payload.Repository.Owner.Login,
-		payload.Repository.Name,
-		payload.WorkflowJob.Status,
-	)
+		RunID:       payload.WorkflowJob.RunID,
+		GitHubJobID: payload.WorkflowJob.ID,
+
+		TraceID:      tracing.TraceID(ctx),
+		TraceContext: tracing.Carrier(ctx),
+	}
+	if err := core.AddJob(job); err != nil {
+		observeWebhook(d.Event, payload.Action, repo, "dropped")
+		d.Status, d.Error = DeliveryDropped, err.Error()
+		return d
+	}
+	observeWebhook(d.Event, payload.Action, repo, "accepted")
+	d.Status, d.JobID = DeliveryProcessed, job.ID
 
-	log.Printf("📦 Received job from repo %s/%s | action: %s | job: %s | status: %s",
-		payload.Repository.Owner.Login,
-		payload.Repository.Name,
-		payload.Action,
-		payload.WorkflowJob.Name,
-		payload.WorkflowJob.Status,
+	logger.Info("webhook job received",
+		"delivery", d.ID,
+		"source", d.Source,
+		"attempt", d.Attempts,
+		"repo", repo,
+		"action", payload.Action,
+		"job", payload.WorkflowJob.Name,
+		"job_id", job.ID,
+		"status", payload.WorkflowJob.Status,
+		"run_id", payload.WorkflowJob.RunID,
+		"trace_id", tracing.TraceID(ctx),
 	)
+	return d
+}
+
+// deliveryJobID: waktu proses ditambah potongan delivery ID (dan nomor
+// percobaan untuk replay) supaya delivery yang datang di detik yang sama
+// tidak menghasilkan job ID kembar
+func deliveryJobID(d Delivery) string {
+	short := d.ID
+	if len(short) > 8 {
+		short = short[:8]
+	}
+	id := time.Now().Format("20060102150405") + "-" + short
+	if d.Attempts > 1 {
+		id += fmt.Sprintf("-%d", d.Attempts)
+	}
+	return id
+}
+
+// ReplayDelivery memproses ulang delivery tersimpan atas nama by (tanpa
+// verifikasi signature ulang: hanya delivery terverifikasi yang disimpan).
+// Job baru dibuat walaupun delivery sudah pernah diproses.
+func ReplayDelivery(id, by string) (_ Delivery, err error) {
+	params := map[string]interface{}{}
+	defer func() { audit.Record(by, audit.WebhookReplay, id, params, err) }()
 
-	w.WriteHeader(http.StatusOK)
+	d, ok, err := Deliveries.Get(id)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if !ok {
+		return Delivery{}, fmt.Errorf("%w: %s", ErrDeliveryNotFound, id)
+	}
+	if !Deliveries.Claim(id) {
+		return d.Summary(), fmt.Errorf("%w: delivery %s is being processed", ErrReplayFailed, id)
+	}
+	defer Deliveries.Release(id)
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "webhook.replay", trace.WithAttributes(
+		attribute.String("github.event", eventLabel(d.Event)),
+		attribute.String("github.delivery", d.ID),
+		attribute.String("tcr.actor", by),
+	))
+	defer span.End()
+
+	previous := d.Status
+	d = processDelivery(ctx, d)
+	params["previous"], params["status"], params["job_id"] = previous, d.Status, d.JobID
+	logger.Info("webhook delivery replayed", "delivery", id, "by", by, "previous", previous, "status", d.Status, "job_id", d.JobID)
+	if d.Status == DeliveryFailed || d.Status == DeliveryDropped {
+		return d.Summary(), fmt.Errorf("%w: delivery %s %s: %s", ErrReplayFailed, id, d.Status, d.Error)
+	}
+	return d.Summary(), nil
 }
@@ -0,0 +1,64 @@
+package github
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ridwandwisiswanto/tcr/internal/secrets"
+)
+
+// WebhookSecrets melacak rotasi GITHUB_WEBHOOK_SECRET: ketika nilai dari
+// secret provider berubah, secret lama tetap diterima selama Grace supaya
+// delivery yang sudah ditandatangani GitHub dengan secret lama tidak ditolak.
+type WebhookSecrets struct {
+	Key   string
+	Grace time.Duration
+	Fetch func(key string) string
+
+	mu            sync.Mutex
+	current       string
+	previous      string
+	previousUntil time.Time
+}
+
+// Webhook dipakai WebhookHandler; grace bisa diatur lewat WEBHOOK_SECRET_GRACE_SEC
+var Webhook = &WebhookSecrets{
+	Key:   "GITHUB_WEBHOOK_SECRET",
+	Grace: time.Duration(webhookGraceSec()) * time.Second,
+	Fetch: secrets.Get,
+}
+
+func webhookGraceSec() int {
+	if i, err := strconv.Atoi(os.Getenv("WEBHOOK_SECRET_GRACE_SEC")); err == nil {
+		return i
+	}
+	return 3600
+}
+
+// Active mengembalikan secret yang saat ini valid (terbaru di depan)
+func (s *WebhookSecrets) Active() []string {
+	latest := s.Fetch(s.Key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if latest != "" && latest != s.current {
+		if s.current != "" {
+			s.previous, s.previousUntil = s.current, now.Add(s.Grace)
+			logger.Info("webhook secret rotated", "previous_accepted_until", s.previousUntil.Format(time.RFC3339))
+		}
+		s.current = latest
+	}
+
+	var out []string
+	if s.current != "" {
+		out = append(out, s.current)
+	}
+	if s.previous != "" && now.Before(s.previousUntil) {
+		out = append(out, s.previous)
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+// Package logging adalah logger terstruktur (log/slog) untuk towerd, agentd
+// dan runnerd: level per komponen, output text atau JSON, dan redaksi token
+// serta secret sebelum apa pun ditulis.
+//
+// Tiap package memakai logger komponennya sendiri:
+//
+//	var logger = logging.For("controller")
+//	logger.Info("job dispatched", "job_id", j.ID, "runner_id", r.ID)
+//
+// Logger boleh dibuat sebelum Setup; handler baru dipakai saat record ditulis.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/ridwandwisiswanto/tcr/internal/config"
+)
+
+// ComponentKey adalah atribut yang menentukan level komponen. Package yang
+// tidak bisa mengimport logging (misal config) cukup memakai
+// slog.Default().With("component", "...").
+const ComponentKey = "component"
+
+var (
+	mu     sync.RWMutex
+	base   slog.Handler = newBase(os.Stderr, "text")
+	level               = slog.LevelInfo
+	levels              = map[string]slog.Level{}
+)
+
+func init() {
+	slog.SetDefault(slog.New(&handler{}))
+}
+
+// Setup memasang format output dan level dari config, menambahkan atribut
+// service di setiap record, dan mengarahkan package log standar ke slog.
+func Setup(service string, cfg config.Logging) error {
+	return setup(os.Stderr, service, cfg)
+}
+
+func setup(w io.Writer, service string, cfg config.Logging) error {
+	lv, err := config.ParseLogLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	comps, err := cfg.ComponentLevels()
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	base = newBase(w, cfg.Format).WithAttrs([]slog.Attr{slog.String("service", service)})
+	level, levels = lv, comps
+	mu.Unlock()
+	return nil
+}
+
+// Reload mengganti level global dan per komponen tanpa restart (format tetap)
+func Reload(cfg config.Logging) error {
+	lv, err := config.ParseLogLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	comps, err := cfg.ComponentLevels()
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	level, levels = lv, comps
+	mu.Unlock()
+	return nil
+}
+
+// For mengembalikan logger untuk satu komponen (controller, agent, github, ...)
+func For(component string) *slog.Logger {
+	return slog.New(&handler{component: component}).With(ComponentKey, component)
+}
+
+// Fatal menulis record level error lalu keluar, pengganti log.Fatalf
+func Fatal(l *slog.Logger, msg string, args ...any) {
+	l.Error(msg, args...)
+	os.Exit(1)
+}
+
+func newBase(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: redactAttr}
+	if format == "json" {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+func enabled(component string, l slog.Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	min, ok := levels[component]
+	if !ok {
+		min = level
+	}
+	return l >= min
+}
+
+func currentBase() slog.Handler {
+	mu.RLock()
+	defer mu.RUnlock()
+	return base
+}
+
+// handler menyaring level per komponen lalu meneruskan ke base handler yang
+// aktif saat itu. With/WithGroup dicatat dan diputar ulang di atas base,
+// supaya logger yang dibuat sebelum Setup tetap memakai format dari config.
+type handler struct {
+	component string
+	ops       []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, l slog.Level) bool {
+	return enabled(h.component, l)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	next := currentBase()
+	for _, op := range h.ops {
+		next = op(next)
+	}
+	return next.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+	for _, a := range attrs {
+		if a.Key == ComponentKey {
+			c.component = a.Value.String()
+		}
+	}
+	return c
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := append(append([]func(slog.Handler) slog.Handler(nil), h.ops...), op)
+	return &handler{component: h.component, ops: ops}
+}
@@ -0,0 +1,223 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// RoleTower dipakai untuk sertifikat towerd sendiri (server + client)
+	RoleTower = "tower"
+
+	caLifetime = 10 * 365 * 24 * time.Hour
+)
+
+// CA adalah certificate authority internal milik towerd
+type CA struct {
+	Cert    *x509.Certificate
+	CertPEM []byte
+	key     crypto.Signer
+}
+
+// LoadOrCreateCA membaca ca.crt/ca.key dari dir, atau membuat CA baru kalau belum ada
+func LoadOrCreateCA(dir string) (*CA, error) {
+	certPath := filepath.Join(dir, "ca.crt")
+	keyPath := filepath.Join(dir, "ca.key")
+
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+	if certErr == nil && keyErr == nil {
+		pair, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("load CA: %w", err)
+		}
+		cert, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse CA: %w", err)
+		}
+		signer, ok := pair.PrivateKey.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("CA key is not a signer")
+		}
+		return &CA{Cert: cert, CertPEM: certPEM, key: signer}, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: "tcr internal CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(caLifetime),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return nil, err
+	}
+
+	logger.Info("created internal CA", "path", certPath)
+	return &CA{Cert: cert, CertPEM: certPEM, key: key}, nil
+}
+
+// Pool mengembalikan CertPool yang hanya berisi CA ini
+func (ca *CA) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Cert)
+	return pool
+}
+
+// SignCSR menandatangani CSR dari agent/runner. CN dan role diambil dari
+// hasil enrollment (bukan dari CSR). SAN IP dibatasi ke peerIP (alamat yang
+// dipakai towerd untuk memanggil balik agent/runner, selalu ditambahkan) dan
+// loopback; SAN DNS dari CSR dipertahankan.
+func (ca *CA) SignCSR(csrPEM []byte, cn, role string, peerIP net.IP, ttl time.Duration) ([]byte, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("invalid CSR PEM")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("CSR signature: %w", err)
+	}
+
+	var ips []net.IP
+	if peerIP != nil {
+		ips = append(ips, peerIP)
+	}
+	for _, ip := range csr.IPAddresses {
+		if ip.IsLoopback() && !ip.Equal(peerIP) {
+			ips = append(ips, ip)
+		}
+	}
+	return ca.sign(csr.PublicKey, cn, role, csr.DNSNames, ips, ttl)
+}
+
+// Issue membuat key + sertifikat baru (dipakai towerd untuk dirinya sendiri)
+func (ca *CA) Issue(cn, role string, hosts []string, ttl time.Duration) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	dns, ips := splitHosts(hosts)
+	certPEM, err := ca.sign(key.Public(), cn, role, dns, ips, ttl)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	pair, err := tls.X509KeyPair(certPEM, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	if err != nil {
+		return nil, err
+	}
+	return &pair, nil
+}
+
+func (ca *CA) sign(pub crypto.PublicKey, cn, role string, dns []string, ips []net.IP, ttl time.Duration) ([]byte, error) {
+	tmpl := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: cn, OrganizationalUnit: []string{role}},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     dns,
+		IPAddresses:  ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Cert, pub, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// NewKeyAndCSR membuat private key baru beserta CSR untuk dikirim saat enroll
+func NewKeyAndCSR(cn string, hosts []string) (keyPEM, csrPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	dns, ips := splitHosts(hosts)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: cn},
+		DNSNames:    dns,
+		IPAddresses: ips,
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// Identity mengembalikan CN dan role (OU) dari sertifikat peer
+func Identity(cert *x509.Certificate) (cn, role string) {
+	if len(cert.Subject.OrganizationalUnit) > 0 {
+		role = cert.Subject.OrganizationalUnit[0]
+	}
+	return cert.Subject.CommonName, role
+}
+
+// LoadPool membaca file PEM CA (TOWER_CA_FILE) untuk verifikasi peer
+func LoadPool(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates in %s", path)
+	}
+	return pool, nil
+}
+
+func splitHosts(hosts []string) (dns []string, ips []net.IP) {
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			ips = append(ips, ip)
+		} else if h != "" {
+			dns = append(dns, h)
+		}
+	}
+	return dns, ips
+}
+
+func randomSerial() *big.Int {
+	n, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	return n
+}
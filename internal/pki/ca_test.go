@@ -0,0 +1,108 @@
+package pki
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMutualTLS_RoleChecked(t *testing.T) {
+	ca, err := LoadOrCreateCA(t.TempDir())
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+
+	server, err := NewRotator("runner", func() (*tls.Certificate, error) {
+		return ca.Issue("runner-1", "runner", []string{"127.0.0.1"}, time.Hour)
+	})
+	if err != nil {
+		t.Fatalf("issue server cert: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(RequirePeerRole(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), RoleTower))
+	// pakai listener TLS sendiri supaya httptest tidak memasang sertifikatnya
+	srv.Listener = tls.NewListener(srv.Listener, ServerConfig(server, ca.Pool(), true))
+	srv.Start()
+	defer srv.Close()
+	url := strings.Replace(srv.URL, "http://", "https://", 1)
+
+	call := func(cn, role string) (int, error) {
+		var rot *Rotator
+		if cn != "" {
+			rot, err = NewRotator(cn, func() (*tls.Certificate, error) {
+				keyPEM, csrPEM, err := NewKeyAndCSR(cn, nil)
+				if err != nil {
+					return nil, err
+				}
+				certPEM, err := ca.SignCSR(csrPEM, cn, role, nil, time.Hour)
+				if err != nil {
+					return nil, err
+				}
+				pair, err := tls.X509KeyPair(certPEM, keyPEM)
+				return &pair, err
+			})
+			if err != nil {
+				t.Fatalf("issue client cert: %v", err)
+			}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: ClientConfig(rot, ca.Pool(), "runner")}}
+		resp, err := client.Get(url)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	if code, err := call("towerd", RoleTower); err != nil || code != http.StatusOK {
+		t.Fatalf("tower cert: expected 200, got %d (%v)", code, err)
+	}
+	if code, err := call("vm-1", "agent"); err != nil || code != http.StatusForbidden {
+		t.Fatalf("agent cert: expected 403, got %d (%v)", code, err)
+	}
+	if _, err := call("", ""); err == nil {
+		t.Fatalf("expected handshake failure without client cert")
+	}
+
+	// client yang mengharapkan towerd menolak server ber-role runner
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: ClientConfig(nil, ca.Pool(), RoleTower)}}
+	if _, err := client.Get(url); err == nil || !strings.Contains(err.Error(), `role "runner"`) {
+		t.Fatalf("expected server role to be rejected, got %v", err)
+	}
+}
+
+func TestSignCSR_LimitsIPs(t *testing.T) {
+	ca, err := LoadOrCreateCA(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, csrPEM, err := NewKeyAndCSR("vm-1", []string{"vm-1", "127.0.0.1", "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, err := ca.SignCSR(csrPEM, "vm-1", "agent", net.ParseIP("192.168.1.7"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(certPEM)
+	cert, _ := x509.ParseCertificate(block.Bytes)
+
+	var ips []string
+	for _, ip := range cert.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	if strings.Join(ips, ",") != "192.168.1.7,127.0.0.1" {
+		t.Fatalf("unexpected IP SANs %v", ips)
+	}
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "vm-1" {
+		t.Fatalf("unexpected DNS SANs %v", cert.DNSNames)
+	}
+}
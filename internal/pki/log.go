@@ -0,0 +1,5 @@
+package pki
+
+import "github.com/ridwandwisiswanto/tcr/internal/logging"
+
+var logger = logging.For("pki")
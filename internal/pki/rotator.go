@@ -0,0 +1,141 @@
+package pki
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Rotator menyimpan sertifikat aktif dan memperbaruinya lewat renew sebelum
+// expired (saat 2/3 masa berlaku sudah lewat). Dipakai lewat
+// GetCertificate/GetClientCertificate di tls.Config supaya rotasi tidak
+// butuh restart listener atau client.
+type Rotator struct {
+	mu    sync.RWMutex
+	cert  *tls.Certificate
+	renew func() (*tls.Certificate, error)
+	name  string
+}
+
+func NewRotator(name string, renew func() (*tls.Certificate, error)) (*Rotator, error) {
+	r := &Rotator{renew: renew, name: name}
+	if err := r.Renew(); err != nil {
+		return nil, err
+	}
+	go r.loop()
+	return r, nil
+}
+
+// Renew memaksa penerbitan sertifikat baru
+func (r *Rotator) Renew() error {
+	cert, err := r.renew()
+	if err != nil {
+		return err
+	}
+	if cert.Leaf == nil {
+		cert.Leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+	}
+
+	r.mu.Lock()
+	r.cert = cert
+	r.mu.Unlock()
+
+	logger.Info("certificate issued", "name", r.name, "not_after", cert.Leaf.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+func (r *Rotator) loop() {
+	for {
+		r.mu.RLock()
+		leaf := r.cert.Leaf
+		r.mu.RUnlock()
+
+		lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+		wait := time.Until(leaf.NotBefore.Add(lifetime * 2 / 3))
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+
+		if err := r.Renew(); err != nil {
+			logger.Warn("certificate rotation failed, retrying in 1m", "name", r.name, "err", err)
+			time.Sleep(time.Minute)
+		}
+	}
+}
+
+func (r *Rotator) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *Rotator) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// ServerConfig: sertifikat dari rotator, verifikasi client terhadap pool.
+// requireClient=false dipakai towerd supaya /enroll & webhook tetap bisa
+// diakses tanpa client cert (auth.Protect yang menegakkan sisanya).
+func ServerConfig(r *Rotator, pool *x509.CertPool, requireClient bool) *tls.Config {
+	mode := tls.VerifyClientCertIfGiven
+	if requireClient {
+		mode = tls.RequireAndVerifyClientCert
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+		ClientCAs:      pool,
+		ClientAuth:     mode,
+	}
+}
+
+// ClientConfig: verifikasi server terhadap pool dan role-nya (OU) harus salah
+// satu serverRoles, supaya sertifikat agent/runner (yang juga ditandatangani
+// CA yang sama) tidak bisa menyamar sebagai towerd. Client cert dikirim dari
+// rotator (kalau ada).
+func ClientConfig(r *Rotator, pool *x509.CertPool, serverRoles ...string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    pool,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("no server certificate")
+			}
+			_, role := Identity(cs.PeerCertificates[0])
+			for _, allowed := range serverRoles {
+				if role == allowed {
+					return nil
+				}
+			}
+			return fmt.Errorf("server certificate role %q is not allowed", role)
+		},
+	}
+	if r != nil {
+		cfg.GetClientCertificate = r.GetClientCertificate
+	}
+	return cfg
+}
+
+// RequirePeerRole hanya meneruskan request dengan client cert ber-role tertentu
+func RequirePeerRole(next http.Handler, roles ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		_, role := Identity(r.TLS.VerifiedChains[0][0])
+		for _, allowed := range roles {
+			if role == allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		http.Error(w, "forbidden", http.StatusForbidden)
+	})
+}
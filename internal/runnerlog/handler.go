@@ -0,0 +1,165 @@
+package runnerlog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resolver memetakan nama runner ke path file log-nya
+type Resolver func(runner string) (string, bool)
+
+// Handler melayani GET /logs?runner=<name>&tail=<n>&follow=true.
+// Dengan follow=true, baris baru di-stream sampai client disconnect.
+func Handler(resolve Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := r.URL.Query().Get("runner")
+		path, ok := resolve(name)
+		if !ok {
+			http.Error(w, "unknown runner", http.StatusNotFound)
+			return
+		}
+
+		n := 200
+		if v := r.URL.Query().Get("tail"); v != "" {
+			if i, err := strconv.Atoi(v); err == nil && i >= 0 {
+				n = i
+			}
+		}
+
+		lines, err := Tail(path, n)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, l := range lines {
+			fmt.Fprintln(w, l)
+		}
+
+		if r.URL.Query().Get("follow") != "true" {
+			return
+		}
+		flusher, _ := w.(http.Flusher)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		Follow(r, path, w, flusher)
+	}
+}
+
+// maxLineBytes: baris log yang lebih panjang dipotong (output job kadang
+// berisi satu baris raksasa, misal JSON atau base64), bukan membuat Tail gagal
+const maxLineBytes = 1024 * 1024
+
+const truncatedMark = " [truncated]"
+
+// Tail mengembalikan n baris terakhir dari file log
+func Tail(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	br := bufio.NewReaderSize(f, 64*1024)
+	for {
+		line, err := readLine(br)
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, line)
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+}
+
+// readLine membaca satu baris tanpa line ending; byte setelah maxLineBytes
+// dibuang dan baris ditandai truncatedMark
+func readLine(br *bufio.Reader) (string, error) {
+	var buf []byte
+	truncated := false
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if room := maxLineBytes - len(buf); len(chunk) > room {
+			buf = append(buf, chunk[:room]...)
+			truncated = true
+		} else {
+			buf = append(buf, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && (len(buf) > 0 || truncated) {
+			err = nil
+		}
+		if err != nil {
+			return "", err
+		}
+		line := strings.TrimSuffix(strings.TrimSuffix(string(buf), "\n"), "\r")
+		if truncated {
+			line += truncatedMark
+		}
+		return line, nil
+	}
+}
+
+// Follow men-stream data baru di file log ke out sampai request selesai.
+// Kalau file di-rotate (ukuran mengecil / inode berganti) file dibuka ulang.
+func Follow(r *http.Request, path string, out io.Writer, flusher http.Flusher) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer func() { f.Close() }()
+
+	offset, _ := f.Seek(0, io.SeekEnd)
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		old, _ := f.Stat()
+		if !os.SameFile(cur, old) || cur.Size() < offset {
+			f.Close()
+			if f, err = os.Open(path); err != nil {
+				return
+			}
+			offset = 0
+		}
+
+		n, _ := io.Copy(out, f)
+		offset += n
+		if n > 0 && flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
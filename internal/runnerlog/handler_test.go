@@ -0,0 +1,30 @@
+package runnerlog
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandler_TailTruncatesLongLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runner-001.log")
+	long := strings.Repeat("x", 2*maxLineBytes)
+	os.WriteFile(path, []byte("first\r\n"+long+"\nlast"), 0600)
+
+	h := Handler(func(name string) (string, bool) { return path, name == "runner-001" })
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/logs?runner=runner-001&tail=3", nil))
+	if rec.Code != 200 {
+		t.Fatalf("expected 200, got %d: %.100s", rec.Code, rec.Body.String())
+	}
+
+	lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+	if len(lines) != 3 || lines[0] != "first" || lines[2] != "last" {
+		t.Fatalf("unexpected lines (%d): %.40q ... %.40q", len(lines), lines[0], lines[len(lines)-1])
+	}
+	if len(lines[1]) != maxLineBytes+len(truncatedMark) || !strings.HasSuffix(lines[1], truncatedMark) {
+		t.Fatalf("long line not truncated: %d bytes", len(lines[1]))
+	}
+}
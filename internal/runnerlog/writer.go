@@ -0,0 +1,143 @@
+package runnerlog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var (
+	// baris dari Runner.Listener yang menandai awal/akhir job
+	jobStartRe = regexp.MustCompile(`Running job: (.+)$`)
+	jobDoneRe  = regexp.MustCompile(`Job .+ completed with result`)
+)
+
+// Writer menulis output runner ke file sendiri, tiap baris diberi tag
+// waktu, nama runner dan job yang sedang jalan. File di-rotate saat
+// ukurannya melewati maxBytes dan hanya `backups` file lama disimpan.
+type Writer struct {
+	mu       sync.Mutex
+	path     string
+	runner   string
+	job      string
+	maxBytes int64
+	backups  int
+
+	f    *os.File
+	size int64
+	buf  []byte // sisa baris yang belum diakhiri newline
+}
+
+// New membuka (atau melanjutkan) file log runner di path
+func New(path, runner string, maxBytes int64, backups int) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	w := &Writer{path: path, runner: runner, maxBytes: maxBytes, backups: backups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Path mengembalikan lokasi file log aktif
+func (w *Writer) Path() string {
+	return w.path
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f = f
+	w.size = info.Size()
+	return nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.writeLine(w.buf[:i]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *Writer) writeLine(line []byte) error {
+	line = bytes.TrimRight(line, "\r")
+	if m := jobStartRe.FindSubmatch(line); m != nil {
+		w.job = string(m[1])
+	}
+
+	job := w.job
+	if job == "" {
+		job = "-"
+	}
+	out := fmt.Sprintf("%s [%s] [%s] %s\n", time.Now().UTC().Format(time.RFC3339), w.runner, job, line)
+
+	if jobDoneRe.Match(line) {
+		w.job = ""
+	}
+
+	if w.maxBytes > 0 && w.size+int64(len(out)) > w.maxBytes {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.f.WriteString(out)
+	w.size += int64(n)
+	return err
+}
+
+// rotate: path.N-1 → path.N, ..., path → path.1
+func (w *Writer) rotate() error {
+	w.f.Close()
+
+	if w.backups <= 0 {
+		if err := os.Truncate(w.path, 0); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return w.open()
+	}
+
+	_ = os.Remove(fmt.Sprintf("%s.%d", w.path, w.backups))
+	for i := w.backups - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+	}
+	if err := os.Rename(w.path, w.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return w.open()
+}
+
+// Close menulis sisa baris yang belum lengkap lalu menutup file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		_ = w.writeLine(w.buf)
+		w.buf = nil
+	}
+	return w.f.Close()
+}
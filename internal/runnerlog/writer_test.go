@@ -0,0 +1,48 @@
+package runnerlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriter_TagsJobAndRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vm-agent-01.log")
+	w, err := New(path, "vm-agent-01", 200, 2)
+	if err != nil {
+		t.Fatalf("failed to open writer: %v", err)
+	}
+
+	w.Write([]byte("Listening for Jobs\n2024-01-01 Running job: build\nstep out"))
+	w.Write([]byte("put\n"))
+	w.Write([]byte("Job build completed with result: Succeeded\nidle\n"))
+	w.Close()
+
+	lines, err := Tail(path, 100)
+	if err != nil {
+		t.Fatalf("tail failed: %v", err)
+	}
+	rotated, _ := Tail(path+".1", 100)
+	all := append(rotated, lines...)
+
+	want := []string{
+		"[vm-agent-01] [-] Listening for Jobs",
+		"[vm-agent-01] [build] step output",
+		"[vm-agent-01] [build] Job build completed",
+		"[vm-agent-01] [-] idle",
+	}
+	joined := strings.Join(all, "\n")
+	for _, s := range want {
+		if !strings.Contains(joined, s) {
+			t.Fatalf("expected %q in log, got:\n%s", s, joined)
+		}
+	}
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("expected rotated file: %v", err)
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Fatalf("expected at most 2 backups")
+	}
+}
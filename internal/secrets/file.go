@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File membaca secret dari <Dir>/<key> (format mount secret Docker/Kubernetes).
+// Setiap Get cek mtime file, jadi secret yang diganti langsung terbaca
+// tanpa restart towerd.
+type File struct {
+	Dir string
+
+	mu    sync.Mutex
+	cache map[string]fileEntry
+}
+
+type fileEntry struct {
+	value   string
+	modTime time.Time
+	size    int64
+}
+
+func NewFile(dir string) *File {
+	return &File{Dir: dir, cache: make(map[string]fileEntry)}
+}
+
+func (f *File) Get(key string) (string, error) {
+	if strings.ContainsAny(key, `/\`) || key == "" || key == "." || key == ".." {
+		return "", nil
+	}
+	path := filepath.Join(f.Dir, key)
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cache == nil {
+		f.cache = make(map[string]fileEntry)
+	}
+
+	old, ok := f.cache[key]
+	if ok && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+		return old.value, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	e := fileEntry{value: strings.TrimSpace(string(data)), modTime: info.ModTime(), size: info.Size()}
+	f.cache[key] = e
+	if ok && old.value != e.value {
+		logger.Info("secret reloaded", "name", key, "path", path)
+	}
+	return e.value, nil
+}
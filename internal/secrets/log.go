@@ -0,0 +1,5 @@
+package secrets
+
+import "github.com/ridwandwisiswanto/tcr/internal/logging"
+
+var logger = logging.For("secrets")
@@ -0,0 +1,96 @@
+// Package secrets menyediakan sumber secret untuk towerd: file (hot reload),
+// environment, dan backend HTTP kompatibel HashiCorp Vault.
+package secrets
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Provider mengembalikan nilai secret berdasarkan nama (misal GITHUB_TOKEN).
+// Secret yang tidak ada dikembalikan sebagai string kosong tanpa error.
+type Provider interface {
+	Get(key string) (string, error)
+}
+
+// Env membaca secret dari environment (termasuk yang di-load godotenv)
+type Env struct{}
+
+func (Env) Get(key string) (string, error) {
+	return os.Getenv(key), nil
+}
+
+// Chain mencoba provider berurutan, nilai non-kosong pertama yang dipakai
+type Chain []Provider
+
+func (c Chain) Get(key string) (string, error) {
+	var firstErr error
+	for _, p := range c {
+		v, err := p.Get(key)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if v != "" {
+			return v, nil
+		}
+	}
+	return "", firstErr
+}
+
+var (
+	mu      sync.RWMutex
+	current Provider = Env{}
+)
+
+// SetDefault mengganti provider yang dipakai Get
+func SetDefault(p Provider) {
+	mu.Lock()
+	current = p
+	mu.Unlock()
+}
+
+// Get membaca secret dari provider default. Error dicatat ke log dan
+// dikembalikan sebagai string kosong supaya caller cukup cek "".
+func Get(key string) string {
+	mu.RLock()
+	p := current
+	mu.RUnlock()
+
+	v, err := p.Get(key)
+	if err != nil {
+		logger.Warn("secret unavailable", "name", key, "err", err)
+	}
+	return v
+}
+
+// FromEnv menyusun provider dari env:
+//   - SECRETS_DIR: satu file per secret (nama file = nama secret), hot reload
+//   - VAULT_ADDR + VAULT_SECRET_PATH (+ VAULT_TOKEN, VAULT_NAMESPACE, VAULT_CACHE_SEC)
+//   - environment sebagai fallback terakhir
+func FromEnv() Provider {
+	var chain Chain
+	if dir := os.Getenv("SECRETS_DIR"); dir != "" {
+		logger.Info("secrets from files", "dir", dir)
+		chain = append(chain, NewFile(dir))
+	}
+	if addr := os.Getenv("VAULT_ADDR"); addr != "" && os.Getenv("VAULT_SECRET_PATH") != "" {
+		ttl := 60
+		if i, err := strconv.Atoi(os.Getenv("VAULT_CACHE_SEC")); err == nil {
+			ttl = i
+		}
+		logger.Info("secrets from vault", "addr", addr, "path", os.Getenv("VAULT_SECRET_PATH"))
+		chain = append(chain, &Vault{
+			Addr:      addr,
+			Path:      os.Getenv("VAULT_SECRET_PATH"),
+			Token:     os.Getenv("VAULT_TOKEN"),
+			Namespace: os.Getenv("VAULT_NAMESPACE"),
+			CacheTTL:  time.Duration(ttl) * time.Second,
+		})
+	}
+	return append(chain, Env{})
+}
@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFile_HotReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "GITHUB_WEBHOOK_SECRET")
+	os.WriteFile(path, []byte("old\n"), 0600)
+
+	f := NewFile(dir)
+	if v, _ := f.Get("GITHUB_WEBHOOK_SECRET"); v != "old" {
+		t.Fatalf("expected old, got %q", v)
+	}
+
+	os.WriteFile(path, []byte("rotated-secret\n"), 0600)
+	later := time.Now().Add(time.Second)
+	os.Chtimes(path, later, later)
+
+	if v, _ := f.Get("GITHUB_WEBHOOK_SECRET"); v != "rotated-secret" {
+		t.Fatalf("expected reloaded secret, got %q", v)
+	}
+	if v, err := f.Get("MISSING"); v != "" || err != nil {
+		t.Fatalf("expected empty value for missing secret, got %q (%v)", v, err)
+	}
+	if v, _ := f.Get("../GITHUB_WEBHOOK_SECRET"); v != "" {
+		t.Fatalf("path traversal must not resolve")
+	}
+}
+
+func TestVault_KVv2AndChain(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-Vault-Token") != "root" || r.URL.Path != "/v1/secret/data/tcr" {
+			http.Error(w, "denied", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"GITHUB_TOKEN":"ghp_vault"},"metadata":{"version":3}}}`))
+	}))
+	defer srv.Close()
+
+	v := &Vault{Addr: srv.URL, Path: "secret/data/tcr", Token: "root", CacheTTL: time.Minute}
+	t.Setenv("TCR_TEST_ONLY_ENV", "from-env")
+	chain := Chain{v, Env{}}
+
+	if got, _ := chain.Get("GITHUB_TOKEN"); got != "ghp_vault" {
+		t.Fatalf("expected vault value, got %q", got)
+	}
+	if got, _ := chain.Get("TCR_TEST_ONLY_ENV"); got != "from-env" {
+		t.Fatalf("expected env fallback, got %q", got)
+	}
+	if calls != 1 {
+		t.Fatalf("expected cached vault response, got %d calls", calls)
+	}
+}
+
+func TestVault_StaleCacheWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	var fail atomic.Bool
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if n == 2 {
+			<-release // fetch kedua lambat
+		}
+		if fail.Load() {
+			http.Error(w, "sealed", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"data":{"GITHUB_TOKEN":"ghp_v1"}}`))
+	}))
+	defer srv.Close()
+
+	v := &Vault{Addr: srv.URL, Path: "secret/tcr", CacheTTL: time.Millisecond}
+	if got, _ := v.Get("GITHUB_TOKEN"); got != "ghp_v1" {
+		t.Fatalf("expected vault value, got %q", got)
+	}
+	time.Sleep(5 * time.Millisecond)
+	fail.Store(true)
+
+	// fetch yang lambat tidak menahan pembaca lain: cache lama langsung dipakai
+	slow := make(chan string)
+	go func() {
+		got, _ := v.Get("GITHUB_TOKEN")
+		slow <- got
+	}()
+	for calls.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if got, err := v.Get("GITHUB_TOKEN"); got != "ghp_v1" || err != nil {
+		t.Fatalf("expected stale value during fetch, got %q %v", got, err)
+	}
+	close(release)
+	if got := <-slow; got != "ghp_v1" {
+		t.Fatalf("expected stale value after failed fetch, got %q", got)
+	}
+
+	// setelah gagal, Vault tidak dihubungi lagi selama backoff
+	for i := 0; i < 5; i++ {
+		v.Get("GITHUB_TOKEN")
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("expected no fetch during backoff, got %d calls", n)
+	}
+}
@@ -0,0 +1,154 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vaultRetryBackoff adalah jeda sebelum fetch dicoba lagi setelah gagal;
+// selama itu cache lama (atau error terakhir) dipakai tanpa request baru
+const vaultRetryBackoff = 30 * time.Second
+
+// Vault membaca secret dari backend HTTP kompatibel HashiCorp Vault.
+// Path KV v2 (misal "secret/data/tcr") maupun KV v1 ("secret/tcr") didukung;
+// seluruh key di path itu di-cache selama CacheTTL. Hanya satu fetch berjalan
+// pada satu waktu dan lock tidak dipegang selama request HTTP.
+type Vault struct {
+	Addr      string
+	Path      string
+	Token     string
+	Namespace string
+	CacheTTL  time.Duration
+	HTTP      *http.Client
+
+	mu       sync.Mutex
+	data     map[string]string
+	fetched  time.Time
+	failedAt time.Time
+	lastErr  error
+	inflight chan struct{} // ditutup saat fetch yang sedang berjalan selesai
+}
+
+func (v *Vault) Get(key string) (string, error) {
+	v.mu.Lock()
+	for {
+		if v.data != nil && time.Since(v.fetched) < v.CacheTTL {
+			break
+		}
+		if time.Since(v.failedAt) < vaultRetryBackoff {
+			// Vault baru saja gagal: pakai cache lama, jangan request lagi
+			if v.data == nil {
+				err := v.lastErr
+				v.mu.Unlock()
+				return "", err
+			}
+			break
+		}
+		if v.inflight != nil {
+			// cache lama tetap dipakai selama fetch berjalan; tanpa cache tunggu hasilnya
+			if v.data != nil {
+				break
+			}
+			wait := v.inflight
+			v.mu.Unlock()
+			<-wait
+			v.mu.Lock()
+			if v.data == nil && v.lastErr != nil {
+				err := v.lastErr
+				v.mu.Unlock()
+				return "", err
+			}
+			if v.data != nil {
+				break
+			}
+			continue
+		}
+		// CacheTTL 0: tiap Get fetch sekali, hasilnya langsung dipakai
+		if v.refresh(); v.lastErr == nil {
+			break
+		}
+	}
+	value := v.data[key]
+	v.mu.Unlock()
+	return value, nil
+}
+
+// refresh menjalankan satu fetch di luar lock; dipanggil dan kembali dengan
+// v.mu terkunci
+func (v *Vault) refresh() {
+	done := make(chan struct{})
+	v.inflight = done
+	v.mu.Unlock()
+
+	data, err := v.fetch()
+
+	v.mu.Lock()
+	if err != nil {
+		v.failedAt, v.lastErr = time.Now(), err
+		logger.Warn("vault fetch failed, using cached secrets", "path", v.Path, "cached", v.data != nil, "retry_in", vaultRetryBackoff, "err", err)
+	} else {
+		v.data, v.fetched, v.failedAt, v.lastErr = data, time.Now(), time.Time{}, nil
+	}
+	v.inflight = nil
+	close(done)
+}
+
+func (v *Vault) fetch() (map[string]string, error) {
+	url := strings.TrimRight(v.Addr, "/") + "/v1/" + strings.TrimLeft(v.Path, "/")
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v.Token != "" {
+		req.Header.Set("X-Vault-Token", v.Token)
+	}
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	client := v.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault responded %d for %s", resp.StatusCode, v.Path)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode vault response: %v", err)
+	}
+
+	// KV v2 membungkus nilai di data.data
+	fields := body.Data
+	if inner, ok := body.Data["data"]; ok {
+		if _, hasMeta := body.Data["metadata"]; hasMeta {
+			fields = nil
+			if err := json.Unmarshal(inner, &fields); err != nil {
+				return nil, fmt.Errorf("decode vault kv v2 data: %v", err)
+			}
+		}
+	}
+
+	out := make(map[string]string, len(fields))
+	for k, raw := range fields {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			s = string(raw)
+		}
+		out[k] = s
+	}
+	return out, nil
+}
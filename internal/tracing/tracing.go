@@ -0,0 +1,116 @@
+// Package tracing memasang OpenTelemetry tracing untuk towerd/agentd/runnerd
+// (export OTLP/HTTP ke collector) dan helper untuk membawa trace context lewat
+// header HTTP maupun record job, sehingga satu job GitHub bisa diikuti dari
+// webhook → queue → dispatch → result.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ridwandwisiswanto/tcr/internal/config"
+	"github.com/ridwandwisiswanto/tcr/internal/logging"
+)
+
+const instrumentation = "github.com/ridwandwisiswanto/tcr"
+
+func init() {
+	// propagator selalu dipasang supaya traceparent dari hulu tetap diteruskan
+	// walau proses ini sendiri tidak meng-export
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Setup memasang tracer provider global untuk service. Kalau endpoint kosong
+// tracing mati (tracer no-op). Fungsi yang dikembalikan mem-flush span yang
+// tersisa saat shutdown.
+func Setup(service string, cfg config.Tracing) (func(context.Context) error, error) {
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	endpoint, err := tracesURL(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service)))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	logging.For("tracing").Info("tracing enabled", "endpoint", endpoint, "sample_ratio", cfg.SampleRatio)
+	return tp.Shutdown, nil
+}
+
+// tracesURL mengikuti arti OTEL_EXPORTER_OTLP_ENDPOINT: base URL collector,
+// path signal /v1/traces ditambahkan di belakangnya
+func tracesURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("tracing: invalid endpoint %q", endpoint)
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/v1/traces"
+	return u.String(), nil
+}
+
+// Tracer adalah tracer yang dipakai seluruh komponen tcr
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Carrier menyimpan trace context ctx sebagai map (traceparent, tracestate)
+// untuk disimpan di record job atau dikirim di payload. Nil kalau ctx tidak
+// membawa span yang valid.
+func Carrier(ctx context.Context) map[string]string {
+	c := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, c)
+	if len(c) == 0 {
+		return nil
+	}
+	return c
+}
+
+// FromCarrier mengembalikan ctx dengan trace context dari carrier sebagai parent
+func FromCarrier(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// TraceID mengembalikan trace ID span aktif di ctx, atau "" kalau tidak ada
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// Fail menandai span gagal dengan err
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+func TestCarrierRoundTrip(t *testing.T) {
+	rec := record(t)
+
+	if c := Carrier(context.Background()); c != nil {
+		t.Fatalf("carrier without span should be nil, got %v", c)
+	}
+	if id := TraceID(context.Background()); id != "" {
+		t.Fatalf("trace ID without span should be empty, got %q", id)
+	}
+
+	ctx, parent := Tracer().Start(context.Background(), "webhook.receive")
+	carrier := Carrier(ctx)
+	if carrier["traceparent"] == "" {
+		t.Fatalf("carrier has no traceparent: %v", carrier)
+	}
+	parent.End()
+
+	_, child := Tracer().Start(FromCarrier(context.Background(), carrier), "job.dispatch")
+	child.End()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[1].Parent().SpanID() != spans[0].SpanContext().SpanID() {
+		t.Fatalf("dispatch span is not a child of the webhook span")
+	}
+	if got, want := TraceID(ctx), spans[1].SpanContext().TraceID().String(); got != want {
+		t.Fatalf("trace ID %s, want %s", got, want)
+	}
+}
+
+func TestMiddleware_ContinuesCallerTrace(t *testing.T) {
+	rec := record(t)
+
+	ctx, caller := Tracer().Start(context.Background(), "agent.heartbeat")
+	req := httptest.NewRequest("POST", "/vm/heartbeat", nil)
+	Inject(ctx, req.Header)
+
+	var seen string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = TraceID(r.Context())
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	caller.End()
+
+	if seen != TraceID(ctx) {
+		t.Fatalf("handler saw trace %q, want caller trace %q", seen, TraceID(ctx))
+	}
+	server := rec.Ended()[0]
+	if server.Name() != "POST" || server.Parent().SpanID() != caller.SpanContext().SpanID() {
+		t.Fatalf("unexpected server span %q with parent %s", server.Name(), server.Parent().SpanID())
+	}
+	if server.Status().Code != codes.Error {
+		t.Fatalf("5xx response should mark the span as error, got %v", server.Status())
+	}
+}
+
+func TestTracesURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"http://localhost:4318":          "http://localhost:4318/v1/traces",
+		"https://otel.example.com/otlp/": "https://otel.example.com/otlp/v1/traces",
+	} {
+		got, err := tracesURL(in)
+		if err != nil || got != want {
+			t.Errorf("tracesURL(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := tracesURL("localhost:4318"); err == nil {
+		t.Errorf("expected error for endpoint without scheme")
+	}
+}